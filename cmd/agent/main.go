@@ -2,10 +2,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	"github.com/gitslim/monit/internal/agent"
 	"github.com/gitslim/monit/internal/agent/conf"
+	"github.com/gitslim/monit/internal/debugserver"
 	"github.com/gitslim/monit/internal/logging"
 )
 
@@ -28,10 +31,15 @@ func getOrDefault(value string) string {
 	return value
 }
 
-func main() {
-	// вывод информации о билде.
-	printBuildInfo()
+func buildInfo() debugserver.BuildInfo {
+	return debugserver.BuildInfo{
+		Version: getOrDefault(buildVersion),
+		Date:    getOrDefault(buildDate),
+		Commit:  getOrDefault(buildCommit),
+	}
+}
 
+func main() {
 	// Инициализация логгера.
 	log, err := logging.NewLogger()
 	if err != nil {
@@ -45,5 +53,30 @@ func main() {
 		log.Fatalf("Config parse failed: %v", err)
 	}
 
+	// Вывод итоговой конфигурации вместо запуска.
+	if cfg.PrintConfig {
+		if err := cfg.Dump(os.Stdout); err != nil {
+			log.Fatalf("Config print failed: %v", err)
+		}
+		return
+	}
+
+	// вывод информации о билде.
+	printBuildInfo()
+
+	// Переинициализация логгера с параметрами из конфига.
+	log, err = logging.NewLogger(cfg.LogOptions()...)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize logger: %v\n", err))
+	}
+	defer log.Close()
+
+	// Запуск отладочного сервера, он останавливается вместе с агентом.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if cfg.DebugAddr != "" {
+		debugserver.Start(ctx, log, cfg.DebugAddr, cfg.DebugToken, buildInfo())
+	}
+
 	agent.Start(cfg, log)
 }
@@ -0,0 +1,230 @@
+// Команда monit-keys генерирует и проверяет ключи агента и сервера метрик.
+//
+// Использование:
+//
+//	monit-keys gen -type rsa [-bits 4096] [-private private.pem] [-public public.pem] [-force]
+//	monit-keys gen -type ed25519 [-private ed25519-key.pem] [-public ed25519.pem] [-force]
+//	monit-keys gen -type hmac [-bytes 32]
+//	monit-keys fingerprint <key.pem>...
+//	monit-keys validate -private <private.pem> -public <public.pem>
+//
+// Ключи RSA используются для шифрования тела запросов: открытый ключ передается агенту (-crypto-key),
+// закрытый - серверу (-crypto-key). Сервер принимает несколько закрытых ключей через запятую,
+// поэтому для смены ключа без простоя новый ключ сначала добавляется на сервер, затем раздается агентам,
+// после чего старый ключ удаляется из конфигурации сервера.
+//
+// Ключи Ed25519 используются для подписи запросов агента: закрытый ключ передается агенту (-sign-key),
+// открытый ключ кладется в каталог ключей сервера (-signature-keys) под именем <agent-id>.pem.
+//
+// Ключ HMAC - общий секрет подписи запросов агента и сервера (-k).
+package main
+
+import (
+	"crypto"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/gitslim/monit/internal/security"
+)
+
+// Размеры ключей по умолчанию.
+const (
+	defaultRSABits     = 4096
+	defaultHMACBytes   = 32
+	minRSABits         = 2048
+	minHMACBytes       = 16
+	privateKeyFileMode = 0o600
+	publicKeyFileMode  = 0o644
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("monit-keys: ")
+
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run выполняет подкоманду и выводит результат в out.
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: monit-keys gen|fingerprint|validate [flags]")
+	}
+
+	switch args[0] {
+	case "gen":
+		return runGen(args[1:], out)
+	case "fingerprint":
+		return runFingerprint(args[1:], out)
+	case "validate":
+		return runValidate(args[1:], out)
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runGen генерирует пару ключей или секрет HMAC.
+func runGen(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("gen", flag.ContinueOnError)
+	keyType := fs.String("type", "rsa", "Тип ключа: rsa, ed25519 или hmac")
+	bits := fs.Int("bits", defaultRSABits, "Размер ключа RSA (в битах)")
+	size := fs.Int("bytes", defaultHMACBytes, "Размер ключа HMAC (в байтах)")
+	privPath := fs.String("private", "", "Путь до файла закрытого ключа")
+	pubPath := fs.String("public", "", "Путь до файла открытого ключа")
+	force := fs.Bool("force", false, "Перезаписать существующие файлы ключей")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var pair *security.KeyPair
+	var err error
+	switch *keyType {
+	case "hmac":
+		if *size < minHMACBytes {
+			return fmt.Errorf("HMAC key must be at least %d bytes", minHMACBytes)
+		}
+		secret, err := security.GenerateHMACSecret(*size)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, secret)
+		return err
+	case "rsa":
+		if *bits < minRSABits {
+			return fmt.Errorf("RSA key must be at least %d bits", minRSABits)
+		}
+		setDefault(privPath, "private.pem")
+		setDefault(pubPath, "public.pem")
+		pair, err = security.GenerateRSAKeyPair(*bits)
+	case "ed25519":
+		setDefault(privPath, "ed25519-key.pem")
+		setDefault(pubPath, "ed25519.pem")
+		pair, err = security.GenerateEd25519KeyPair()
+	default:
+		return fmt.Errorf("unknown key type %q", *keyType)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := writeKeyFile(*privPath, pair.Private, privateKeyFileMode, *force); err != nil {
+		return err
+	}
+	if err := writeKeyFile(*pubPath, pair.Public, publicKeyFileMode, *force); err != nil {
+		return err
+	}
+
+	return printFingerprint(out, *pubPath)
+}
+
+// runFingerprint выводит отпечатки ключей.
+func runFingerprint(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: monit-keys fingerprint <key.pem>...")
+	}
+	for _, path := range args {
+		if err := printFingerprint(out, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runValidate проверяет соответствие закрытого и открытого ключей.
+func runValidate(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	privPath := fs.String("private", "", "Путь до файла закрытого ключа")
+	pubPath := fs.String("public", "", "Путь до файла открытого ключа")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *privPath == "" || *pubPath == "" {
+		return errors.New("both -private and -public are required")
+	}
+
+	priv, err := readKey(*privPath)
+	if err != nil {
+		return err
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("%s: not a private key", *privPath)
+	}
+	pub, err := readKey(*pubPath)
+	if err != nil {
+		return err
+	}
+	if _, ok := pub.(crypto.Signer); ok {
+		return fmt.Errorf("%s: not a public key", *pubPath)
+	}
+
+	if err := security.ValidateKeyPair(signer, pub); err != nil {
+		return err
+	}
+	fp, err := security.Fingerprint(pub)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "OK %s\n", fp)
+	return err
+}
+
+// printFingerprint выводит отпечаток ключа из файла.
+func printFingerprint(out io.Writer, path string) error {
+	key, err := readKey(path)
+	if err != nil {
+		return err
+	}
+	if signer, ok := key.(crypto.Signer); ok {
+		key = signer.Public()
+	}
+	fp, err := security.Fingerprint(key)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s  %s\n", fp, path)
+	return err
+}
+
+// readKey читает ключ из файла, добавляя путь к ошибке.
+func readKey(path string) (any, error) {
+	key, err := security.ReadKeyFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// writeKeyFile записывает ключ в файл, не перезаписывая существующий файл без force.
+// Права перезаписанного файла тоже меняются на mode, чтобы закрытый ключ не остался доступным другим.
+func writeKeyFile(path string, data []byte, mode os.FileMode, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, mode)
+	if err != nil {
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// setDefault задает значение флага, если оно не указано.
+func setDefault(v *string, def string) {
+	if *v == "" {
+		*v = def
+	}
+}
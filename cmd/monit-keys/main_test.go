@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// genPair генерирует пару ключей типа keyType в каталоге dir и возвращает пути до файлов.
+func genPair(t *testing.T, dir, keyType, name string, extra ...string) (string, string) {
+	t.Helper()
+	priv := filepath.Join(dir, name+"-key.pem")
+	pub := filepath.Join(dir, name+".pem")
+	args := append([]string{"gen", "-type", keyType, "-private", priv, "-public", pub}, extra...)
+	require.NoError(t, run(args, &bytes.Buffer{}))
+	return priv, pub
+}
+
+// fileMode возвращает права доступа к файлу.
+func fileMode(t *testing.T, path string) os.FileMode {
+	t.Helper()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	return info.Mode().Perm()
+}
+
+func TestRunUsage(t *testing.T) {
+	assert.Error(t, run(nil, &bytes.Buffer{}))
+	assert.ErrorContains(t, run([]string{"rotate"}, &bytes.Buffer{}), `unknown command "rotate"`)
+	assert.ErrorContains(t, run([]string{"gen", "-type", "dsa"}, &bytes.Buffer{}), `unknown key type "dsa"`)
+}
+
+func TestGen(t *testing.T) {
+	for _, tt := range []struct {
+		keyType string
+		extra   []string
+	}{
+		{keyType: "rsa", extra: []string{"-bits", "2048"}},
+		{keyType: "ed25519"},
+	} {
+		t.Run(tt.keyType, func(t *testing.T) {
+			dir := t.TempDir()
+			priv := filepath.Join(dir, "key.pem")
+			pub := filepath.Join(dir, "pub.pem")
+			args := append([]string{"gen", "-type", tt.keyType, "-private", priv, "-public", pub}, tt.extra...)
+
+			var out bytes.Buffer
+			require.NoError(t, run(args, &out))
+			assert.True(t, strings.HasSuffix(strings.TrimSpace(out.String()), pub))
+
+			// Закрытый ключ доступен только владельцу, открытый не доступен другим на запись.
+			assert.Equal(t, os.FileMode(privateKeyFileMode), fileMode(t, priv))
+			assert.Zero(t, fileMode(t, pub)&^publicKeyFileMode)
+
+			out.Reset()
+			require.NoError(t, run([]string{"validate", "-private", priv, "-public", pub}, &out))
+			assert.True(t, strings.HasPrefix(out.String(), "OK "))
+		})
+	}
+}
+
+func TestGenOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	priv, pub := genPair(t, dir, "ed25519", "agent")
+	before, err := os.ReadFile(priv)
+	require.NoError(t, err)
+
+	// Без -force существующие ключи не перезаписываются.
+	args := []string{"gen", "-type", "ed25519", "-private", priv, "-public", pub}
+	assert.ErrorIs(t, run(args, &bytes.Buffer{}), os.ErrExist)
+	after, err := os.ReadFile(priv)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	// С -force ключи перезаписываются, а права закрытого ключа восстанавливаются.
+	require.NoError(t, os.Chmod(priv, 0o644))
+	require.NoError(t, run(append(args, "-force"), &bytes.Buffer{}))
+	after, err = os.ReadFile(priv)
+	require.NoError(t, err)
+	assert.NotEqual(t, before, after)
+	assert.Equal(t, os.FileMode(privateKeyFileMode), fileMode(t, priv))
+	require.NoError(t, run([]string{"validate", "-private", priv, "-public", pub}, &bytes.Buffer{}))
+}
+
+func TestGenMinimumSize(t *testing.T) {
+	dir := t.TempDir()
+	priv := filepath.Join(dir, "key.pem")
+	pub := filepath.Join(dir, "pub.pem")
+
+	err := run([]string{"gen", "-type", "rsa", "-bits", "1024", "-private", priv, "-public", pub}, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "at least 2048 bits")
+	assert.NoFileExists(t, priv)
+
+	assert.ErrorContains(t, run([]string{"gen", "-type", "hmac", "-bytes", "8"}, &bytes.Buffer{}), "at least 16 bytes")
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"gen", "-type", "hmac", "-bytes", "16"}, &out))
+	assert.NotEmpty(t, strings.TrimSpace(out.String()))
+}
+
+func TestValidateMismatch(t *testing.T) {
+	dir := t.TempDir()
+	priv1, pub1 := genPair(t, dir, "ed25519", "agent-1")
+	_, pub2 := genPair(t, dir, "ed25519", "agent-2")
+	_, rsaPub := genPair(t, dir, "rsa", "rsa", "-bits", "2048")
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"validate", "-private", priv1, "-public", pub1}, &out))
+
+	assert.Error(t, run([]string{"validate", "-private", priv1, "-public", pub2}, &bytes.Buffer{}))
+	assert.Error(t, run([]string{"validate", "-private", priv1, "-public", rsaPub}, &bytes.Buffer{}))
+	assert.ErrorContains(t, run([]string{"validate", "-private", pub1, "-public", pub1}, &bytes.Buffer{}), "not a private key")
+	assert.ErrorContains(t, run([]string{"validate", "-private", priv1, "-public", priv1}, &bytes.Buffer{}), "not a public key")
+	assert.ErrorContains(t, run([]string{"validate", "-private", priv1}, &bytes.Buffer{}), "both -private and -public are required")
+}
+
+func TestFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	priv, pub := genPair(t, dir, "ed25519", "agent")
+	_, rsaPub := genPair(t, dir, "rsa", "rsa", "-bits", "2048")
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"fingerprint", priv, pub, rsaPub}, &out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+
+	// Отпечаток закрытого ключа совпадает с отпечатком его открытого ключа.
+	fps := make([]string, len(lines))
+	for i, path := range []string{priv, pub, rsaPub} {
+		fp, file, ok := strings.Cut(lines[i], "  ")
+		require.True(t, ok, lines[i])
+		assert.Equal(t, path, file)
+		fps[i] = fp
+	}
+	assert.Equal(t, fps[0], fps[1])
+	assert.NotEqual(t, fps[1], fps[2])
+
+	assert.Error(t, run([]string{"fingerprint"}, &bytes.Buffer{}))
+	missing := filepath.Join(dir, "missing.pem")
+	assert.ErrorContains(t, run([]string{"fingerprint", pub, missing}, &bytes.Buffer{}), missing)
+}
@@ -5,13 +5,21 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"time"
 
-	_ "net/http/pprof"
-
+	"github.com/gitslim/monit/internal/alerting"
+	"github.com/gitslim/monit/internal/auth"
+	"github.com/gitslim/monit/internal/debugserver"
 	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/notifier"
+	"github.com/gitslim/monit/internal/selfmetrics"
 	"github.com/gitslim/monit/internal/server"
 	"github.com/gitslim/monit/internal/server/conf"
+	"github.com/gitslim/monit/internal/server/engine"
 	"github.com/gitslim/monit/internal/services"
+	"github.com/gitslim/monit/internal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
@@ -33,10 +41,15 @@ func getOrDefault(value string) string {
 	return value
 }
 
-func main() {
-	// вывод информации о билде.
-	printBuildInfo()
+func buildInfo() debugserver.BuildInfo {
+	return debugserver.BuildInfo{
+		Version: getOrDefault(buildVersion),
+		Date:    getOrDefault(buildDate),
+		Commit:  getOrDefault(buildCommit),
+	}
+}
 
+func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -53,16 +66,47 @@ func main() {
 		log.Fatalf("Config parse failed: %v", err)
 	}
 
-	log.Debugf("Server config: %+v", cfg)
+	// Вывод итоговой конфигурации вместо запуска.
+	if cfg.PrintConfig {
+		if err := cfg.Dump(os.Stdout); err != nil {
+			log.Fatalf("Config print failed: %v", err)
+		}
+		return
+	}
+
+	// вывод информации о билде.
+	printBuildInfo()
+
+	// Переинициализация логгера с параметрами из конфига.
+	log, err = logging.NewLogger(cfg.LogOptions()...)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize logger: %v\n", err))
+	}
+	defer log.Close()
+
+	redacted := cfg.Redacted()
+	log.Debugf("Server config: %+v", &redacted)
 
 	// Инициализация хранилища.
+	// Пул соединений с базой данных общий для хранилища метрик и хранилища токенов агентов.
 	var metricConf services.MetricServiceConf
+	var pool *pgxpool.Pool
 	if cfg.DatabaseDSN != "" {
 		log.Debug("Using postgres storage")
-		metricConf, err = services.WithPGStorage(ctx, log, cfg)
+		pool, err = storage.CreateConnPool(cfg.DatabaseDSN)
+		if err != nil {
+			log.Fatalf("Postgres connection failed: %v", err)
+		}
+		metricConf, err = services.WithPGStorage(ctx, log, cfg, pool)
 		if err != nil {
 			log.Fatalf("Postgres storage configuration failed: %v", err)
 		}
+	} else if cfg.RedisAddr != "" {
+		log.Debug("Using redis storage")
+		metricConf, err = services.WithRedisStorage(cfg)
+		if err != nil {
+			log.Fatalf("Redis storage configuration failed: %v", err)
+		}
 	} else {
 		log.Debug("Using memory storage")
 		errCh := make(chan error)
@@ -79,19 +123,68 @@ func main() {
 	}
 
 	// Инициализация сервиса метрик.
-	svc, err := services.NewMetricService(metricConf)
+	historyConf := services.WithHistory(time.Duration(cfg.HistoryRetention))
+	selfMetricsConf := services.WithSelfMetrics(selfmetrics.NewRegistry())
+	svc, err := services.NewMetricService(metricConf, historyConf, selfMetricsConf)
 	if err != nil {
 		log.Fatalf("Metric service initialization failed: %v", err)
 	}
+	if cfg.SelfMetricsInterval > 0 {
+		go svc.StartSelfMetricsPublisher(ctx, log, time.Duration(cfg.SelfMetricsInterval))
+	}
+
+	// Инициализация доставки оповещений.
+	var n *notifier.Notifier
+	if cfg.Webhooks != "" {
+		receivers, err := notifier.LoadReceivers(cfg.Webhooks)
+		if err != nil {
+			log.Fatalf("Webhook receivers loading failed: %v", err)
+		}
+		log.Debugf("Loaded %d webhook receivers", len(receivers))
+
+		n = notifier.NewNotifier(log, &http.Client{}, receivers)
+		go n.Run(ctx)
+	}
 
-	// Запуск pprof сервера.
-	go func() {
-		err := http.ListenAndServe(":8081", nil)
+	// Инициализация движка оповещений.
+	var engineConfs []engine.EngineConf
+	if cfg.AlertRules != "" {
+		rules, err := alerting.LoadRules(cfg.AlertRules)
 		if err != nil {
-			log.Fatalf("pprof server failed: %v", err)
+			log.Fatalf("Alert rules loading failed: %v", err)
+		}
+		log.Debugf("Loaded %d alert rules", len(rules))
+
+		alertEngine := alerting.NewEngine(svc, log, rules)
+		if n != nil {
+			alertEngine.OnStateChange(n.NotifyAlert)
 		}
-	}()
+		go alertEngine.Run(ctx, time.Duration(cfg.AlertInterval))
+		engineConfs = append(engineConfs, engine.WithAlerts(log, alertEngine))
+	}
+
+	// Инициализация хранилища токенов агентов.
+	if cfg.AuthTokens != "" {
+		log.Debug("Using file token store")
+		store, err := auth.NewFileTokenStore(cfg.AuthTokens)
+		if err != nil {
+			log.Fatalf("Token store initialization failed: %v", err)
+		}
+		engineConfs = append(engineConfs, engine.WithTokenStore(store))
+	} else if cfg.AuthTokensDB {
+		log.Debug("Using postgres token store")
+		store, err := auth.NewPGTokenStore(ctx, pool)
+		if err != nil {
+			log.Fatalf("Token store initialization failed: %v", err)
+		}
+		engineConfs = append(engineConfs, engine.WithTokenStore(store))
+	}
+
+	// Запуск отладочного сервера.
+	if cfg.DebugAddr != "" {
+		debugserver.Start(ctx, log, cfg.DebugAddr, cfg.DebugToken, buildInfo())
+	}
 
 	// Запуск сервера.
-	server.Start(ctx, cfg, log, svc)
+	server.Start(ctx, cfg, log, svc, engineConfs...)
 }
@@ -3,6 +3,8 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,47 +13,90 @@ import (
 	"github.com/gitslim/monit/internal/agent/collector"
 	"github.com/gitslim/monit/internal/agent/conf"
 	"github.com/gitslim/monit/internal/agent/sender"
+	"github.com/gitslim/monit/internal/agent/telemetry"
 	"github.com/gitslim/monit/internal/agent/worker"
 	"github.com/gitslim/monit/internal/logging"
 )
 
 // Start запускает агент сбора метрик.
+//
+// При остановке агент прекращает сбор метрик и отправляет накопленные метрики на сервер
+// в течение cfg.ShutdownTimeout, после чего неотправленные метрики сохраняются в спул.
 func Start(cfg *conf.Config, log *logging.Logger) {
 	log.Info("Monit agent started")
 
-	// Таймаут ожидания завершения работы сервера.
-	gracefulTimeout := 5 * time.Second
-
-	// Контекст для graceful shutdown с таймаутом.
-	ctx, cancel := context.WithTimeout(context.Background(), gracefulTimeout)
+	// Контекст сбора метрик отменяется по сигналу завершения.
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Контекст отправки метрик отменяется по истечении времени на отправку накопленных метрик.
+	sendCtx, cancelSend := context.WithCancel(context.Background())
+	defer cancelSend()
+
 	// Канал для сигналов ОС.
 	quitChan := make(chan os.Signal, 1)
 	signal.Notify(quitChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer signal.Stop(quitChan)
 
 	// Создание пула worker'ов.
 	wp := worker.NewWorkerPool(cfg)
 
+	// Сборщики метрик с настройками из конфигурации.
+	collectors, err := collector.NewRegistry().Configure(time.Duration(cfg.PollInterval), cfg.Collectors)
+	if err != nil {
+		log.Fatalf("Failed to configure collectors: %v", err)
+	}
+
+	// HTTP-клиент с учетом параметров TLS.
+	client, err := sender.NewClient(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure TLS: %v", err)
+	}
+	wp.Client = client
+
 	// Запуск worker'ов отсылки метрик.
-	wp.Start(ctx, func(ctx context.Context) {
-		sender.RunSendMetricsWorker(ctx, log, wp)
+	wp.Start(sendCtx, func(ctx context.Context) {
+		sender.RunSendMetricsWorker(ctx, log, wp, time.Duration(cfg.ReportInterval))
 	})
 
+	// Отправка метрик, сохраненных в спул при предыдущей остановке.
+	if cfg.SpoolDir != "" {
+		wp.AddWorker(ctx, func(ctx context.Context) {
+			sender.RunSendSpooledWorker(ctx, log, wp, time.Duration(cfg.ReportInterval))
+		})
+	}
+
 	// Добавление worker'ов сбора метрик.
+	for _, c := range collectors {
+		log.Infof("Collector %s started with interval %s", c.Name, c.Interval)
+		wp.AddWorker(ctx, func(ctx context.Context) {
+			c.Run(ctx, log, wp)
+		})
+	}
+
+	// Метрики самого агента отправляются на сервер с той же периодичностью, что и остальные.
+	queued := func() int { return len(wp.Metrics) }
 	wp.AddWorker(ctx, func(ctx context.Context) {
-		collector.CollectRuntimeMetrics(ctx, log, wp)
-	})
-	wp.AddWorker(ctx, func(ctx context.Context) {
-		collector.CollectSystemMetrics(ctx, log, wp)
+		telemetry.Publish(ctx, wp.Telemetry, time.Duration(cfg.ReportInterval), queued, wp.Metrics)
 	})
 
+	// Локальный эндпоинт состояния агента.
+	if cfg.StatusAddr != "" {
+		wp.AddWorker(ctx, func(ctx context.Context) {
+			serveStatus(ctx, log, cfg, wp, queued)
+		})
+	}
+
 	// Ожидание сигнала завершения.
 	go func() {
 		quit := <-quitChan
 		log.Infof("Received signal: %v, shutting down...", quit)
-		cancel()  // Останавливаем контекст
-		wp.Stop() // Останавливаем worker'ов
+
+		// Время на отправку накопленных метрик ограничено.
+		time.AfterFunc(time.Duration(cfg.ShutdownTimeout), cancelSend)
+
+		cancel()  // Останавливаем сбор метрик
+		wp.Stop() // Worker'ы отправки отправляют накопленные метрики и завершаются
 	}()
 
 	// Ожидание завершения пула worker'ов.
@@ -59,3 +104,24 @@ func Start(cfg *conf.Config, log *logging.Logger) {
 
 	log.Info("Monit agent stopped.")
 }
+
+// serveStatus обслуживает локальный эндпоинт /status с состоянием агента до завершения контекста.
+func serveStatus(ctx context.Context, log *logging.Logger, cfg *conf.Config, wp *worker.WorkerPool, queued func() int) {
+	srv := &http.Server{
+		Addr:              cfg.StatusAddr,
+		Handler:           telemetry.StatusHandler(wp.Telemetry, cfg.Redacted(), queued),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Infof("Agent status endpoint listening on %s", cfg.StatusAddr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Errorf("Agent status endpoint failed: %v", err)
+	}
+}
@@ -7,17 +7,18 @@ import (
 
 	"github.com/gitslim/monit/internal/agent/conf"
 	"github.com/gitslim/monit/internal/agent/worker"
+	"github.com/gitslim/monit/internal/config"
 	"github.com/gitslim/monit/internal/entities"
 	"github.com/gitslim/monit/internal/logging"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TesCollectMetrics тестирует сбор метрик.
 func TestCollectMetrics(t *testing.T) {
 	// Создаем конфигурацию.
 	cfg := &conf.Config{
-		PollInterval: 1,
-		RateLimit:    5,
+		RateLimit: 5,
 	}
 
 	// Канал сбора метрик.
@@ -36,12 +37,13 @@ func TestCollectMetrics(t *testing.T) {
 	defer cancel()
 
 	// Добавление worker'ов сбора метрик.
-	wp.AddWorker(ctx, func(ctx context.Context) {
-		CollectRuntimeMetrics(ctx, log, wp)
-	})
-	wp.AddWorker(ctx, func(ctx context.Context) {
-		CollectSystemMetrics(ctx, log, wp)
-	})
+	collectors, err := NewRegistry().Configure(time.Second, nil)
+	require.NoError(t, err)
+	for _, c := range collectors {
+		wp.AddWorker(ctx, func(ctx context.Context) {
+			c.Run(ctx, log, wp)
+		})
+	}
 
 	// Даем время на сбор метрик.
 	time.Sleep(3 * time.Second)
@@ -68,3 +70,129 @@ func TestCollectMetrics(t *testing.T) {
 		assert.Contains(t, collected, metricName, "Metric %s should be collected", metricName)
 	}
 }
+
+func TestRegistryConfigure(t *testing.T) {
+	disabled := false
+	r := NewRegistry()
+	r.Register("custom", func() ([]entities.MetricDTO, error) { return nil, nil })
+	assert.Equal(t, []string{RuntimeCollector, SystemCollector, "custom"}, r.Names())
+
+	collectors, err := r.Configure(2*time.Second, []conf.CollectorConfig{
+		{Name: SystemCollector, Enabled: &disabled},
+		{Name: "custom", Interval: config.Seconds(30)},
+	})
+	require.NoError(t, err)
+	require.Len(t, collectors, 2)
+	assert.Equal(t, RuntimeCollector, collectors[0].Name)
+	assert.Equal(t, 2*time.Second, collectors[0].Interval)
+	assert.Equal(t, "custom", collectors[1].Name)
+	assert.Equal(t, 30*time.Second, collectors[1].Interval)
+
+	_, err = r.Configure(time.Second, []conf.CollectorConfig{{Name: "gpu"}, {Name: "disk"}})
+	assert.ErrorContains(t, err, "неизвестный сборщик метрик: gpu")
+	assert.ErrorContains(t, err, "неизвестный сборщик метрик: disk")
+}
+
+func TestCollectorFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  filter
+		allowed []string
+		denied  []string
+	}{
+		{
+			name:    "all",
+			allowed: []string{"Alloc", "CPUutilization1"},
+		},
+		{
+			name:    "include",
+			filter:  filter{include: []string{"Alloc", "CPUutilization*"}},
+			allowed: []string{"Alloc", "CPUutilization1", "CPUutilization12"},
+			denied:  []string{"HeapAlloc", "PollCount"},
+		},
+		{
+			name:    "exclude",
+			filter:  filter{exclude: []string{"Heap*", "RandomValue"}},
+			allowed: []string{"Alloc", "PollCount"},
+			denied:  []string{"HeapAlloc", "HeapSys", "RandomValue"},
+		},
+		{
+			name:    "include and exclude",
+			filter:  filter{include: []string{"CPUutilization*"}, exclude: []string{"CPUutilization1"}},
+			allowed: []string{"CPUutilization2", "CPUutilization10"},
+			denied:  []string{"CPUutilization1", "Alloc"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range tt.allowed {
+				assert.True(t, tt.filter.match(name), name)
+			}
+			for _, name := range tt.denied {
+				assert.False(t, tt.filter.match(name), name)
+			}
+		})
+	}
+}
+
+func TestCollectorRunFiltersMetrics(t *testing.T) {
+	wp := worker.NewWorkerPool(&conf.Config{RateLimit: 100})
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	collectors, err := NewRegistry().Configure(10*time.Millisecond, []conf.CollectorConfig{
+		{Name: RuntimeCollector, Include: []string{"Alloc", "PollCount"}},
+		{Name: SystemCollector, Exclude: []string{"*"}},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, c := range collectors {
+		wp.AddWorker(ctx, func(ctx context.Context) {
+			c.Run(ctx, log, wp)
+		})
+	}
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	wp.Stop()
+
+	collected := make(map[string]bool)
+	for metric := range wp.Metrics {
+		collected[metric.ID] = true
+	}
+	assert.Equal(t, map[string]bool{"Alloc": true, "PollCount": true}, collected)
+	assert.Contains(t, wp.Telemetry.Status(0).Collectors, SystemCollector)
+}
+
+// TestCollectorRunDurationExcludesBackpressure тестирует, что длительность сбора учитывается
+// сразу после сбора и не включает ожидание места в очереди отправки.
+func TestCollectorRunDurationExcludesBackpressure(t *testing.T) {
+	// Очередь на одну метрику без читателя: отправка второй метрики блокируется.
+	wp := worker.NewWorkerPool(&conf.Config{RateLimit: 1})
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	r := NewRegistry()
+	r.Register("test", func() ([]entities.MetricDTO, error) {
+		return []entities.MetricDTO{{ID: "m1", MType: "gauge"}, {ID: "m2", MType: "gauge"}}, nil
+	})
+	collectors, err := r.Configure(10*time.Millisecond, []conf.CollectorConfig{
+		{Name: RuntimeCollector, Enabled: new(bool)},
+		{Name: SystemCollector, Enabled: new(bool)},
+	})
+	require.NoError(t, err)
+	require.Len(t, collectors, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wp.AddWorker(ctx, func(ctx context.Context) {
+		collectors[0].Run(ctx, log, wp)
+	})
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	wp.Stop()
+
+	stat, ok := wp.Telemetry.Status(0).Collectors["test"]
+	require.True(t, ok)
+	assert.Equal(t, int64(1), stat.Runs)
+	assert.Less(t, stat.LastDurationSeconds, 0.05)
+}
@@ -1,2 +1,12 @@
 // Package collector собирает метрики компьютера с заданной периодичностью.
+//
+// Сборщики регистрируются в реестре Registry по именам. Для каждого сборщика в конфигурации
+// агента можно задать собственный интервал сбора, выключить его или ограничить набор
+// отправляемых метрик списками include/exclude.
 package collector
+
+// Имена встроенных сборщиков метрик.
+const (
+	RuntimeCollector = "runtime"
+	SystemCollector  = "system"
+)
@@ -0,0 +1,29 @@
+package collector
+
+import "path"
+
+// filter отбирает метрики сборщика по именам.
+type filter struct {
+	include []string
+	exclude []string
+}
+
+// match проверяет, отправляется ли метрика name. Пустой список include пропускает все метрики,
+// список exclude применяется после include. Шаблоны проверяются при разборе конфигурации,
+// поэтому некорректный шаблон считается несовпавшим.
+func (f filter) match(name string) bool {
+	if len(f.include) > 0 && !matchAny(f.include, name) {
+		return false
+	}
+	return !matchAny(f.exclude, name)
+}
+
+// matchAny проверяет, совпадает ли имя name с одним из шаблонов patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
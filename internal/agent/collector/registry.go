@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gitslim/monit/internal/agent/conf"
+	"github.com/gitslim/monit/internal/agent/worker"
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/logging"
+)
+
+// CollectFunc собирает метрики за один проход. При ошибке получения части данных
+// возвращает собранные метрики вместе с ошибкой.
+type CollectFunc func() ([]entities.MetricDTO, error)
+
+// Registry реестр сборщиков метрик.
+type Registry struct {
+	names    []string // Имена в порядке регистрации
+	collects map[string]CollectFunc
+}
+
+// NewRegistry создает реестр со встроенными сборщиками runtime и system.
+func NewRegistry() *Registry {
+	r := &Registry{collects: make(map[string]CollectFunc)}
+	r.Register(RuntimeCollector, collectRuntime)
+	r.Register(SystemCollector, collectSystem)
+	return r
+}
+
+// Register регистрирует сборщик collect под именем name, заменяя ранее зарегистрированный.
+func (r *Registry) Register(name string, collect CollectFunc) {
+	if _, ok := r.collects[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.collects[name] = collect
+}
+
+// Names возвращает имена зарегистрированных сборщиков в порядке регистрации.
+func (r *Registry) Names() []string {
+	return append([]string(nil), r.names...)
+}
+
+// Configure возвращает включенные сборщики с настройками cfgs. Сборщики без настроек
+// включены, собирают все метрики и используют интервал interval.
+// Возвращает ошибку, если в настройках указан незарегистрированный сборщик.
+func (r *Registry) Configure(interval time.Duration, cfgs []conf.CollectorConfig) ([]*Collector, error) {
+	byName := make(map[string]conf.CollectorConfig, len(cfgs))
+	var errList []error
+	for _, c := range cfgs {
+		if _, ok := r.collects[c.Name]; !ok {
+			errList = append(errList, fmt.Errorf("неизвестный сборщик метрик: %s", c.Name))
+			continue
+		}
+		byName[c.Name] = c
+	}
+	if err := errors.Join(errList...); err != nil {
+		return nil, err
+	}
+
+	var collectors []*Collector
+	for _, name := range r.names {
+		c := byName[name]
+		if !c.IsEnabled() {
+			continue
+		}
+		collector := &Collector{
+			Name:     name,
+			Interval: interval,
+			collect:  r.collects[name],
+			filter:   filter{include: c.Include, exclude: c.Exclude},
+		}
+		if c.Interval != 0 {
+			collector.Interval = time.Duration(c.Interval)
+		}
+		collectors = append(collectors, collector)
+	}
+	return collectors, nil
+}
+
+// Collector настроенный сборщик метрик.
+type Collector struct {
+	Name     string
+	Interval time.Duration
+	collect  CollectFunc
+	filter   filter
+}
+
+// Run собирает метрики с интервалом c.Interval и отправляет отобранные фильтром метрики
+// в канал wp.Metrics до завершения контекста.
+func (c *Collector) Run(ctx context.Context, log *logging.Logger, wp *worker.WorkerPool) {
+	// Таймер для периодического сбора метрик.
+	pollTicker := time.NewTicker(c.Interval)
+	defer pollTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-pollTicker.C:
+			start := time.Now()
+			metrics, err := c.collect()
+			// Длительность сбора не включает ожидание места в очереди отправки.
+			wp.Telemetry.RecordCollect(c.Name, time.Since(start), err)
+			if err != nil {
+				log.Errorf("Collector %s failed: %v", c.Name, err)
+			}
+
+			for _, metric := range metrics {
+				if !c.filter.match(metric.ID) {
+					continue
+				}
+				if !wp.Push(ctx, metric) {
+					// Отправка метрики в канал прервана остановкой агента.
+					return
+				}
+			}
+		}
+	}
+}
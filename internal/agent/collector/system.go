@@ -1,68 +1,48 @@
 package collector
 
 import (
-	"context"
+	"errors"
 	"fmt"
-	"time"
 
-	"github.com/gitslim/monit/internal/agent/worker"
 	"github.com/gitslim/monit/internal/entities"
-	"github.com/gitslim/monit/internal/logging"
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/mem"
 )
 
-// CollectSystemMetrics собирает метрики системной информации и отправляет их в канал wp.Metrics.
-func CollectSystemMetrics(ctx context.Context, log *logging.Logger, wp *worker.WorkerPool) {
-	// Таймер для периодического сбора метрик.
-	pollTicker := time.NewTicker(time.Duration(wp.Cfg.PollInterval * uint64(time.Second)))
-	defer pollTicker.Stop()
-
-	var metric *entities.MetricDTO
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-pollTicker.C:
-			// Данные виртуальной памяти.
-			vMem, err := mem.VirtualMemory()
-			if err != nil {
-				log.Errorf("failed to get memory info: %v", err)
-			} else {
-				metric, err = entities.NewMetricDTO("TotalMemory", "gauge", float64(vMem.Total))
-				if err != nil {
-					log.Error("Failed to create gauge DTO: TotalMemory")
-				} else {
-					wp.Metrics <- *metric
-				}
-
-				metric, err = entities.NewMetricDTO("FreeMemory", "gauge", float64(vMem.Free))
-				if err != nil {
-					log.Error("Failed to create gauge DTO: FreeMemory")
-				} else {
-					wp.Metrics <- *metric
-				}
-			}
+// collectSystem собирает метрики системы: объем памяти и утилизацию процессора по ядрам.
+// При ошибке получения части данных возвращает собранные метрики вместе с ошибкой.
+func collectSystem() ([]entities.MetricDTO, error) {
+	gauges := make(map[string]float64)
+	var errList []error
+
+	// Данные виртуальной памяти.
+	vMem, err := mem.VirtualMemory()
+	if err != nil {
+		errList = append(errList, fmt.Errorf("failed to get memory info: %w", err))
+	} else {
+		gauges["TotalMemory"] = float64(vMem.Total)
+		gauges["FreeMemory"] = float64(vMem.Free)
+	}
 
-			// Данные утилизации процессора.
-			cpuPercents, err := cpu.Percent(0, true)
-			if err != nil {
-				log.Errorf("failed to get CPU info: %v", err)
-			} else {
-				// Утилизация процессора по ядрам.
-				for i, cpuPercent := range cpuPercents {
-					metricName := fmt.Sprintf("CPUutilization%d", i+1)
-					metric, err = entities.NewMetricDTO(metricName, "gauge", cpuPercent)
-					if err != nil {
-						log.Errorf("Failed to create gauge DTO: %v", metricName)
-					} else {
-						// Отправка метрики в канал.
-						wp.Metrics <- *metric
-					}
+	// Данные утилизации процессора по ядрам.
+	cpuPercents, err := cpu.Percent(0, true)
+	if err != nil {
+		errList = append(errList, fmt.Errorf("failed to get CPU info: %w", err))
+	} else {
+		for i, cpuPercent := range cpuPercents {
+			gauges[fmt.Sprintf("CPUutilization%d", i+1)] = cpuPercent
+		}
+	}
 
-				}
-			}
+	metrics := make([]entities.MetricDTO, 0, len(gauges))
+	for k, v := range gauges {
+		gauge, err := entities.NewMetricDTO(k, "gauge", v)
+		if err != nil {
+			errList = append(errList, err)
+			continue
 		}
+		metrics = append(metrics, *gauge)
 	}
+
+	return metrics, errors.Join(errList...)
 }
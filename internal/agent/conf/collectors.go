@@ -0,0 +1,53 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/gitslim/monit/internal/config"
+)
+
+// CollectorConfig настройки сборщика метрик. Задаются только в конфигурационном файле.
+//
+// Списки Include и Exclude содержат имена метрик или шаблоны в формате path.Match
+// (например "CPUutilization*"). Пустой Include означает все метрики сборщика,
+// Exclude применяется после Include.
+type CollectorConfig struct {
+	Name     string          `json:"name" yaml:"name"`
+	Enabled  *bool           `json:"enabled,omitempty" yaml:"enabled,omitempty"`   // nil - сборщик включен
+	Interval config.Duration `json:"interval,omitempty" yaml:"interval,omitempty"` // 0 - интервал сбора метрик агента
+	Include  []string        `json:"include,omitempty" yaml:"include,omitempty"`
+	Exclude  []string        `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+// IsEnabled проверяет, включен ли сборщик.
+func (c *CollectorConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// validateCollectors проверяет настройки сборщиков метрик. Имена сборщиков проверяются
+// при их запуске, так как список сборщиков известен только пакету collector.
+func validateCollectors(collectors []CollectorConfig) []error {
+	var errList []error
+
+	seen := make(map[string]bool, len(collectors))
+	for _, c := range collectors {
+		if c.Name == "" {
+			errList = append(errList, errors.New("имя сборщика метрик не может быть пустым"))
+			continue
+		}
+		if seen[c.Name] {
+			errList = append(errList, fmt.Errorf("сборщик метрик %s настроен несколько раз", c.Name))
+		}
+		seen[c.Name] = true
+
+		for _, pattern := range append(append([]string(nil), c.Include...), c.Exclude...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errList = append(errList, fmt.Errorf("сборщик метрик %s: некорректный шаблон имени метрики %q", c.Name, pattern))
+			}
+		}
+	}
+
+	return errList
+}
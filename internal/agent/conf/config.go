@@ -2,132 +2,249 @@
 package conf
 
 import (
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
-	env "github.com/caarlos0/env/v6"
+	"github.com/gitslim/monit/internal/compression"
+	"github.com/gitslim/monit/internal/config"
+	"github.com/gitslim/monit/internal/debugserver"
+	"github.com/gitslim/monit/internal/logging"
 )
 
 // Значения по умолчанию для конфигурации.
 const (
-	DefaultAddr           = "localhost:8080"
-	DefaultPollInterval   = 2
-	DefaultReportInterval = 10
-	DefaultKey            = ""
-	DefaultRateLimit      = 10
-	DefaultCryptoKey      = ""
-	DefaultConfig         = ""
+	DefaultAddr                  = "localhost:8080"
+	DefaultPollInterval          = config.Duration(2 * time.Second)
+	DefaultReportInterval        = config.Duration(10 * time.Second)
+	DefaultKey                   = ""
+	DefaultRateLimit             = 10
+	DefaultCryptoKey             = ""
+	DefaultTLSCA                 = ""
+	DefaultTLSCert               = ""
+	DefaultTLSKey                = ""
+	DefaultToken                 = ""
+	DefaultSignKey               = ""
+	DefaultAgentID               = ""
+	DefaultCompression           = "gzip"
+	DefaultStatusAddr            = ""
+	DefaultShutdownTimeout       = config.Duration(5 * time.Second)
+	DefaultSpoolDir              = ""
+	DefaultDebugAddr             = ""
+	DefaultDebugToken            = ""
+	DefaultLogLevel              = logging.DefaultLevel
+	DefaultLogFormat             = logging.DefaultFormat
+	DefaultLogOutput             = logging.DefaultOutput
+	DefaultLogMaxSize            = logging.DefaultMaxSize
+	DefaultLogMaxBackups         = logging.DefaultMaxBackups
+	DefaultLogSamplingInitial    = logging.DefaultSamplingInitial
+	DefaultLogSamplingThereafter = logging.DefaultSamplingThereafter
+	DefaultConfig                = ""
 )
 
 // Config представляет конфигурацию агента сбора метрик.
 type Config struct {
-	Addr           string `env:"ADDRESS" json:"address"`
-	PollInterval   uint64 `env:"POLL_INTERVAL" json:"poll_interval"`
-	ReportInterval uint64 `env:"REPORT_INTERVAL" json:"report_interval"`
-	Key            string `env:"KEY" json:"key"`
-	RateLimit      uint64 `env:"RATE_LIMIT" json:"rate_limit"`
-	CryptoKey      string `env:"CRYPTO_KEY" json:"crypto_key"`
-	ConfigPath     string `env:"CONFIG" json:"-"`
+	Addr                  string            `env:"ADDRESS" json:"address" flag:"a" usage:"Адрес сервера (host:port)"`
+	PollInterval          config.Duration   `env:"POLL_INTERVAL" json:"poll_interval" flag:"p" usage:"Интервал сбора метрик (например 500ms, 10s или 5m, число без единиц - секунды)"`
+	ReportInterval        config.Duration   `env:"REPORT_INTERVAL" json:"report_interval" flag:"r" usage:"Интервал отправки метрик (например 500ms, 10s или 5m, число без единиц - секунды)"`
+	Key                   string            `env:"KEY" json:"key" flag:"k" usage:"Ключ шифрования" secret:"true"`
+	RateLimit             uint64            `env:"RATE_LIMIT" json:"rate_limit" flag:"l" usage:"Лимит запросов"`
+	CryptoKey             string            `env:"CRYPTO_KEY" json:"crypto_key" flag:"crypto-key" usage:"Публичный ключ шифрования"`
+	TLSCA                 string            `env:"TLS_CA" json:"tls_ca" flag:"tls-ca" usage:"Путь до сертификата CA для проверки сертификата сервера (PEM)"`
+	TLSCert               string            `env:"TLS_CERT" json:"tls_cert" flag:"tls-cert" usage:"Путь до сертификата агента (PEM) для mTLS"`
+	TLSKey                string            `env:"TLS_KEY" json:"tls_key" flag:"tls-key" usage:"Путь до приватного ключа сертификата агента (PEM)"`
+	Token                 string            `env:"TOKEN" json:"token" flag:"token" usage:"Токен доступа агента к серверу" secret:"true"`
+	SignKey               string            `env:"SIGN_KEY" json:"sign_key" flag:"sign-key" usage:"Путь до закрытого ключа Ed25519 агента (PEM) для подписи запросов"`
+	AgentID               string            `env:"AGENT_ID" json:"agent_id" flag:"agent-id" usage:"Идентификатор агента, под которым на сервере зарегистрирован его открытый ключ"`
+	Compression           string            `env:"COMPRESSION" json:"compression" flag:"compression" usage:"Алгоритм сжатия запросов: zstd, br, gzip, deflate или identity (без сжатия)"`
+	StatusAddr            string            `env:"STATUS_ADDR" json:"status_addr" flag:"status-addr" usage:"Адрес локального HTTP-эндпоинта /status с состоянием агента (host:port), пустое значение выключает его"`
+	ShutdownTimeout       config.Duration   `env:"SHUTDOWN_TIMEOUT" json:"shutdown_timeout" flag:"shutdown-timeout" usage:"Время на отправку накопленных метрик при остановке агента (например 500ms, 10s или 5m, число без единиц - секунды)"`
+	SpoolDir              string            `env:"SPOOL_DIR" json:"spool_dir" flag:"spool-dir" usage:"Каталог, в который сохраняются метрики, не отправленные при остановке агента, для отправки после перезапуска"`
+	DebugAddr             string            `env:"DEBUG_ADDR" json:"debug_addr" flag:"debug-addr" usage:"Адрес отладочного сервера pprof/expvar (host:port), пустое значение выключает его"`
+	DebugToken            string            `env:"DEBUG_TOKEN" json:"debug_token" flag:"debug-token" usage:"Токен доступа к отладочному серверу" secret:"true"`
+	LogLevel              string            `env:"LOG_LEVEL" json:"log_level" flag:"log-level" usage:"Уровень логгирования: debug, info, warn или error"`
+	LogFormat             string            `env:"LOG_FORMAT" json:"log_format" flag:"log-format" usage:"Формат логов: json или console"`
+	LogOutput             string            `env:"LOG_OUTPUT" json:"log_output" flag:"log-output" usage:"Вывод логов: stderr, stdout или путь до файла"`
+	LogMaxSize            uint64            `env:"LOG_MAX_SIZE" json:"log_max_size" flag:"log-max-size" usage:"Размер файла логов, после которого выполняется ротация (в мегабайтах, 0 - без ротации)"`
+	LogMaxBackups         uint64            `env:"LOG_MAX_BACKUPS" json:"log_max_backups" flag:"log-max-backups" usage:"Число хранимых старых файлов логов"`
+	LogSamplingInitial    uint64            `env:"LOG_SAMPLING_INITIAL" json:"log_sampling_initial" flag:"log-sampling-initial" usage:"Число одинаковых сообщений в секунду, выводимых без сэмплирования (0 - без сэмплирования)"`
+	LogSamplingThereafter uint64            `env:"LOG_SAMPLING_THEREAFTER" json:"log_sampling_thereafter" flag:"log-sampling-thereafter" usage:"Сверх этого выводится каждое N-е одинаковое сообщение"`
+	ConfigPath            string            `env:"CONFIG" json:"-" flag:"config" usage:"Путь до конфигурационного файла (JSON, YAML или TOML)"`
+	PrintConfig           bool              `json:"-" flag:"print-config" usage:"Вывести итоговую конфигурацию с источниками значений и завершить работу"`
+	Collectors            []CollectorConfig `json:"collectors"`
+
+	// sources источники значений полей конфигурации.
+	sources config.Sources
 }
 
-// ParseConfig парсит конфигурацию из json-конфига, флагов и переменных окружения.
-func ParseConfig() (*Config, error) {
-	configPath := flag.String("config", DefaultConfig, "Путь до конфигурационного файла (JSON)")
-	addr := flag.String("a", DefaultAddr, "Адрес сервера (host:port)")
-	pollInterval := flag.Uint64("p", DefaultPollInterval, "Интервал сбора метрик (сек)")
-	reportInterval := flag.Uint64("r", DefaultReportInterval, "Интервал отправки метрик (сек)")
-	key := flag.String("k", DefaultKey, "Ключ шифрования")
-	rateLimit := flag.Uint64("l", DefaultRateLimit, "Лимит запросов")
-	cryptoKey := flag.String("crypto-key", DefaultCryptoKey, "Публичный ключ шифрования")
-
-	// Парсим флаги
-	flag.Parse()
-
-	// Загружаем конфиг из JSON если путь указан
-	cfg := Config{
-		Addr:           DefaultAddr,
-		PollInterval:   DefaultPollInterval,
-		ReportInterval: DefaultReportInterval,
-		Key:            DefaultKey,
-		RateLimit:      DefaultRateLimit,
-		CryptoKey:      DefaultCryptoKey,
-		ConfigPath:     *configPath,
-	}
+// UseTLS проверяет, должен ли агент подключаться к серверу по HTTPS.
+// HTTPS используется, если адрес сервера задан со схемой https или заданы параметры TLS.
+func (cfg *Config) UseTLS() bool {
+	return strings.HasPrefix(cfg.Addr, "https://") || cfg.TLSCA != "" || cfg.TLSCert != ""
+}
 
-	if *configPath != "" {
-		if err := loadConfigFromJSON(*configPath, &cfg); err != nil {
-			return nil, err
-		}
+// ServerURL возвращает базовый URL сервера метрик.
+func (cfg *Config) ServerURL() string {
+	addr := strings.TrimSuffix(cfg.Addr, "/")
+	if strings.HasPrefix(addr, "http://") || strings.HasPrefix(addr, "https://") {
+		return addr
 	}
-
-	// Перезаписываем значениями из переменных окружения
-	if err := env.Parse(&cfg); err != nil {
-		return nil, fmt.Errorf("ошибка парсинга env: %w", err)
+	if cfg.UseTLS() {
+		return "https://" + addr
 	}
+	return "http://" + addr
+}
 
-	// Перезаписываем значениями флагов (если они были переданы)
-	if flag.Lookup("a").Value.String() != DefaultAddr {
-		cfg.Addr = *addr
+// Codec возвращает алгоритм сжатия запросов к серверу или nil, если запросы отправляются без сжатия.
+// Пустое значение в конфигурации означает алгоритм по умолчанию.
+func (cfg *Config) Codec() *compression.Codec {
+	name := cfg.Compression
+	if name == "" {
+		name = DefaultCompression
 	}
-	if flag.Lookup("p").Value.String() != fmt.Sprint(DefaultPollInterval) {
-		cfg.PollInterval = *pollInterval
+	codec, ok := compression.Lookup(name)
+	if !ok {
+		return nil
 	}
-	if flag.Lookup("r").Value.String() != fmt.Sprint(DefaultReportInterval) {
-		cfg.ReportInterval = *reportInterval
-	}
-	if flag.Lookup("k").Value.String() != DefaultKey {
-		cfg.Key = *key
-	}
-	if flag.Lookup("l").Value.String() != fmt.Sprint(DefaultRateLimit) {
-		cfg.RateLimit = *rateLimit
+	return codec
+}
+
+// Redacted возвращает копию конфигурации, в которой скрыты секреты: ключ HMAC и токены доступа.
+// Пути до файлов ключей секретами не считаются и выводятся как есть.
+func (cfg *Config) Redacted() Config {
+	c := *cfg
+	config.Redact(&c)
+	return c
+}
+
+// LogOptions возвращает параметры логгера из конфигурации.
+func (cfg *Config) LogOptions() []logging.Option {
+	return []logging.Option{
+		logging.WithLevel(cfg.LogLevel),
+		logging.WithFormat(cfg.LogFormat),
+		logging.WithOutput(cfg.LogOutput),
+		logging.WithRotation(cfg.LogMaxSize, cfg.LogMaxBackups),
+		logging.WithSampling(cfg.LogSamplingInitial, cfg.LogSamplingThereafter),
 	}
-	if flag.Lookup("crypto-key").Value.String() != DefaultCryptoKey {
-		cfg.CryptoKey = *cryptoKey
+}
+
+// ParseConfig парсит конфигурацию агента из конфигурационного файла, переменных окружения и флагов.
+func ParseConfig() (*Config, error) {
+	return parseConfig(flag.CommandLine, os.Args[1:])
+}
+
+// parseConfig парсит конфигурацию с флагами fs из аргументов args.
+// Ошибки значений переменных окружения и флагов возвращаются вместе с ошибками валидации.
+func parseConfig(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Config{
+		Addr:                  DefaultAddr,
+		PollInterval:          DefaultPollInterval,
+		ReportInterval:        DefaultReportInterval,
+		Key:                   DefaultKey,
+		RateLimit:             DefaultRateLimit,
+		CryptoKey:             DefaultCryptoKey,
+		TLSCA:                 DefaultTLSCA,
+		TLSCert:               DefaultTLSCert,
+		TLSKey:                DefaultTLSKey,
+		Token:                 DefaultToken,
+		SignKey:               DefaultSignKey,
+		AgentID:               DefaultAgentID,
+		Compression:           DefaultCompression,
+		StatusAddr:            DefaultStatusAddr,
+		ShutdownTimeout:       DefaultShutdownTimeout,
+		SpoolDir:              DefaultSpoolDir,
+		DebugAddr:             DefaultDebugAddr,
+		DebugToken:            DefaultDebugToken,
+		LogLevel:              DefaultLogLevel,
+		LogFormat:             DefaultLogFormat,
+		LogOutput:             DefaultLogOutput,
+		LogMaxSize:            DefaultLogMaxSize,
+		LogMaxBackups:         DefaultLogMaxBackups,
+		LogSamplingInitial:    DefaultLogSamplingInitial,
+		LogSamplingThereafter: DefaultLogSamplingThereafter,
+		ConfigPath:            DefaultConfig,
+	}
+
+	sources, err := config.Load(fs, args, &cfg)
+	if sources == nil {
+		return nil, err
 	}
+	cfg.sources = sources
 
-	// Валидация
-	if err := validateConfig(&cfg); err != nil {
+	if err := errors.Join(err, validateConfig(&cfg)); err != nil {
 		return nil, err
 	}
 
 	return &cfg, nil
 }
 
-// loadConfigFromJSON загружает конфигурацию из JSON-файла.
-func loadConfigFromJSON(path string, cfg *Config) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return fmt.Errorf("не удалось открыть файл конфигурации: %w", err)
-	}
-	defer func() { _ = file.Close() }()
-
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(cfg); err != nil {
-		return fmt.Errorf("ошибка декодирования JSON: %w", err)
-	}
-	return nil
+// Dump выводит итоговую конфигурацию со скрытыми секретами и источниками значений в формате YAML.
+func (cfg *Config) Dump(w io.Writer) error {
+	c := cfg.Redacted()
+	return config.Dump(w, &c, cfg.sources)
 }
 
-// valiadteConfig - проверка конфига на корректность.
+// validateConfig проверяет конфигурацию и возвращает все найденные ошибки.
 func validateConfig(cfg *Config) error {
+	var errList []error
+
 	if cfg.Addr == "" {
-		return errors.New("адрес сервера не может быть пустым")
+		errList = append(errList, errors.New("адрес сервера не может быть пустым"))
 	}
 
 	if cfg.PollInterval == 0 {
-		return errors.New("интервал сбора метрик не может быть равен 0")
+		errList = append(errList, errors.New("интервал сбора метрик не может быть равен 0"))
 	}
 
 	if cfg.ReportInterval == 0 {
-		return errors.New("интервал отправки метрик на сервер не может быть равен 0")
+		errList = append(errList, errors.New("интервал отправки метрик на сервер не может быть равен 0"))
 	}
 
 	if cfg.RateLimit == 0 {
-		return errors.New("лимит одновременно исходящих запросов на отправку метрик не может быть равен 0")
+		errList = append(errList, errors.New("лимит одновременно исходящих запросов на отправку метрик не может быть равен 0"))
+	}
+
+	if cfg.ShutdownTimeout == 0 {
+		errList = append(errList, errors.New("время на отправку метрик при остановке агента не может быть равно 0"))
+	}
+
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		errList = append(errList, fmt.Errorf("неизвестный уровень логгирования: %s", cfg.LogLevel))
 	}
 
-	return nil
+	if !logging.IsFormat(cfg.LogFormat) {
+		errList = append(errList, fmt.Errorf("неизвестный формат логов: %s", cfg.LogFormat))
+	}
+
+	if cfg.LogOutput == "" {
+		errList = append(errList, errors.New("вывод логов не может быть пустым"))
+	}
+
+	if cfg.DebugAddr != "" && cfg.DebugToken == "" && !debugserver.IsLoopback(cfg.DebugAddr) {
+		errList = append(errList, errors.New("отладочный сервер на внешнем адресе требует токена доступа"))
+	}
+
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		errList = append(errList, errors.New("сертификат и ключ TLS агента должны быть заданы вместе"))
+	}
+
+	if cfg.SignKey != "" && cfg.AgentID == "" {
+		errList = append(errList, errors.New("подпись запросов ключом Ed25519 требует идентификатора агента"))
+	}
+
+	if _, ok := compression.Lookup(cfg.Compression); !ok && !compression.IsIdentity(cfg.Compression) {
+		errList = append(errList, fmt.Errorf("неизвестный алгоритм сжатия запросов: %s", cfg.Compression))
+	}
+
+	if strings.HasPrefix(cfg.Addr, "http://") && cfg.UseTLS() {
+		errList = append(errList, errors.New("параметры TLS заданы для адреса сервера со схемой http"))
+	}
+
+	errList = append(errList, validateCollectors(cfg.Collectors)...)
+
+	return errors.Join(errList...)
 }
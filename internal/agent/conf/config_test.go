@@ -0,0 +1,72 @@
+package conf
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parse парсит конфигурацию агента из аргументов args.
+func parse(args ...string) (*Config, error) {
+	fs := flag.NewFlagSet("agent", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return parseConfig(fs, args)
+}
+
+// writeConfig создает в temp-каталоге теста конфигурационный файл name с содержимым content.
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestParseConfigCollectors(t *testing.T) {
+	path := writeConfig(t, "agent.json", `{
+		"collectors": [
+			{"name": "runtime", "interval": "1s", "include": ["Alloc", "PollCount"]},
+			{"name": "system", "enabled": false, "exclude": ["CPUutilization*"]}
+		]
+	}`)
+
+	cfg, err := parse("-config", path)
+	require.NoError(t, err)
+	require.Len(t, cfg.Collectors, 2)
+	assert.Equal(t, "runtime", cfg.Collectors[0].Name)
+	assert.Equal(t, time.Second, time.Duration(cfg.Collectors[0].Interval))
+	assert.Equal(t, []string{"Alloc", "PollCount"}, cfg.Collectors[0].Include)
+	assert.True(t, cfg.Collectors[0].IsEnabled())
+	assert.False(t, cfg.Collectors[1].IsEnabled())
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.Dump(&buf))
+	assert.Contains(t, buf.String(), "collectors: # file "+path)
+	assert.Contains(t, buf.String(), "interval: 1s")
+	assert.Contains(t, buf.String(), "enabled: false")
+}
+
+func TestParseConfigCollectorsErrors(t *testing.T) {
+	path := writeConfig(t, "agent.yaml", `collectors:
+  - name: runtime
+    include: ["Heap["]
+  - name: runtime
+  - interval: 5s
+`)
+
+	_, err := parse("-config", path)
+	require.Error(t, err)
+	for _, msg := range []string{
+		`сборщик метрик runtime: некорректный шаблон имени метрики "Heap["`,
+		"сборщик метрик runtime настроен несколько раз",
+		"имя сборщика метрик не может быть пустым",
+	} {
+		assert.ErrorContains(t, err, msg)
+	}
+}
@@ -0,0 +1,23 @@
+package sender
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/gitslim/monit/internal/compression"
+)
+
+// compress сжимает данные алгоритмом codec.
+func compress(codec *compression.Codec, data []byte) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+
+	w := codec.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return nil, fmt.Errorf("failed to write to %s writer: %v", codec.Name(), err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close %s writer: %v", codec.Name(), err)
+	}
+	return &buf, nil
+}
@@ -0,0 +1,241 @@
+package sender_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/agent/conf"
+	"github.com/gitslim/monit/internal/agent/sender"
+	"github.com/gitslim/monit/internal/config"
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/security"
+	serverconf "github.com/gitslim/monit/internal/server/conf"
+	"github.com/gitslim/monit/internal/server/engine"
+	"github.com/gitslim/monit/internal/services"
+	"github.com/gitslim/monit/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSendMetricsTLS тестирует отправку метрик по HTTPS с проверкой сертификата агента (mTLS).
+func TestSendMetricsTLS(t *testing.T) {
+	certs, err := testhelpers.GenerateCerts(t.TempDir())
+	require.NoError(t, err)
+
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	srvCfg := &serverconf.Config{
+		FileStoragePath: t.TempDir() + "/memstorage.json",
+		TLSCert:         certs.ServerCert,
+		TLSKey:          certs.ServerKey,
+		TLSClientCA:     certs.CA,
+	}
+	svcCfg, err := services.WithMemStorage(context.Background(), log, srvCfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+	r, err := engine.CreateGinEngine(srvCfg, log, gin.TestMode, metricService)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(r)
+	srv.TLS, err = security.ServerTLSConfig(srvCfg.TLSCert, srvCfg.TLSKey, srvCfg.TLSClientCA)
+	require.NoError(t, err)
+	srv.StartTLS()
+	defer srv.Close()
+
+	metrics, err := testhelpers.JSONToMetricDTO(`[{"id": "tls_gauge", "type": "gauge", "value": 1.5}]`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		cfg     *conf.Config
+		wantErr bool
+	}{
+		{
+			name: "client certificate",
+			cfg: &conf.Config{
+				Addr:    srv.Listener.Addr().String(),
+				TLSCA:   certs.CA,
+				TLSCert: certs.ClientCert,
+				TLSKey:  certs.ClientKey,
+			},
+		},
+		{
+			name: "no client certificate",
+			cfg: &conf.Config{
+				Addr:  srv.Listener.Addr().String(),
+				TLSCA: certs.CA,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown server CA",
+			cfg: &conf.Config{
+				Addr: "https://" + srv.Listener.Addr().String(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := sender.NewClient(tt.cfg)
+			require.NoError(t, err)
+
+			err = sender.SendMetrics(context.Background(), tt.cfg, client, metrics, true)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			m, err := metricService.GetMetric("tls_gauge", "gauge")
+			require.NoError(t, err)
+			assert.Equal(t, 1.5, m.GetValue())
+		})
+	}
+}
+
+// TestSendMetricsSigned тестирует отправку подписанных метрик на сервер, проверяющий подпись.
+func TestSendMetricsSigned(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	srvCfg := &serverconf.Config{
+		FileStoragePath: t.TempDir() + "/memstorage.json",
+		Key:             "secret",
+		SignatureWindow: config.Seconds(60),
+	}
+	svcCfg, err := services.WithMemStorage(context.Background(), log, srvCfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+	r, err := engine.CreateGinEngine(srvCfg, log, gin.TestMode, metricService)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	metrics, err := testhelpers.JSONToMetricDTO(`[{"id": "signed_counter", "type": "counter", "delta": 2}]`)
+	require.NoError(t, err)
+
+	cfg := &conf.Config{Addr: srv.URL, Key: "secret"}
+	for _, batch := range []bool{true, false} {
+		require.NoError(t, sender.SendMetrics(context.Background(), cfg, srv.Client(), metrics, batch))
+	}
+
+	m, err := metricService.GetMetric("signed_counter", "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), m.GetValue())
+
+	cfg.Key = "wrong"
+	assert.Error(t, sender.SendMetrics(context.Background(), cfg, srv.Client(), metrics, true))
+}
+
+// TestSendMetricsEd25519 тестирует отправку метрик, подписанных ключом Ed25519 агента.
+func TestSendMetricsEd25519(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	pubDir, privDir := t.TempDir(), t.TempDir()
+	priv1, err := testhelpers.GenerateEd25519Keys(pubDir, privDir, "agent-1")
+	require.NoError(t, err)
+	priv2, err := testhelpers.GenerateEd25519Keys(pubDir, privDir, "agent-2")
+	require.NoError(t, err)
+
+	srvCfg := &serverconf.Config{
+		FileStoragePath: t.TempDir() + "/memstorage.json",
+		SignatureKeys:   pubDir,
+		SignatureWindow: config.Seconds(60),
+	}
+	svcCfg, err := services.WithMemStorage(context.Background(), log, srvCfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+	r, err := engine.CreateGinEngine(srvCfg, log, gin.TestMode, metricService)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	metrics, err := testhelpers.JSONToMetricDTO(`[{"id": "ed_gauge", "type": "gauge", "value": 3}]`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		cfg     *conf.Config
+		wantErr bool
+	}{
+		{name: "registered agent", cfg: &conf.Config{Addr: srv.URL, SignKey: priv1, AgentID: "agent-1"}},
+		{name: "unknown agent", cfg: &conf.Config{Addr: srv.URL, SignKey: priv1, AgentID: "agent-3"}, wantErr: true},
+		{name: "impersonation", cfg: &conf.Config{Addr: srv.URL, SignKey: priv2, AgentID: "agent-1"}, wantErr: true},
+		{name: "hmac disabled", cfg: &conf.Config{Addr: srv.URL, Key: "secret"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sender.SendMetrics(context.Background(), tt.cfg, srv.Client(), metrics, true)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	u, ok := metricService.LastUpdate("ed_gauge", entities.Gauge)
+	require.True(t, ok)
+	assert.Equal(t, "agent-1", u.Agent)
+}
+
+// TestSendMetricsKeyRotation тестирует расшифровку запросов сервером с несколькими ключами.
+func TestSendMetricsKeyRotation(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writeKeys := func(name string) (string, string) {
+		pair, err := security.GenerateRSAKeyPair(2048)
+		require.NoError(t, err)
+		priv, pub := filepath.Join(dir, name+"-key.pem"), filepath.Join(dir, name+".pem")
+		require.NoError(t, os.WriteFile(priv, pair.Private, 0o600))
+		require.NoError(t, os.WriteFile(pub, pair.Public, 0o600))
+		return priv, pub
+	}
+	oldPriv, oldPub := writeKeys("old")
+	newPriv, newPub := writeKeys("new")
+	_, unknownPub := writeKeys("unknown")
+
+	srvCfg := &serverconf.Config{
+		FileStoragePath: filepath.Join(dir, "memstorage.json"),
+		CryptoKey:       oldPriv + "," + newPriv,
+	}
+	svcCfg, err := services.WithMemStorage(context.Background(), log, srvCfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+	r, err := engine.CreateGinEngine(srvCfg, log, gin.TestMode, metricService)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	metrics, err := testhelpers.JSONToMetricDTO(`[{"id": "rotated", "type": "counter", "delta": 1}]`)
+	require.NoError(t, err)
+
+	// Агенты со старым и новым ключом обслуживаются одновременно.
+	for _, pub := range []string{oldPub, newPub} {
+		cfg := &conf.Config{Addr: srv.URL, CryptoKey: pub}
+		require.NoError(t, sender.SendMetrics(context.Background(), cfg, srv.Client(), metrics, false))
+	}
+	m, err := metricService.GetMetric("rotated", "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), m.GetValue())
+
+	cfg := &conf.Config{Addr: srv.URL, CryptoKey: unknownPub}
+	assert.Error(t, sender.SendMetrics(context.Background(), cfg, srv.Client(), metrics, true))
+}
@@ -1,136 +1,236 @@
 package sender
 
 import (
-	"compress/gzip"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gitslim/monit/internal/agent/conf"
 	"github.com/gitslim/monit/internal/entities"
 	"github.com/gitslim/monit/internal/httpconst"
+	"github.com/gitslim/monit/internal/requestid"
 	"github.com/gitslim/monit/internal/retry"
 	"github.com/gitslim/monit/internal/security"
 )
 
 // encryptData шифрует данные перед отправкой.
-func encryptData(cfg *conf.Config, data []byte) ([]byte, error) {
+// Возвращает отпечаток открытого ключа шифрования, по которому сервер выбирает ключ расшифровки.
+func encryptData(cfg *conf.Config, data []byte) ([]byte, string, error) {
 	// Если задан ключ шифрования, шифруем данные.
-	if cfg.CryptoKey != "" {
-		pubKey, err := security.ReadRSAPublicKeyFromFile(cfg.CryptoKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read public key: %v", err)
-		}
-		data, err = security.EncryptRSA(pubKey, data)
-		if err != nil {
-			return nil, fmt.Errorf("failed to encrypt body: %v", err)
-		}
+	if cfg.CryptoKey == "" {
+		return data, "", nil
+	}
+
+	pubKey, err := security.ReadRSAPublicKeyFromFile(cfg.CryptoKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read public key: %v", err)
+	}
+	fingerprint, err := security.Fingerprint(pubKey)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err = security.EncryptRSA(pubKey, data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encrypt body: %v", err)
 	}
-	return data, nil
+	return data, fingerprint, nil
 }
 
 // signRequest подписывает запрос перед отправкой.
-func signRequest(req *http.Request, cfg *conf.Config) error {
-	// Если задан ключ, подписываем данные.
-	if cfg.Key != "" {
-		body, err := req.GetBody()
-		if err != nil {
-			return err
-		}
-		defer func() { _ = body.Close() }()
+// Подписывается исходное тело запроса body, которое сервер получит после распаковки и расшифровки.
+// Если задан ключ Ed25519 агента, запрос подписывается им, иначе общим ключом HMAC.
+func signRequest(req *http.Request, cfg *conf.Config, body []byte) error {
+	if cfg.Key == "" && cfg.SignKey == "" {
+		return nil
+	}
+
+	nonce, err := security.NewNonce()
+	if err != nil {
+		return err
+	}
+
+	sr := security.SignedRequest{
+		Timestamp: time.Now(),
+		Nonce:     nonce,
+		Method:    req.Method,
+		Path:      req.URL.Path,
+		Body:      body,
+	}
 
-		// Подписываем данные.
-		hash, err := security.MakeSHA256Hash(body, cfg.Key)
+	// Записываем подпись, время и nonce в заголовки.
+	if cfg.SignKey != "" {
+		key, err := security.ReadEd25519PrivateKeyFromFile(cfg.SignKey)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to read signing key: %v", err)
 		}
-
-		// Записываем хэш в заголовок.
-		req.Header.Set(httpconst.HeaderHashSHA256, hash)
+		req.Header.Set(httpconst.HeaderSignatureEd25519, sr.SignEd25519(key))
+		req.Header.Set(httpconst.HeaderAgentID, cfg.AgentID)
+	} else {
+		req.Header.Set(httpconst.HeaderHashSHA256, sr.Sign(cfg.Key))
 	}
+	req.Header.Set(httpconst.HeaderSignatureTimestamp, strconv.FormatInt(sr.Timestamp.Unix(), 10))
+	req.Header.Set(httpconst.HeaderSignatureNonce, nonce)
 	return nil
 }
 
+// NewClient создает HTTP-клиент агента с учетом параметров TLS из конфигурации.
+func NewClient(cfg *conf.Config) (*http.Client, error) {
+	if !cfg.UseTLS() {
+		return &http.Client{}, nil
+	}
+
+	tlsCfg, err := security.ClientTLSConfig(cfg.TLSCA, cfg.TLSCert, cfg.TLSKey)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+	return &http.Client{Transport: transport}, nil
+}
+
+// statusError ошибка ответа сервера с неуспешным кодом.
+type statusError struct {
+	code       int
+	requestID  string
+	retryAfter time.Duration
+}
+
+// Error реализует интерфейс error.
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d (request id %s)", e.code, e.requestID)
+}
+
+// IsRetriable реализует интерфейс retry.IRetriableError.
+// Повторяются запросы, отклоненные из-за превышения частоты или временной недоступности сервера.
+func (e *statusError) IsRetriable() bool {
+	return e.code == http.StatusTooManyRequests || e.code == http.StatusServiceUnavailable
+}
+
+// RetryAfter реализует интерфейс retry.IRetryAfterError.
+func (e *statusError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// parseRetryAfter разбирает заголовок Retry-After, заданный числом секунд или датой HTTP.
+// Возвращает 0, если заголовок отсутствует или некорректен.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
 // sendJSON отправляет метрики в формате JSON батчем или по одной.
 func sendJSON(ctx context.Context, cfg *conf.Config, client *http.Client, url string, jsonData []byte) error {
 	// Шифруем данные если необходимо.
-	jsonData, err := encryptData(cfg, jsonData)
+	body, fingerprint, err := encryptData(cfg, jsonData)
 	if err != nil {
 		return fmt.Errorf("failed to encrypt body: %v", err)
 	}
 
-	// Сжимаем данные в gzip
-	buf, err := compressGzip(jsonData, gzip.BestSpeed)
-	if err != nil {
-		return fmt.Errorf("failed to compress with gzip: %v", err)
+	// Сжимаем данные выбранным алгоритмом.
+	codec := cfg.Codec()
+	var reqBody io.Reader = bytes.NewReader(body)
+	if codec != nil {
+		buf, err := compress(codec, body)
+		if err != nil {
+			return fmt.Errorf("failed to compress with %s: %v", codec.Name(), err)
+		}
+		reqBody = buf
 	}
 
 	// Таймаут запроса.
 	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, url, buf)
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, url, reqBody)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %v", err)
 	}
 
+	// Идентификатор запроса позволяет найти запись о нем в логе сервера.
+	requestID := requestid.New()
+	req.Header.Set(httpconst.HeaderRequestID, requestID)
 	req.Header.Set(httpconst.HeaderContentType, httpconst.ContentTypeJSON)
-	req.Header.Set(httpconst.HeaderContentEncoding, httpconst.ContentEncodingGzip)
+	if codec != nil {
+		req.Header.Set(httpconst.HeaderContentEncoding, codec.Name())
+	}
+	if fingerprint != "" {
+		req.Header.Set(httpconst.HeaderEncryptionKey, fingerprint)
+	}
+	if cfg.Token != "" {
+		req.Header.Set(httpconst.HeaderAuthorization, httpconst.AuthSchemeBearer+" "+cfg.Token)
+	}
 
 	// Подписываем запрос если необходимо.
-	err = signRequest(req, cfg)
+	err = signRequest(req, cfg, jsonData)
 	if err != nil {
 		return fmt.Errorf("failed to sign request: %v", err)
 	}
 
 	res, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %v", err)
+		return fmt.Errorf("failed to send request (request id %s): %v", requestID, err)
 	}
 	defer func() { _ = res.Body.Close() }()
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", res.StatusCode)
+		return &statusError{
+			code:       res.StatusCode,
+			requestID:  requestID,
+			retryAfter: parseRetryAfter(res.Header.Get(httpconst.HeaderRetryAfter), time.Now()),
+		}
 	}
 
 	return nil
 }
 
 // SendMetrics отправляет метрики на сервер в формате JSON батчем или по одной.
+// Каждый запрос повторяется отдельно, чтобы при повторе не отправлять уже принятые сервером метрики.
 func SendMetrics(ctx context.Context, cfg *conf.Config, client *http.Client, metrics []*entities.MetricDTO, batch bool) error {
-	serverURL := fmt.Sprintf("http://%s", cfg.Addr)
-
-	// Ретраи при сбое.
-	return retry.Retry(func() error {
-		var url string
-		var jsonData []byte
-		var err error
-
-		if batch {
-			// Отправляем батч метрик.
-			url = fmt.Sprintf("%s/updates/", serverURL)
-			jsonData, err = json.Marshal(metrics)
-			if err != nil {
-				return err
-			}
+	serverURL := cfg.ServerURL()
+
+	send := func(url string, jsonData []byte) error {
+		// Ретраи при сбое.
+		return retry.RetryContext(ctx, func() error {
 			return sendJSON(ctx, cfg, client, url, jsonData)
-		} else {
-			// Отправляем метрики по одной.
-			url = fmt.Sprintf("%s/update/", serverURL)
-
-			for _, metric := range metrics {
-				jsonData, err = json.Marshal(&metric)
-				if err != nil {
-					return err
-				}
-				err := sendJSON(ctx, cfg, client, url, jsonData)
-				if err != nil {
-					return err
-				}
-			}
-			return nil
+		}, 3)
+	}
+
+	if batch {
+		// Отправляем батч метрик.
+		jsonData, err := json.Marshal(metrics)
+		if err != nil {
+			return err
+		}
+		return send(fmt.Sprintf("%s/updates/", serverURL), jsonData)
+	}
+
+	// Отправляем метрики по одной.
+	url := fmt.Sprintf("%s/update/", serverURL)
+	for _, metric := range metrics {
+		jsonData, err := json.Marshal(&metric)
+		if err != nil {
+			return err
 		}
-	}, 3)
+		if err := send(url, jsonData); err != nil {
+			return err
+		}
+	}
+	return nil
 }
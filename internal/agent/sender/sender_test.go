@@ -3,12 +3,18 @@ package sender_test
 import (
 	"context"
 	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gitslim/monit/internal/agent/conf"
 	"github.com/gitslim/monit/internal/agent/sender"
+	"github.com/gitslim/monit/internal/compression"
+	"github.com/gitslim/monit/internal/requestid"
 	"github.com/gitslim/monit/internal/testhelpers"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSendMetrics(t *testing.T) {
@@ -60,7 +66,7 @@ func TestSendMetrics(t *testing.T) {
 			wantErr:  false,
 		},
 		{
-			jsonData: `[{"id": "test_counter", "type": "counter", "value": 10}]`,
+			jsonData: `[{"id": "test_counter", "type": "counter", "delta": 10}]`,
 			batch:    true,
 			key:      "some-key",
 			wantErr:  false,
@@ -81,3 +87,81 @@ func TestSendMetrics(t *testing.T) {
 		})
 	}
 }
+
+// TestSendMetricsRetryAfter тестирует повтор отклоненных запросов через время из заголовка Retry-After.
+func TestSendMetricsRetryAfter(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int32
+		wantErr   bool
+		minDelay  time.Duration
+	}{
+		{name: "too many requests", status: http.StatusTooManyRequests, wantCalls: 2, minDelay: 2 * time.Second},
+		{name: "too large", status: http.StatusRequestEntityTooLarge, wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					w.Header().Set("Retry-After", "2")
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			metrics, err := testhelpers.JSONToMetricDTO(`[{"id": "g1", "type": "gauge", "value": 1.0}]`)
+			require.NoError(t, err)
+
+			start := time.Now()
+			err = sender.SendMetrics(context.Background(), &conf.Config{Addr: srv.URL}, srv.Client(), metrics, true)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCalls, calls.Load())
+			assert.GreaterOrEqual(t, time.Since(start), tt.minDelay)
+		})
+	}
+}
+
+// TestSendMetricsRequestID тестирует передачу идентификатора запроса на сервер.
+func TestSendMetricsRequestID(t *testing.T) {
+	ids := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids <- r.Header.Get("X-Request-ID")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	metrics, err := testhelpers.JSONToMetricDTO(`[{"id": "g1", "type": "gauge", "value": 1.0}, {"id": "g2", "type": "gauge", "value": 2.0}]`)
+	require.NoError(t, err)
+	require.NoError(t, sender.SendMetrics(context.Background(), &conf.Config{Addr: srv.URL}, srv.Client(), metrics, false))
+
+	first, second := <-ids, <-ids
+	assert.True(t, requestid.Valid(first))
+	assert.NotEqual(t, first, second)
+}
+
+// TestSendMetricsCompression тестирует отправку метрик с разными алгоритмами сжатия.
+func TestSendMetricsCompression(t *testing.T) {
+	r, teardown, err := testhelpers.CreateServerMock(false)
+	require.NoError(t, err)
+	defer teardown()
+	srv, teardown, err := testhelpers.StartServerMock(r)
+	require.NoError(t, err)
+	defer teardown()
+
+	for _, name := range append(compression.Names(), "identity") {
+		t.Run(name, func(t *testing.T) {
+			metrics, err := testhelpers.JSONToMetricDTO(`[{"id": "g1", "type": "gauge", "value": 1.0}]`)
+			require.NoError(t, err)
+			cfg := &conf.Config{Addr: srv.Addr, Compression: name}
+			assert.NoError(t, sender.SendMetrics(context.Background(), cfg, &http.Client{}, metrics, true))
+		})
+	}
+}
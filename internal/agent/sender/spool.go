@@ -0,0 +1,107 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gitslim/monit/internal/agent/worker"
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/requestid"
+)
+
+// spoolPattern шаблон имен файлов спула.
+const spoolPattern = "batch-*.json"
+
+// spoolBatch сохраняет батч метрик в новый файл каталога dir и возвращает путь до него.
+// Файл сначала записывается под временным именем, чтобы при сбое в спуле не оказался неполный батч.
+func spoolBatch(dir string, batch []*entities.MetricDTO) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return "", err
+	}
+
+	// Имена упорядочены по времени сохранения, случайный суффикс различает батчи разных worker'ов.
+	name := fmt.Sprintf("batch-%020d-%s.json", time.Now().UnixNano(), requestid.New())
+	path := filepath.Join(dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	return path, nil
+}
+
+// sendSpooled отправляет на сервер батчи из каталога спула в порядке сохранения и удаляет отправленные.
+// Возвращает число отправленных батчей и ошибку первой неудачной отправки, оставшиеся батчи остаются в спуле.
+func sendSpooled(ctx context.Context, log *logging.Logger, wp *worker.WorkerPool) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(wp.Cfg.SpoolDir, spoolPattern))
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(paths)
+
+	sent := 0
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return sent, err
+		}
+
+		var batch []*entities.MetricDTO
+		if err := json.Unmarshal(data, &batch); err != nil {
+			// Поврежденный файл не отправляется повторно.
+			log.Errorf("Dropping corrupted spool file %s: %v", path, err)
+			if err := os.Remove(path); err != nil {
+				return sent, err
+			}
+			continue
+		}
+
+		err = SendMetrics(ctx, wp.Cfg, wp.Client, batch, true)
+		wp.Telemetry.RecordSend(len(batch), err)
+		if err != nil {
+			return sent, err
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return sent, err
+		}
+		log.Infof("Sent %d spooled metrics from %s", len(batch), path)
+		sent++
+	}
+	return sent, nil
+}
+
+// RunSendSpooledWorker отправляет на сервер метрики, сохраненные в спул при предыдущей остановке агента.
+// Неудачная отправка повторяется с интервалом interval, worker завершается, когда спул пуст.
+func RunSendSpooledWorker(ctx context.Context, log *logging.Logger, wp *worker.WorkerPool, interval time.Duration) {
+	reportTicker := time.NewTicker(interval)
+	defer reportTicker.Stop()
+
+	for {
+		_, err := sendSpooled(ctx, log, wp)
+		if err == nil {
+			return
+		}
+		log.Errorf("Send spooled metrics failed: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-reportTicker.C:
+		}
+	}
+}
@@ -9,10 +9,13 @@ import (
 	"github.com/gitslim/monit/internal/logging"
 )
 
-// RunSendMetricsWorker запуск воркера отправки метрик.
-func RunSendMetricsWorker(ctx context.Context, log *logging.Logger, wp *worker.WorkerPool) {
+// RunSendMetricsWorker запуск воркера отправки метрик с интервалом interval.
+//
+// Worker работает до закрытия канала wp.Metrics, после чего отправляет накопленный батч и завершается.
+// Отмена ctx ограничивает время отправки: батч, который не удалось отправить, сохраняется в спул.
+func RunSendMetricsWorker(ctx context.Context, log *logging.Logger, wp *worker.WorkerPool, interval time.Duration) {
 	// Таймер для периодической отправки метрик.
-	reportTicker := time.NewTicker(time.Duration(wp.Cfg.ReportInterval * uint64(time.Second)))
+	reportTicker := time.NewTicker(interval)
 	defer reportTicker.Stop()
 
 	// Создаем пустой батч метрик.
@@ -20,17 +23,60 @@ func RunSendMetricsWorker(ctx context.Context, log *logging.Logger, wp *worker.W
 
 	for {
 		select {
-		case metric := <-wp.Metrics:
+		case metric, ok := <-wp.Metrics:
+			if !ok {
+				// Агент останавливается: отправляем накопленные метрики.
+				flushBatch(ctx, log, wp, batch)
+				return
+			}
 			// Добавляем метрику в батч.
 			batch = append(batch, &metric)
+			wp.Telemetry.AddPending(1)
 		case <-ctx.Done():
+			flushBatch(ctx, log, wp, batch)
 			return
 		case <-reportTicker.C:
+			if len(batch) == 0 {
+				continue
+			}
 			// Отправляем батч метрик.
 			err := SendMetrics(ctx, wp.Cfg, wp.Client, batch, false)
+			wp.Telemetry.RecordSend(len(batch), err)
 			if err != nil {
 				log.Errorf("Send metrics failed: %v\n", err)
 			}
+			// Метрики отправляются по одной, поэтому после ошибки батч не повторяется целиком:
+			// уже принятые сервером счетчики были бы учтены дважды.
+			wp.Telemetry.AddPending(-len(batch))
+			batch = batch[:0]
 		}
 	}
 }
+
+// flushBatch отправляет накопленный батч одним запросом при остановке агента.
+// Если отправить батч не удалось, он сохраняется в спул, если каталог спула задан, иначе теряется.
+func flushBatch(ctx context.Context, log *logging.Logger, wp *worker.WorkerPool, batch []*entities.MetricDTO) {
+	if len(batch) == 0 {
+		return
+	}
+	defer wp.Telemetry.AddPending(-len(batch))
+
+	err := SendMetrics(ctx, wp.Cfg, wp.Client, batch, true)
+	wp.Telemetry.RecordSend(len(batch), err)
+	if err == nil {
+		log.Infof("Flushed %d metrics", len(batch))
+		return
+	}
+	log.Errorf("Flush metrics failed: %v", err)
+
+	if wp.Cfg.SpoolDir == "" {
+		log.Warnf("Dropped %d unsent metrics", len(batch))
+		return
+	}
+	path, err := spoolBatch(wp.Cfg.SpoolDir, batch)
+	if err != nil {
+		log.Errorf("Spool metrics failed, dropped %d unsent metrics: %v", len(batch), err)
+		return
+	}
+	log.Infof("Spooled %d unsent metrics to %s", len(batch), path)
+}
@@ -0,0 +1,119 @@
+package sender_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gitslim/monit/internal/agent/conf"
+	"github.com/gitslim/monit/internal/agent/sender"
+	"github.com/gitslim/monit/internal/agent/worker"
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchServer возвращает сервер, принимающий батчи метрик без сжатия и считающий принятые метрики.
+// Пока fail равен true, сервер отвечает ошибкой.
+func batchServer(t *testing.T, received *atomic.Int64, fail *atomic.Bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		assert.Equal(t, "/updates/", r.URL.Path)
+		var batch []*entities.MetricDTO
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&batch)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received.Add(int64(len(batch)))
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestPool создает пул с одним worker'ом отправки, который отправляет метрики только при остановке.
+func newTestPool(t *testing.T, srv *httptest.Server, spoolDir string) *worker.WorkerPool {
+	t.Helper()
+	cfg := &conf.Config{
+		Addr:        srv.URL,
+		RateLimit:   1,
+		Compression: "identity",
+		SpoolDir:    spoolDir,
+	}
+	wp := worker.NewWorkerPool(cfg)
+	wp.Client = srv.Client()
+	return wp
+}
+
+// TestSendMetricsWorkerFlush тестирует отправку накопленного батча при остановке агента.
+func TestSendMetricsWorkerFlush(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	var received atomic.Int64
+	var fail atomic.Bool
+	wp := newTestPool(t, batchServer(t, &received, &fail), "")
+
+	wp.Start(context.Background(), func(ctx context.Context) {
+		sender.RunSendMetricsWorker(ctx, log, wp, time.Hour)
+	})
+	for i := 0; i < 3; i++ {
+		metric, err := entities.NewMetricDTO("PollCount", "counter", int64(1))
+		require.NoError(t, err)
+		require.True(t, wp.Push(context.Background(), *metric))
+	}
+	wp.Stop()
+	wp.Wait()
+
+	assert.Equal(t, int64(3), received.Load())
+	status := wp.Telemetry.Status(0)
+	assert.Equal(t, int64(0), status.Send.PendingMetrics)
+	assert.Equal(t, int64(3), status.Send.SentMetrics)
+}
+
+// TestSendMetricsWorkerSpool тестирует сохранение неотправленного батча в спул и его отправку после перезапуска.
+func TestSendMetricsWorkerSpool(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	var received atomic.Int64
+	var fail atomic.Bool
+	fail.Store(true)
+	srv := batchServer(t, &received, &fail)
+	spoolDir := filepath.Join(t.TempDir(), "spool")
+
+	wp := newTestPool(t, srv, spoolDir)
+	wp.Start(context.Background(), func(ctx context.Context) {
+		sender.RunSendMetricsWorker(ctx, log, wp, time.Hour)
+	})
+	metric, err := entities.NewMetricDTO("Alloc", "gauge", float64(42))
+	require.NoError(t, err)
+	require.True(t, wp.Push(context.Background(), *metric))
+	wp.Stop()
+	wp.Wait()
+
+	spooled, err := filepath.Glob(filepath.Join(spoolDir, "batch-*.json"))
+	require.NoError(t, err)
+	require.Len(t, spooled, 1)
+	assert.Equal(t, int64(0), received.Load())
+
+	// После перезапуска агент отправляет батч из спула и удаляет файл.
+	fail.Store(false)
+	wp = newTestPool(t, srv, spoolDir)
+	sender.RunSendSpooledWorker(context.Background(), log, wp, time.Hour)
+
+	assert.Equal(t, int64(1), received.Load())
+	_, err = os.Stat(spooled[0])
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,54 @@
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/selfmetrics"
+)
+
+// Publish периодически помещает метрики агента в очередь out, откуда они отправляются на сервер
+// вместе с остальными метриками. queued возвращает текущее число метрик в очереди.
+// Счетчики сервера накапливают значения, поэтому для счетчиков помещается прирост с прошлой публикации.
+func Publish(ctx context.Context, t *Telemetry, interval time.Duration, queued func() int, out chan<- entities.MetricDTO) {
+	published := make(map[string]int64)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, m := range deltas(t.Metrics(queued()), published) {
+				select {
+				case out <- *m:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// deltas заменяет значения счетчиков приростом с прошлой публикации и пропускает счетчики без прироста.
+// published содержит значения счетчиков, опубликованные ранее, и обновляется.
+func deltas(metrics []*entities.MetricDTO, published map[string]int64) []*entities.MetricDTO {
+	result := make([]*entities.MetricDTO, 0, len(metrics))
+	for _, m := range metrics {
+		if m.Delta == nil {
+			result = append(result, m)
+			continue
+		}
+		prev, ok := published[m.ID]
+		delta := *m.Delta - prev
+		if ok && delta == 0 {
+			continue
+		}
+		published[m.ID] = *m.Delta
+		result = append(result, selfmetrics.NewCounter(m.ID, delta))
+	}
+	return result
+}
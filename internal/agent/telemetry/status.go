@@ -0,0 +1,31 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gitslim/monit/internal/httpconst"
+)
+
+// StatusHandler возвращает обработчик GET /status, отдающий состояние агента в формате JSON.
+// config выводится как есть, поэтому секреты в нем должны быть скрыты вызывающим кодом.
+// queued возвращает текущее число метрик в очереди сборщиков.
+func StatusHandler(t *Telemetry, config any, queued func() int) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set(httpconst.HeaderAllow, "GET, HEAD")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		status := t.Status(queued())
+		status.Config = config
+
+		w.Header().Set(httpconst.HeaderContentType, httpconst.ContentTypeJSON)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(status)
+	})
+	return mux
+}
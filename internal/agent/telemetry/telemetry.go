@@ -0,0 +1,178 @@
+// Package telemetry содержит метрики самого агента: время последней успешной отправки, ошибки отправки,
+// длительность и ошибки сборщиков метрик.
+//
+// Состояние агента доступно на локальном HTTP-эндпоинте /status и отправляется на сервер вместе
+// с остальными метриками под зарезервированным префиксом Prefix. Методы Telemetry безопасны
+// для конкурентного использования и ничего не делают для nil-значения.
+package telemetry
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/selfmetrics"
+)
+
+// Prefix префикс имен метрик агента.
+const Prefix = "monit_agent_"
+
+// Name возвращает полное имя метрики агента с префиксом и метками, заданными парами ключ-значение.
+func Name(name string, labels ...string) string {
+	return selfmetrics.PrefixedName(Prefix, name, labels...)
+}
+
+// CollectorStat статистика запусков сборщика метрик.
+type CollectorStat struct {
+	Runs                int64     `json:"runs"`
+	Errors              int64     `json:"errors"`
+	LastRun             time.Time `json:"last_run"`
+	LastDurationSeconds float64   `json:"last_duration_seconds"`
+	LastError           string    `json:"last_error,omitempty"`
+}
+
+// SendStat статистика отправки метрик на сервер.
+type SendStat struct {
+	Requests       int64      `json:"requests"`
+	Errors         int64      `json:"errors"`
+	SentMetrics    int64      `json:"sent_metrics"`
+	LastSuccess    *time.Time `json:"last_success,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	PendingMetrics int64      `json:"pending_metrics"`
+}
+
+// Telemetry хранит метрики агента.
+type Telemetry struct {
+	mu         sync.Mutex
+	now        func() time.Time
+	started    time.Time
+	send       SendStat
+	collectors map[string]*CollectorStat
+}
+
+// New создает пустое хранилище метрик агента.
+func New() *Telemetry {
+	return &Telemetry{
+		now:        time.Now,
+		started:    time.Now(),
+		collectors: make(map[string]*CollectorStat),
+	}
+}
+
+// RecordCollect учитывает запуск сборщика name длительностью d, завершившийся ошибкой err.
+func (t *Telemetry) RecordCollect(name string, d time.Duration, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stat, ok := t.collectors[name]
+	if !ok {
+		stat = &CollectorStat{}
+		t.collectors[name] = stat
+	}
+	stat.Runs++
+	stat.LastRun = t.now()
+	stat.LastDurationSeconds = d.Seconds()
+	stat.LastError = ""
+	if err != nil {
+		stat.Errors++
+		stat.LastError = err.Error()
+	}
+}
+
+// RecordSend учитывает отправку n метрик на сервер, завершившуюся ошибкой err.
+func (t *Telemetry) RecordSend(n int, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.send.Requests++
+	if err != nil {
+		t.send.Errors++
+		t.send.LastError = err.Error()
+		return
+	}
+	now := t.now()
+	t.send.SentMetrics += int64(n)
+	t.send.LastSuccess = &now
+	t.send.LastError = ""
+}
+
+// AddPending изменяет на delta число метрик, принятых из очереди в батчи, но еще не отправленных.
+func (t *Telemetry) AddPending(delta int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.send.PendingMetrics += int64(delta)
+}
+
+// Status состояние агента.
+type Status struct {
+	StartedAt     time.Time                `json:"started_at"`
+	UptimeSeconds float64                  `json:"uptime_seconds"`
+	Config        any                      `json:"config,omitempty"`
+	QueuedMetrics int                      `json:"queued_metrics"`
+	Send          SendStat                 `json:"send"`
+	Collectors    map[string]CollectorStat `json:"collectors"`
+}
+
+// Status возвращает текущее состояние агента с числом метрик queued в очереди сборщиков.
+func (t *Telemetry) Status(queued int) Status {
+	if t == nil {
+		return Status{QueuedMetrics: queued}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := Status{
+		StartedAt:     t.started,
+		UptimeSeconds: t.now().Sub(t.started).Seconds(),
+		QueuedMetrics: queued,
+		Send:          t.send,
+		Collectors:    make(map[string]CollectorStat, len(t.collectors)),
+	}
+	for name, stat := range t.collectors {
+		status.Collectors[name] = *stat
+	}
+	return status
+}
+
+// Metrics возвращает метрики агента с числом метрик queued в очереди сборщиков, отсортированные по имени.
+// Счетчики содержат накопленные с запуска агента значения.
+func (t *Telemetry) Metrics(queued int) []*entities.MetricDTO {
+	if t == nil {
+		return nil
+	}
+	status := t.Status(queued)
+
+	metrics := []*entities.MetricDTO{
+		selfmetrics.NewGauge(Name("uptime_seconds"), status.UptimeSeconds),
+		selfmetrics.NewGauge(Name("queued_metrics"), float64(status.QueuedMetrics)),
+		selfmetrics.NewGauge(Name("pending_metrics"), float64(status.Send.PendingMetrics)),
+		selfmetrics.NewCounter(Name("send_requests_total"), status.Send.Requests),
+		selfmetrics.NewCounter(Name("send_errors_total"), status.Send.Errors),
+		selfmetrics.NewCounter(Name("sent_metrics_total"), status.Send.SentMetrics),
+	}
+	if status.Send.LastSuccess != nil {
+		metrics = append(metrics, selfmetrics.NewGauge(Name("last_send_timestamp_seconds"), float64(status.Send.LastSuccess.Unix())))
+	}
+	for name, stat := range status.Collectors {
+		metrics = append(metrics,
+			selfmetrics.NewCounter(Name("collector_runs_total", "collector", name), stat.Runs),
+			selfmetrics.NewCounter(Name("collector_errors_total", "collector", name), stat.Errors),
+			selfmetrics.NewGauge(Name("collector_duration_seconds", "collector", name), stat.LastDurationSeconds),
+		)
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].ID < metrics[j].ID
+	})
+	return metrics
+}
@@ -0,0 +1,129 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gitslim/monit/internal/agent/conf"
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metricsMap возвращает значения метрик по именам.
+func metricsMap(t *testing.T, metrics []*entities.MetricDTO) map[string]any {
+	t.Helper()
+	values := make(map[string]any)
+	for _, m := range metrics {
+		switch {
+		case m.Delta != nil:
+			values[m.ID] = *m.Delta
+		case m.Value != nil:
+			values[m.ID] = *m.Value
+		default:
+			t.Fatalf("metric %s without value", m.ID)
+		}
+	}
+	return values
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "monit_agent_up", Name("up"))
+	assert.Equal(t, "monit_agent_collector_runs_total{collector=runtime}", Name("collector_runs_total", "collector", "runtime"))
+}
+
+func TestTelemetry(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tm := New()
+	tm.now = func() time.Time { return now }
+	tm.started = now.Add(-time.Minute)
+
+	tm.RecordCollect("runtime", 20*time.Millisecond, nil)
+	tm.RecordCollect("system", 10*time.Millisecond, errors.New("no cpu info"))
+	tm.RecordSend(5, nil)
+	tm.RecordSend(3, errors.New("connection refused"))
+	tm.AddPending(4)
+
+	status := tm.Status(2)
+	assert.Equal(t, 2, status.QueuedMetrics)
+	assert.Equal(t, 60.0, status.UptimeSeconds)
+	assert.Equal(t, int64(2), status.Send.Requests)
+	assert.Equal(t, int64(1), status.Send.Errors)
+	assert.Equal(t, int64(5), status.Send.SentMetrics)
+	assert.Equal(t, int64(4), status.Send.PendingMetrics)
+	assert.Equal(t, "connection refused", status.Send.LastError)
+	require.NotNil(t, status.Send.LastSuccess)
+	assert.Equal(t, now, *status.Send.LastSuccess)
+	assert.Equal(t, int64(1), status.Collectors["system"].Errors)
+	assert.Equal(t, "no cpu info", status.Collectors["system"].LastError)
+	assert.Empty(t, status.Collectors["runtime"].LastError)
+
+	values := metricsMap(t, tm.Metrics(2))
+	assert.Equal(t, 2.0, values["monit_agent_queued_metrics"])
+	assert.Equal(t, 4.0, values["monit_agent_pending_metrics"])
+	assert.Equal(t, int64(1), values["monit_agent_send_errors_total"])
+	assert.Equal(t, int64(5), values["monit_agent_sent_metrics_total"])
+	assert.Equal(t, 1000.0, values["monit_agent_last_send_timestamp_seconds"])
+	assert.Equal(t, 0.02, values["monit_agent_collector_duration_seconds{collector=runtime}"])
+	assert.Equal(t, int64(1), values["monit_agent_collector_errors_total{collector=system}"])
+	assert.Equal(t, int64(0), values["monit_agent_collector_errors_total{collector=runtime}"])
+
+	// nil-значение ничего не учитывает.
+	var nilTelemetry *Telemetry
+	nilTelemetry.RecordSend(1, nil)
+	nilTelemetry.RecordCollect("runtime", time.Second, nil)
+	assert.Nil(t, nilTelemetry.Metrics(0))
+}
+
+func TestDeltas(t *testing.T) {
+	tm := New()
+	published := make(map[string]int64)
+
+	tm.RecordSend(5, nil)
+	values := metricsMap(t, deltas(tm.Metrics(0), published))
+	assert.Equal(t, int64(5), values["monit_agent_sent_metrics_total"])
+	assert.Equal(t, int64(0), values["monit_agent_send_errors_total"])
+
+	// Счетчики без прироста не публикуются, gauges публикуются всегда.
+	tm.RecordSend(2, nil)
+	values = metricsMap(t, deltas(tm.Metrics(0), published))
+	assert.Equal(t, int64(2), values["monit_agent_sent_metrics_total"])
+	assert.NotContains(t, values, "monit_agent_send_errors_total")
+	assert.Contains(t, values, "monit_agent_queued_metrics")
+}
+
+func TestStatusHandler(t *testing.T) {
+	cfg := &conf.Config{Addr: "localhost:8080", Key: "secret", Token: "token", StatusAddr: "localhost:9090"}
+	tm := New()
+	tm.RecordCollect("runtime", time.Millisecond, nil)
+	handler := StatusHandler(tm, cfg.Redacted(), func() int { return 7 })
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "secret")
+
+	var status struct {
+		Status
+		Config conf.Config `json:"config"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, 7, status.QueuedMetrics)
+	assert.Equal(t, "localhost:8080", status.Config.Addr)
+	assert.Equal(t, "[REDACTED]", status.Config.Key)
+	assert.Equal(t, "[REDACTED]", status.Config.Token)
+	assert.Equal(t, int64(1), status.Collectors["runtime"].Runs)
+	assert.Equal(t, "secret", cfg.Key)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/status", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/other", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -7,16 +7,22 @@ import (
 	"sync"
 
 	"github.com/gitslim/monit/internal/agent/conf"
+	"github.com/gitslim/monit/internal/agent/telemetry"
 	"github.com/gitslim/monit/internal/entities"
 )
 
 // WorkerPool определяет пул worker'ов.
+//
+// Worker'ы, добавленные AddWorker, собирают метрики и помещают их в канал Metrics,
+// worker'ы, запущенные Start, забирают метрики из канала и отправляют их на сервер.
 type WorkerPool struct {
-	Metrics chan entities.MetricDTO
-	WG      *sync.WaitGroup
-	Cfg     *conf.Config
-	Client  *http.Client
-	once    sync.Once // Для безопасного закрытия канала Metrics
+	Metrics   chan entities.MetricDTO
+	WG        *sync.WaitGroup // Worker'ы отправки метрик
+	Cfg       *conf.Config
+	Client    *http.Client
+	Telemetry *telemetry.Telemetry // Метрики самого агента, nil выключает их учет
+	producers sync.WaitGroup       // Worker'ы сбора метрик
+	once      sync.Once            // Для безопасного закрытия канала Metrics
 }
 
 // Start запускает пул worker'ов с поддержкой контекста.
@@ -32,31 +38,47 @@ func (w *WorkerPool) Start(ctx context.Context, f func(ctx context.Context)) {
 
 // AddWorker добавляет worker'а в пул с поддержкой контекста.
 func (w *WorkerPool) AddWorker(ctx context.Context, f func(ctx context.Context)) {
-	w.WG.Add(1)
+	w.producers.Add(1)
 	go func() {
-		defer w.WG.Done()
+		defer w.producers.Done()
 		f(ctx)
 	}()
 }
 
-// Stop останавливает пул worker'ов.
+// Push помещает метрику в канал Metrics.
+// Возвращает false, если контекст завершился раньше, чем в канале освободилось место.
+func (w *WorkerPool) Push(ctx context.Context, metric entities.MetricDTO) bool {
+	select {
+	case w.Metrics <- metric:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Stop останавливает пул worker'ов: ожидает завершения worker'ов, добавленных AddWorker,
+// и закрывает канал Metrics, после чего worker'ы отправки отправляют накопленные метрики и завершаются.
+// Контекст worker'ов AddWorker должен быть отменен до вызова Stop.
 func (w *WorkerPool) Stop() {
 	w.once.Do(func() {
+		w.producers.Wait()
 		close(w.Metrics) // Закрываем канал метрик
 	})
 }
 
 // Wait ожидает завершения всех worker'ов.
 func (w *WorkerPool) Wait() {
+	w.producers.Wait()
 	w.WG.Wait()
 }
 
 // NewWorkerPool создает пул worker'ов.
 func NewWorkerPool(cfg *conf.Config) *WorkerPool {
 	return &WorkerPool{
-		Metrics: make(chan entities.MetricDTO, cfg.RateLimit),
-		WG:      &sync.WaitGroup{},
-		Cfg:     cfg,
-		Client:  &http.Client{},
+		Metrics:   make(chan entities.MetricDTO, cfg.RateLimit),
+		WG:        &sync.WaitGroup{},
+		Cfg:       cfg,
+		Client:    &http.Client{},
+		Telemetry: telemetry.New(),
 	}
 }
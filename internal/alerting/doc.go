@@ -0,0 +1,6 @@
+// Package alerting содержит движок правил оповещений сервера метрик.
+//
+// Правила загружаются из JSON-файла и периодически вычисляются над метриками сервиса.
+// Поддерживаются условия превышения порога, отсутствия обновлений и скорости изменения значения.
+// Каждое оповещение проходит состояния pending, firing и resolved.
+package alerting
@@ -0,0 +1,260 @@
+package alerting
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/logging"
+)
+
+// Состояния оповещения.
+const (
+	StateInactive State = "inactive"
+	StatePending  State = "pending"
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// State определяет состояние оповещения.
+type State string
+
+// MetricSource определяет источник метрик для вычисления правил.
+type MetricSource interface {
+	// GetMetric получает метрику по имени и типу.
+	GetMetric(mName string, mType string) (entities.Metric, error)
+	// LastUpdated возвращает время последнего обновления метрики, сохраненное в хранилище.
+	LastUpdated(mName string, mType entities.MetricType) (time.Time, bool)
+}
+
+// Alert описывает состояние оповещения по правилу.
+type Alert struct {
+	Rule        string     `json:"rule"`                  // имя правила
+	Metric      string     `json:"metric"`                // имя метрики
+	Type        string     `json:"type"`                  // тип метрики
+	State       State      `json:"state"`                 // состояние оповещения
+	Value       float64    `json:"value"`                 // последнее вычисленное значение условия
+	Description string     `json:"description,omitempty"` // описание оповещения
+	ActiveAt    time.Time  `json:"active_at"`             // время перехода в pending
+	FiredAt     *time.Time `json:"fired_at,omitempty"`    // время перехода в firing
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"` // время перехода в resolved
+}
+
+// ruleState содержит состояние вычисления правила.
+type ruleState struct {
+	rule  *Rule
+	alert Alert
+
+	// Предыдущее значение метрики для условия rate.
+	prevValue float64
+	prevTime  time.Time
+	hasPrev   bool
+}
+
+// Engine периодически вычисляет правила и хранит состояния оповещений.
+type Engine struct {
+	source    MetricSource
+	log       *logging.Logger
+	mu        sync.RWMutex
+	states    []*ruleState
+	startedAt time.Time
+	handlers  []func(Alert)
+}
+
+// NewEngine создает движок правил оповещений.
+func NewEngine(source MetricSource, log *logging.Logger, rules []*Rule) *Engine {
+	states := make([]*ruleState, 0, len(rules))
+	for _, r := range rules {
+		states = append(states, &ruleState{
+			rule: r,
+			alert: Alert{
+				Rule:        r.Name,
+				Metric:      r.Metric,
+				Type:        r.Type,
+				State:       StateInactive,
+				Description: r.Description,
+			},
+		})
+	}
+
+	return &Engine{
+		source:    source,
+		log:       log,
+		states:    states,
+		startedAt: time.Now(),
+	}
+}
+
+// OnStateChange регистрирует обработчик, вызываемый при каждом изменении состояния оповещения.
+// Обработчики должны регистрироваться до запуска Run и не должны блокироваться.
+func (e *Engine) OnStateChange(f func(Alert)) {
+	e.handlers = append(e.handlers, f)
+}
+
+// Run запускает периодическое вычисление правил.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.log.Debug("Alerting engine stopped")
+			return
+		case now := <-ticker.C:
+			e.Evaluate(now)
+		}
+	}
+}
+
+// Evaluate вычисляет все правила на момент времени now.
+func (e *Engine) Evaluate(now time.Time) {
+	changed := e.evaluate(now)
+
+	// Обработчики вызываются вне блокировки.
+	for _, a := range changed {
+		for _, f := range e.handlers {
+			f(a)
+		}
+	}
+}
+
+// evaluate вычисляет правила и возвращает оповещения, изменившие состояние.
+func (e *Engine) evaluate(now time.Time) []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var changed []Alert
+	for _, s := range e.states {
+		active, value, ok := e.evaluateCondition(s, now)
+		if !ok {
+			continue
+		}
+		s.alert.Value = value
+
+		prev := s.alert.State
+		s.transition(active, now)
+		if s.alert.State != prev {
+			e.log.Info("Alert state changed",
+				"rule", s.rule.Name,
+				"from", prev,
+				"to", s.alert.State,
+				"value", value)
+			changed = append(changed, s.alert)
+		}
+	}
+	return changed
+}
+
+// evaluateCondition вычисляет условие правила.
+// Возвращает ok=false, если значение условия пока невозможно вычислить.
+func (e *Engine) evaluateCondition(s *ruleState, now time.Time) (active bool, value float64, ok bool) {
+	r := s.rule
+
+	switch r.Condition {
+	case ConditionAbsence:
+		// Время обновления хранится вместе с метрикой и сохраняется между перезапусками сервера.
+		// Для метрик, которые ни разу не обновлялись, отсчет ведется от запуска движка.
+		last, found := e.source.LastUpdated(r.Metric, r.metricType())
+		if !found {
+			last = e.startedAt
+		}
+		absent := now.Sub(last)
+		return absent >= time.Duration(r.AbsentFor), absent.Seconds(), true
+
+	case ConditionThreshold:
+		v, found := e.metricValue(r)
+		if !found {
+			return false, 0, true
+		}
+		return operators[r.Op](v, r.Value), v, true
+
+	case ConditionRate:
+		v, found := e.metricValue(r)
+		if !found {
+			return false, 0, true
+		}
+		prevValue, prevTime, hasPrev := s.prevValue, s.prevTime, s.hasPrev
+		s.prevValue, s.prevTime, s.hasPrev = v, now, true
+
+		dt := now.Sub(prevTime).Seconds()
+		if !hasPrev || dt <= 0 {
+			return false, 0, false
+		}
+		rate := (v - prevValue) / dt
+		return operators[r.Op](rate, r.Value), rate, true
+	}
+
+	return false, 0, false
+}
+
+// metricValue получает текущее значение метрики правила.
+func (e *Engine) metricValue(r *Rule) (float64, bool) {
+	m, err := e.source.GetMetric(r.Metric, r.Type)
+	if err != nil {
+		return 0, false
+	}
+	switch v := m.GetValue().(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// transition переводит оповещение в следующее состояние.
+func (s *ruleState) transition(active bool, now time.Time) {
+	a := &s.alert
+
+	if !active {
+		switch a.State {
+		case StatePending:
+			a.State = StateInactive
+		case StateFiring:
+			a.State = StateResolved
+			a.ResolvedAt = &now
+		}
+		return
+	}
+
+	switch a.State {
+	case StateInactive, StateResolved:
+		a.State = StatePending
+		a.ActiveAt = now
+		a.FiredAt = nil
+		a.ResolvedAt = nil
+	}
+
+	if a.State == StatePending && now.Sub(a.ActiveAt) >= s.rule.pendingFor() {
+		a.State = StateFiring
+		a.FiredAt = &now
+	}
+}
+
+// Alerts возвращает оповещения в заданных состояниях, отсортированные по имени правила.
+// Если состояния не заданы, возвращаются все оповещения, кроме неактивных.
+func (e *Engine) Alerts(states ...State) []Alert {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	alerts := make([]Alert, 0)
+	for _, s := range e.states {
+		if len(states) == 0 && s.alert.State == StateInactive {
+			continue
+		}
+		if len(states) > 0 && !slices.Contains(states, s.alert.State) {
+			continue
+		}
+		alerts = append(alerts, s.alert)
+	}
+
+	slices.SortFunc(alerts, func(a, b Alert) int {
+		return strings.Compare(a.Rule, b.Rule)
+	})
+	return alerts
+}
@@ -0,0 +1,207 @@
+package alerting
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gitslim/monit/internal/config"
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/errs"
+	"github.com/gitslim/monit/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource источник метрик для тестов.
+type fakeSource struct {
+	mu      sync.Mutex
+	metrics map[string]entities.Metric
+	updated map[string]time.Time
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		metrics: make(map[string]entities.Metric),
+		updated: make(map[string]time.Time),
+	}
+}
+
+func (f *fakeSource) set(m entities.Metric, at time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.metrics[m.GetName()] = m
+	f.updated[m.GetName()] = at
+}
+
+func (f *fakeSource) GetMetric(mName string, mType string) (entities.Metric, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if m, ok := f.metrics[mName]; ok && m.GetType().String() == mType {
+		return m, nil
+	}
+	return nil, errs.ErrMetricNotFound
+}
+
+func (f *fakeSource) LastUpdated(mName string, mType entities.MetricType) (time.Time, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.updated[mName]
+	return t, ok
+}
+
+// newTestEngine создает движок с фиксированным временем запуска.
+func newTestEngine(t *testing.T, source MetricSource, rules ...*Rule) *Engine {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	for _, r := range rules {
+		require.NoError(t, r.validate())
+	}
+	e := NewEngine(source, log, rules)
+	e.startedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return e
+}
+
+// TestLoadRules тестирует загрузку правил из файла.
+func TestLoadRules(t *testing.T) {
+	rules, err := LoadRules("../../testdata/config/alert_rules.json")
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+	assert.Equal(t, ConditionAbsence, rules[1].Condition)
+	assert.Equal(t, config.Duration(time.Minute), rules[0].For)
+	assert.Equal(t, config.Duration(30*time.Second), rules[1].AbsentFor)
+
+	_, err = LoadRules("../../testdata/config/missing.json")
+	assert.Error(t, err)
+}
+
+// TestRuleValidate тестирует проверку правил.
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "valid threshold", rule: Rule{Name: "r", Metric: "m", Type: "gauge", Condition: ConditionThreshold}},
+		{name: "empty name", rule: Rule{Metric: "m", Type: "gauge", Condition: ConditionThreshold}, wantErr: true},
+		{name: "bad type", rule: Rule{Name: "r", Metric: "m", Type: "foo", Condition: ConditionThreshold}, wantErr: true},
+		{name: "bad op", rule: Rule{Name: "r", Metric: "m", Type: "gauge", Condition: ConditionRate, Op: "=>"}, wantErr: true},
+		{name: "absence without duration", rule: Rule{Name: "r", Metric: "m", Type: "gauge", Condition: ConditionAbsence}, wantErr: true},
+		{name: "unknown condition", rule: Rule{Name: "r", Metric: "m", Type: "gauge", Condition: "foo"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestEngineThreshold тестирует переходы состояний для условия превышения порога.
+func TestEngineThreshold(t *testing.T) {
+	source := newFakeSource()
+	e := newTestEngine(t, source, &Rule{
+		Name: "HighCPU", Metric: "cpu", Type: "gauge",
+		Condition: ConditionThreshold, Op: ">", Value: 90, For: config.Duration(time.Minute),
+	})
+	now := e.startedAt
+
+	source.set(&entities.GaugeMetric{Name: "cpu", Value: 95}, now)
+	e.Evaluate(now)
+	alerts := e.Alerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StatePending, alerts[0].State)
+	assert.Empty(t, e.Alerts(StateFiring))
+
+	now = now.Add(time.Minute)
+	e.Evaluate(now)
+	alerts = e.Alerts(StateFiring)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, 95.0, alerts[0].Value)
+	assert.Equal(t, now, *alerts[0].FiredAt)
+
+	source.set(&entities.GaugeMetric{Name: "cpu", Value: 50}, now)
+	now = now.Add(time.Minute)
+	e.Evaluate(now)
+	alerts = e.Alerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StateResolved, alerts[0].State)
+	assert.Equal(t, now, *alerts[0].ResolvedAt)
+
+	// Кратковременное превышение не переводит оповещение в firing.
+	source.set(&entities.GaugeMetric{Name: "cpu", Value: 99}, now)
+	now = now.Add(time.Second)
+	e.Evaluate(now)
+	source.set(&entities.GaugeMetric{Name: "cpu", Value: 10}, now)
+	now = now.Add(time.Second)
+	e.Evaluate(now)
+	assert.Empty(t, e.Alerts())
+}
+
+// TestEngineAbsence тестирует условие отсутствия обновлений.
+func TestEngineAbsence(t *testing.T) {
+	source := newFakeSource()
+	e := newTestEngine(t, source, &Rule{
+		Name: "AgentDown", Metric: "PollCount", Type: "counter",
+		Condition: ConditionAbsence, AbsentFor: config.Duration(30 * time.Second),
+	})
+	now := e.startedAt
+
+	// Метрика не обновлялась с момента запуска.
+	e.Evaluate(now.Add(10 * time.Second))
+	assert.Empty(t, e.Alerts())
+	e.Evaluate(now.Add(30 * time.Second))
+	assert.Len(t, e.Alerts(StateFiring), 1)
+
+	source.set(&entities.CounterMetric{Name: "PollCount", Value: 1}, now.Add(35*time.Second))
+	e.Evaluate(now.Add(40 * time.Second))
+	assert.Len(t, e.Alerts(StateResolved), 1)
+}
+
+// TestEngineAbsenceBeforeStart тестирует, что отсутствие обновлений отсчитывается от времени
+// последнего обновления в хранилище, даже если оно было до запуска движка.
+func TestEngineAbsenceBeforeStart(t *testing.T) {
+	source := newFakeSource()
+	e := newTestEngine(t, source, &Rule{
+		Name: "AgentDown", Metric: "PollCount", Type: "counter",
+		Condition: ConditionAbsence, AbsentFor: config.Duration(5 * time.Minute),
+	})
+	now := e.startedAt
+
+	source.set(&entities.CounterMetric{Name: "PollCount", Value: 1}, now.Add(-10*time.Minute))
+	e.Evaluate(now)
+	alerts := e.Alerts(StateFiring)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, (10 * time.Minute).Seconds(), alerts[0].Value)
+}
+
+// TestEngineRate тестирует условие скорости изменения значения.
+func TestEngineRate(t *testing.T) {
+	source := newFakeSource()
+	e := newTestEngine(t, source, &Rule{
+		Name: "FastGrowth", Metric: "requests", Type: "counter",
+		Condition: ConditionRate, Op: ">", Value: 10,
+	})
+	now := e.startedAt
+
+	source.set(&entities.CounterMetric{Name: "requests", Value: 0}, now)
+	e.Evaluate(now)
+	assert.Empty(t, e.Alerts())
+
+	// 50 за 10 секунд - 5 в секунду.
+	source.set(&entities.CounterMetric{Name: "requests", Value: 50}, now)
+	e.Evaluate(now.Add(10 * time.Second))
+	assert.Empty(t, e.Alerts())
+
+	// 250 за 10 секунд - 25 в секунду.
+	source.set(&entities.CounterMetric{Name: "requests", Value: 300}, now)
+	e.Evaluate(now.Add(20 * time.Second))
+	alerts := e.Alerts(StateFiring)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, 25.0, alerts[0].Value)
+}
@@ -0,0 +1,116 @@
+package alerting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gitslim/monit/internal/config"
+	"github.com/gitslim/monit/internal/entities"
+)
+
+// Типы условий правил.
+const (
+	ConditionThreshold ConditionType = "threshold"
+	ConditionAbsence   ConditionType = "absence"
+	ConditionRate      ConditionType = "rate"
+)
+
+// ConditionType определяет тип условия правила.
+type ConditionType string
+
+// Rule описывает правило оповещения.
+type Rule struct {
+	Name        string          `json:"name"`        // уникальное имя правила
+	Metric      string          `json:"metric"`      // имя метрики
+	Type        string          `json:"type"`        // тип метрики: gauge или counter
+	Condition   ConditionType   `json:"condition"`   // тип условия
+	Op          string          `json:"op"`          // оператор сравнения: >, >=, <, <=, ==, != (по умолчанию >)
+	Value       float64         `json:"value"`       // порог для threshold, скорость изменения в секунду для rate
+	AbsentFor   config.Duration `json:"absent_for"`  // время без обновлений для absence ("30s", "5m" или число секунд)
+	For         config.Duration `json:"for"`         // время в состоянии pending до перехода в firing ("1m" или число секунд)
+	Description string          `json:"description"` // описание оповещения
+}
+
+// rulesFile определяет формат файла правил.
+type rulesFile struct {
+	Rules []*Rule `json:"rules"`
+}
+
+// LoadRules загружает правила из JSON-файла.
+func LoadRules(path string) ([]*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать файл правил: %w", err)
+	}
+
+	var f rulesFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("ошибка декодирования JSON: %w", err)
+	}
+
+	names := make(map[string]bool, len(f.Rules))
+	for _, r := range f.Rules {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("правило %q: %w", r.Name, err)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("правило %q: имя правила должно быть уникальным", r.Name)
+		}
+		names[r.Name] = true
+	}
+	return f.Rules, nil
+}
+
+// validate проверяет правило на корректность и заполняет значения по умолчанию.
+func (r *Rule) validate() error {
+	if r.Name == "" {
+		return errors.New("имя правила не может быть пустым")
+	}
+	if r.Metric == "" {
+		return errors.New("имя метрики не может быть пустым")
+	}
+	if _, err := entities.GetMetricType(r.Type); err != nil {
+		return fmt.Errorf("неизвестный тип метрики %q", r.Type)
+	}
+
+	switch r.Condition {
+	case ConditionThreshold, ConditionRate:
+		if r.Op == "" {
+			r.Op = ">"
+		}
+		if _, ok := operators[r.Op]; !ok {
+			return fmt.Errorf("неизвестный оператор сравнения %q", r.Op)
+		}
+	case ConditionAbsence:
+		if r.AbsentFor == 0 {
+			return errors.New("время отсутствия обновлений не может быть равно 0")
+		}
+	default:
+		return fmt.Errorf("неизвестный тип условия %q", r.Condition)
+	}
+	return nil
+}
+
+// metricType возвращает тип метрики правила.
+func (r *Rule) metricType() entities.MetricType {
+	t, _ := entities.GetMetricType(r.Type)
+	return t
+}
+
+// pendingFor возвращает время в состоянии pending до перехода в firing.
+func (r *Rule) pendingFor() time.Duration {
+	return time.Duration(r.For)
+}
+
+// operators содержит поддерживаемые операторы сравнения.
+var operators = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
@@ -0,0 +1,6 @@
+// Package auth содержит токены доступа агентов и клиентов к серверу метрик.
+//
+// Каждый токен принадлежит агенту и дает набор прав (scope): write - запись метрик,
+// read - чтение метрик, admin - все права, включая административные роуты.
+// Токены передаются в заголовке Authorization: Bearer <token>, а хранилища содержат только их хэши SHA-256.
+package auth
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gitslim/monit/internal/errs"
+)
+
+// tokensFile определяет формат файла токенов.
+type tokensFile struct {
+	Tokens []*Token `json:"tokens"`
+}
+
+// FileTokenStore хранилище токенов в JSON-файле.
+//
+// Файл перечитывается при изменении времени его модификации,
+// поэтому отзыв токена вступает в силу без перезапуска сервера.
+type FileTokenStore struct {
+	path    string
+	mu      sync.RWMutex
+	modTime time.Time
+	tokens  map[string]*Token
+}
+
+// NewFileTokenStore создает хранилище токенов и загружает токены из файла.
+func NewFileTokenStore(path string) (*FileTokenStore, error) {
+	s := &FileTokenStore{path: path}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Lookup реализует интерфейс TokenStore.
+func (s *FileTokenStore) Lookup(_ context.Context, hash string) (*Token, error) {
+	// При ошибке перечитывания используются ранее загруженные токены.
+	_ = s.reload()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tokens[hash]
+	if !ok {
+		return nil, errs.ErrUnauthorized
+	}
+	return t, nil
+}
+
+// reload перечитывает файл токенов, если он изменился.
+func (s *FileTokenStore) reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть файл токенов: %w", err)
+	}
+
+	s.mu.RLock()
+	unchanged := s.tokens != nil && info.ModTime().Equal(s.modTime)
+	s.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	tokens, err := loadTokens(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens, s.modTime = tokens, info.ModTime()
+	s.mu.Unlock()
+	return nil
+}
+
+// loadTokens загружает токены из JSON-файла.
+func loadTokens(path string) (map[string]*Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать файл токенов: %w", err)
+	}
+
+	var f tokensFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("ошибка декодирования JSON: %w", err)
+	}
+
+	tokens := make(map[string]*Token, len(f.Tokens))
+	for _, t := range f.Tokens {
+		if err := t.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := tokens[t.Hash]; ok {
+			return nil, fmt.Errorf("токен агента %q задан несколько раз", t.Agent)
+		}
+		tokens[t.Hash] = t
+	}
+	return tokens, nil
+}
@@ -0,0 +1,69 @@
+package auth_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gitslim/monit/internal/auth"
+	"github.com/gitslim/monit/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTokens записывает файл токенов с одним токеном agent-1.
+func writeTokens(t *testing.T, path string, revoked bool, modTime time.Time) {
+	data := fmt.Sprintf(`{"tokens": [{"agent": "agent-1", "token_sha256": %q, "scopes": ["write"], "revoked": %v}]}`,
+		auth.HashToken("secret"), revoked)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+// TestFileTokenStore тестирует проверку токенов из файла и отзыв токена без перезапуска.
+func TestFileTokenStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	now := time.Now()
+	writeTokens(t, path, false, now.Add(-time.Minute))
+
+	store, err := auth.NewFileTokenStore(path)
+	require.NoError(t, err)
+
+	tok, err := auth.Authenticate(ctx, store, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "agent-1", tok.Agent)
+	assert.True(t, tok.Has(auth.ScopeWrite))
+	assert.False(t, tok.Has(auth.ScopeRead))
+
+	_, err = auth.Authenticate(ctx, store, "unknown")
+	assert.ErrorIs(t, err, errs.ErrUnauthorized)
+
+	// Отзыв токена применяется после изменения файла.
+	writeTokens(t, path, true, now)
+	_, err = auth.Authenticate(ctx, store, "secret")
+	assert.ErrorIs(t, err, errs.ErrUnauthorized)
+}
+
+// TestLoadTokensInvalid тестирует отклонение некорректных файлов токенов.
+func TestLoadTokensInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty agent", data: fmt.Sprintf(`{"tokens": [{"token_sha256": %q}]}`, auth.HashToken("a"))},
+		{name: "invalid hash", data: `{"tokens": [{"agent": "a", "token_sha256": "secret"}]}`},
+		{name: "unknown scope", data: fmt.Sprintf(`{"tokens": [{"agent": "a", "token_sha256": %q, "scopes": ["root"]}]}`, auth.HashToken("a"))},
+		{name: "duplicate token", data: fmt.Sprintf(`{"tokens": [{"agent": "a", "token_sha256": %[1]q}, {"agent": "b", "token_sha256": %[1]q}]}`, auth.HashToken("a"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tokens.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0o600))
+			_, err := auth.NewFileTokenStore(path)
+			assert.Error(t, err)
+		})
+	}
+}
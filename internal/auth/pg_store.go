@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gitslim/monit/internal/errs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Запросы хранилища токенов в PostgreSQL.
+const (
+	createTokensTableQuery = `CREATE TABLE IF NOT EXISTS auth_tokens (
+	token_sha256 TEXT PRIMARY KEY,
+	agent TEXT NOT NULL,
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	revoked BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+	lookupTokenQuery = `SELECT agent, scopes, revoked FROM auth_tokens WHERE token_sha256 = $1`
+)
+
+// PGTokenStore хранилище токенов в таблице auth_tokens PostgreSQL.
+//
+// Токены выпускаются и отзываются записью в таблицу, каждая проверка читает актуальное состояние токена.
+type PGTokenStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPGTokenStore создает хранилище токенов и при необходимости таблицу auth_tokens.
+func NewPGTokenStore(ctx context.Context, pool *pgxpool.Pool) (*PGTokenStore, error) {
+	if _, err := pool.Exec(ctx, createTokensTableQuery); err != nil {
+		return nil, err
+	}
+	return &PGTokenStore{db: pool}, nil
+}
+
+// Lookup реализует интерфейс TokenStore.
+func (s *PGTokenStore) Lookup(ctx context.Context, hash string) (*Token, error) {
+	t := &Token{Hash: hash}
+	var scopes []string
+	err := s.db.QueryRow(ctx, lookupTokenQuery, hash).Scan(&t.Agent, &scopes, &t.Revoked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errs.ErrUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range scopes {
+		t.Scopes = append(t.Scopes, Scope(s))
+	}
+	return t, nil
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	"github.com/gitslim/monit/internal/errs"
+)
+
+// Права доступа токена.
+const (
+	ScopeWrite Scope = "write"
+	ScopeRead  Scope = "read"
+	ScopeAdmin Scope = "admin"
+)
+
+// Ключи контекста gin, которые заполняет middleware аутентификации.
+const (
+	TokenKey = "auth_token" // *Token предъявленного токена
+	AgentKey = "auth_agent" // имя агента, выполнившего запрос
+)
+
+// Scope определяет право доступа токена.
+type Scope string
+
+// Token описывает токен доступа агента.
+type Token struct {
+	Agent   string  `json:"agent"`        // имя агента, которому выдан токен
+	Hash    string  `json:"token_sha256"` // хэш SHA-256 токена в шестнадцатеричном виде
+	Scopes  []Scope `json:"scopes"`       // права токена
+	Revoked bool    `json:"revoked"`      // токен отозван
+}
+
+// Has проверяет наличие у токена права scope. Право admin включает все остальные права.
+func (t *Token) Has(scope Scope) bool {
+	return slices.Contains(t.Scopes, scope) || slices.Contains(t.Scopes, ScopeAdmin)
+}
+
+// validate проверяет токен на корректность.
+func (t *Token) validate() error {
+	if t.Agent == "" {
+		return fmt.Errorf("имя агента не может быть пустым")
+	}
+	if b, err := hex.DecodeString(t.Hash); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("некорректный хэш токена агента %q", t.Agent)
+	}
+	for _, s := range t.Scopes {
+		switch s {
+		case ScopeWrite, ScopeRead, ScopeAdmin:
+		default:
+			return fmt.Errorf("неизвестное право %q токена агента %q", s, t.Agent)
+		}
+	}
+	return nil
+}
+
+// HashToken вычисляет хэш SHA-256 токена в шестнадцатеричном виде, под которым токен хранится в хранилище.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenStore определяет хранилище токенов.
+type TokenStore interface {
+	// Lookup ищет токен по хэшу. Возвращает errs.ErrUnauthorized, если токен не найден.
+	Lookup(ctx context.Context, hash string) (*Token, error)
+}
+
+// Authenticate проверяет предъявленный токен: токен должен существовать и не быть отозванным.
+func Authenticate(ctx context.Context, store TokenStore, token string) (*Token, error) {
+	t, err := store.Lookup(ctx, HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if t.Revoked {
+		return nil, errs.ErrUnauthorized
+	}
+	return t, nil
+}
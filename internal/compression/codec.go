@@ -0,0 +1,230 @@
+// Package compression содержит реестр алгоритмов сжатия тела HTTP-запросов и ответов (Content-Encoding)
+// и выбор алгоритма по заголовку Accept-Encoding с учетом q-значений.
+package compression
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gitslim/monit/internal/httpconst"
+	"github.com/klauspost/compress/zstd"
+)
+
+// errClosed ошибка чтения или записи после Close.
+var errClosed = errors.New("compression: use after close")
+
+// zstdMaxWindow максимальный размер окна zstd, принимаемый при распаковке.
+// Ограничивает память, которую может потребовать сжатое тело запроса.
+const zstdMaxWindow = 8 << 20
+
+// encoder сжимающий writer, который можно переиспользовать.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// decoder распаковывающий reader, который можно переиспользовать.
+type decoder interface {
+	io.Reader
+	Reset(r io.Reader) error
+}
+
+// Codec алгоритм сжатия с пулами переиспользуемых writer'ов и reader'ов.
+type Codec struct {
+	name       string
+	newEncoder func() encoder
+	newDecoder func(r io.Reader) (decoder, error)
+	encoders   sync.Pool
+	decoders   sync.Pool
+}
+
+// Name возвращает имя алгоритма в заголовке Content-Encoding.
+func (c *Codec) Name() string {
+	return c.name
+}
+
+// Writer сжимающий writer алгоритма. Close завершает поток сжатых данных и возвращает writer в пул,
+// но не закрывает исходный writer. Повторный Close ничего не делает.
+type Writer struct {
+	enc   encoder
+	codec *Codec
+}
+
+// Write реализует интерфейс io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.enc == nil {
+		return 0, errClosed
+	}
+	return w.enc.Write(p)
+}
+
+// Flush записывает в исходный writer все сжатые к этому моменту данные.
+func (w *Writer) Flush() error {
+	if w.enc == nil {
+		return errClosed
+	}
+	return w.enc.Flush()
+}
+
+// Close реализует интерфейс io.Closer.
+func (w *Writer) Close() error {
+	if w.enc == nil {
+		return nil
+	}
+	err := w.enc.Close()
+	// Отвязываем writer от исходного writer'а, чтобы пул не удерживал его.
+	w.enc.Reset(io.Discard)
+	w.codec.encoders.Put(w.enc)
+	w.enc = nil
+	return err
+}
+
+// NewWriter создает writer, сжимающий данные в dst.
+func (c *Codec) NewWriter(dst io.Writer) *Writer {
+	enc, ok := c.encoders.Get().(encoder)
+	if !ok {
+		enc = c.newEncoder()
+	}
+	enc.Reset(dst)
+	return &Writer{enc: enc, codec: c}
+}
+
+// reader распаковывающий reader алгоритма. Close возвращает reader в пул, но не закрывает исходный reader.
+// Тело запроса могут закрыть несколько мидлварей, поэтому повторный Close ничего не делает:
+// иначе один reader попал бы в пул дважды и достался бы двум запросам одновременно.
+type reader struct {
+	dec   decoder
+	codec *Codec
+}
+
+// Read реализует интерфейс io.Reader.
+func (r *reader) Read(p []byte) (int, error) {
+	if r.dec == nil {
+		return 0, errClosed
+	}
+	return r.dec.Read(p)
+}
+
+// Close реализует интерфейс io.Closer.
+func (r *reader) Close() error {
+	if r.dec == nil {
+		return nil
+	}
+	r.codec.decoders.Put(r.dec)
+	r.dec = nil
+	return nil
+}
+
+// NewReader создает reader, распаковывающий данные из src.
+// Возвращает ошибку, если заголовок потока сжатых данных некорректен.
+func (c *Codec) NewReader(src io.Reader) (io.ReadCloser, error) {
+	dec, ok := c.decoders.Get().(decoder)
+	if !ok {
+		var err error
+		if dec, err = c.newDecoder(src); err != nil {
+			return nil, err
+		}
+		return &reader{dec: dec, codec: c}, nil
+	}
+	if err := dec.Reset(src); err != nil {
+		return nil, err
+	}
+	return &reader{dec: dec, codec: c}, nil
+}
+
+// zlibDecoder приводит reader zlib к интерфейсу decoder.
+type zlibDecoder struct {
+	io.ReadCloser
+}
+
+// Reset реализует интерфейс decoder.
+func (d *zlibDecoder) Reset(r io.Reader) error {
+	return d.ReadCloser.(zlib.Resetter).Reset(r, nil)
+}
+
+// Реестр поддерживаемых алгоритмов в порядке предпочтения сервера.
+var codecs = []*Codec{
+	{
+		name: httpconst.ContentEncodingZstd,
+		newEncoder: func() encoder {
+			// Ошибка возможна только при некорректных опциях.
+			enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+			return enc
+		},
+		newDecoder: func(r io.Reader) (decoder, error) {
+			dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(zstdMaxWindow))
+			if err != nil {
+				return nil, err
+			}
+			return dec, nil
+		},
+	},
+	{
+		name: httpconst.ContentEncodingBr,
+		newEncoder: func() encoder {
+			return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+		},
+		newDecoder: func(r io.Reader) (decoder, error) {
+			return brotli.NewReader(r), nil
+		},
+	},
+	{
+		name: httpconst.ContentEncodingGzip,
+		newEncoder: func() encoder {
+			return gzip.NewWriter(nil)
+		},
+		newDecoder: func(r io.Reader) (decoder, error) {
+			dec, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return dec, nil
+		},
+	},
+	{
+		// Кодирование deflate в HTTP - это поток zlib (RFC 1950), а не "сырой" deflate.
+		name: httpconst.ContentEncodingDeflate,
+		newEncoder: func() encoder {
+			return zlib.NewWriter(nil)
+		},
+		newDecoder: func(r io.Reader) (decoder, error) {
+			rc, err := zlib.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return &zlibDecoder{rc}, nil
+		},
+	},
+}
+
+// Lookup возвращает алгоритм сжатия по имени из заголовка Content-Encoding.
+func Lookup(name string) (*Codec, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, c := range codecs {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Names возвращает имена поддерживаемых алгоритмов в порядке предпочтения сервера.
+func Names() []string {
+	names := make([]string, 0, len(codecs))
+	for _, c := range codecs {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// IsIdentity проверяет, что имя из заголовка Content-Encoding означает отсутствие сжатия.
+func IsIdentity(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return name == "" || name == httpconst.AcceptEncodingIdentity
+}
@@ -0,0 +1,126 @@
+package compression_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gitslim/monit/internal/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	data := []byte(strings.Repeat(`{"id":"Alloc","type":"gauge","value":12.5},`, 1000))
+
+	for _, name := range compression.Names() {
+		t.Run(name, func(t *testing.T) {
+			codec, ok := compression.Lookup(name)
+			require.True(t, ok)
+
+			// Повторяем, чтобы проверить переиспользование writer'ов и reader'ов из пула.
+			for i := 0; i < 3; i++ {
+				var buf bytes.Buffer
+				w := codec.NewWriter(&buf)
+				_, err := w.Write(data)
+				require.NoError(t, err)
+				require.NoError(t, w.Close())
+				assert.Less(t, buf.Len(), len(data))
+
+				r, err := codec.NewReader(&buf)
+				require.NoError(t, err)
+				got, err := io.ReadAll(r)
+				require.NoError(t, err)
+				require.NoError(t, r.Close())
+				assert.Equal(t, data, got)
+			}
+		})
+	}
+}
+
+func TestCodecInvalidStream(t *testing.T) {
+	// Заголовок потока gzip и zlib проверяется при создании reader'а.
+	for _, name := range []string{"gzip", "deflate"} {
+		codec, ok := compression.Lookup(name)
+		require.True(t, ok)
+		_, err := codec.NewReader(strings.NewReader("not compressed"))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	c, ok := compression.Lookup(" GZIP ")
+	require.True(t, ok)
+	assert.Equal(t, "gzip", c.Name())
+
+	_, ok = compression.Lookup("compress")
+	assert.False(t, ok)
+
+	assert.True(t, compression.IsIdentity(""))
+	assert.True(t, compression.IsIdentity("identity"))
+	assert.False(t, compression.IsIdentity("gzip"))
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "gzip", want: "gzip"},
+		{header: "gzip, deflate, br, zstd", want: "zstd"},
+		{header: "gzip;q=1.0, zstd;q=0.5", want: "gzip"},
+		{header: "br;q=0.8, gzip;q=0.8", want: "br"},
+		{header: "*", want: "zstd"},
+		{header: "*;q=0.5, zstd;q=0", want: "br"},
+		{header: "GZip; Q=0.3", want: "gzip"},
+		{header: "gzip;q=0", want: ""},
+		{header: "gzip;q=abc", want: ""},
+		{header: "compress, identity", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			c, ok := compression.Negotiate(tt.header)
+			if tt.want == "" {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.want, c.Name())
+		})
+	}
+}
+
+func TestCodecDoubleClose(t *testing.T) {
+	codec, ok := compression.Lookup("gzip")
+	require.True(t, ok)
+
+	var buf bytes.Buffer
+	w := codec.NewWriter(&buf)
+	_, err := w.Write([]byte("payload"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	_, err = w.Write([]byte("more"))
+	assert.Error(t, err)
+
+	r, err := codec.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	require.NoError(t, r.Close())
+	_, err = r.Read(make([]byte, 1))
+	assert.Error(t, err)
+
+	// Повторный Close не возвращает reader в пул второй раз, поэтому новые reader'ы независимы.
+	r1, err := codec.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	r2, err := codec.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	got1, err := io.ReadAll(r1)
+	require.NoError(t, err)
+	got2, err := io.ReadAll(r2)
+	require.NoError(t, err)
+	assert.Equal(t, "payload", string(got1))
+	assert.Equal(t, "payload", string(got2))
+}
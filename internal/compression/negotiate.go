@@ -0,0 +1,61 @@
+package compression
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gitslim/monit/internal/httpconst"
+)
+
+// parseAcceptEncoding разбирает заголовок Accept-Encoding в q-значения алгоритмов.
+// Алгоритмы без параметра q имеют q=1, некорректные q считаются нулевыми.
+func parseAcceptEncoding(header string) map[string]float64 {
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(param, "=")
+			if !ok || strings.ToLower(strings.TrimSpace(key)) != "q" {
+				continue
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || v < 0 || v > 1 {
+				v = 0
+			}
+			q = v
+		}
+		weights[name] = q
+	}
+	return weights
+}
+
+// Negotiate выбирает алгоритм сжатия ответа по заголовку Accept-Encoding.
+//
+// Выбирается поддерживаемый алгоритм с наибольшим q-значением, при равных значениях - в порядке
+// предпочтения сервера. Алгоритмы, не указанные явно, получают q-значение "*".
+// Возвращает false, если клиент не принимает ни один из поддерживаемых алгоритмов.
+func Negotiate(acceptEncoding string) (*Codec, bool) {
+	if strings.TrimSpace(acceptEncoding) == "" {
+		return nil, false
+	}
+	weights := parseAcceptEncoding(acceptEncoding)
+
+	var best *Codec
+	var bestQ float64
+	for _, c := range codecs {
+		q, ok := weights[c.name]
+		if !ok {
+			q = weights[httpconst.AcceptEncodingAll]
+		}
+		if q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+	return best, best != nil
+}
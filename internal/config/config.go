@@ -0,0 +1,190 @@
+// Package config содержит общий загрузчик конфигурации сервера метрик и агента.
+//
+// Конфигурация описывается структурой, поля которой размечены тегами:
+//
+//	json   - ключ в конфигурационном файле (JSON, YAML или TOML);
+//	env    - имя переменной окружения;
+//	flag   - имя флага командной строки, usage - его описание;
+//	secret - значение поля скрывается при выводе конфигурации.
+//
+// Значения применяются в порядке возрастания приоритета: значения по умолчанию, файл, переменные
+// окружения, флаги. Флаг применяется, только если он явно задан в командной строке, поэтому флаг
+// со значением по умолчанию тоже перекрывает значение из файла. Для каждого поля запоминается
+// источник его значения, чтобы вывести итоговую конфигурацию с указанием источников.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+)
+
+// FileFlag имя флага с путем до конфигурационного файла. Поле с этим флагом задает путь до файла
+// и само из файла не загружается.
+const FileFlag = "config"
+
+// Sources источники значений полей конфигурации по именам полей.
+// Поля со значениями по умолчанию в Sources отсутствуют.
+type Sources map[string]string
+
+// Source возвращает источник значения поля name: "file <путь>", "env <переменная>", "flag -<флаг>"
+// или "default".
+func (s Sources) Source(name string) string {
+	if src, ok := s[name]; ok {
+		return src
+	}
+	return "default"
+}
+
+// field поле структуры конфигурации.
+type field struct {
+	name  string
+	key   string
+	env   string
+	flag  string
+	usage string
+	value reflect.Value
+}
+
+// fields возвращает экспортируемые поля структуры конфигурации v.
+func fields(v reflect.Value) []field {
+	t := v.Type()
+	var res []field
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key, _, _ := cutTag(sf.Tag.Get("json"))
+		res = append(res, field{
+			name:  sf.Name,
+			key:   key,
+			env:   sf.Tag.Get("env"),
+			flag:  sf.Tag.Get("flag"),
+			usage: sf.Tag.Get("usage"),
+			value: v.Field(i),
+		})
+	}
+	return res
+}
+
+// flagValue значение флага. Строка сохраняется как есть и разбирается после загрузки файла
+// и переменных окружения, чтобы ошибки всех флагов были выведены вместе.
+type flagValue struct {
+	def    string
+	raw    string
+	isBool bool
+}
+
+// String возвращает значение флага по умолчанию для справки.
+func (f *flagValue) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.def
+}
+
+// Set сохраняет значение флага.
+func (f *flagValue) Set(s string) error {
+	f.raw = s
+	return nil
+}
+
+// IsBoolFlag позволяет задавать логический флаг без значения.
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// Load загружает конфигурацию в структуру по указателю dst, поля которой уже содержат значения
+// по умолчанию. Флаги регистрируются в fs и разбираются из args. Возвращает источники значений полей.
+// Ошибки значений переменных окружения и флагов возвращаются все вместе, при этом источники тоже
+// возвращаются, чтобы вызывающий код мог дополнить ошибки результатами валидации. При ошибке разбора
+// командной строки или конфигурационного файла источники равны nil.
+func Load(fs *flag.FlagSet, args []string, dst any) (Sources, error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: expected pointer to struct, got %T", dst)
+	}
+	all := fields(v.Elem())
+
+	flags := make(map[string]*flagValue)
+	for _, f := range all {
+		if f.flag == "" {
+			continue
+		}
+		fv := &flagValue{def: format(f.value), isBool: f.value.Kind() == reflect.Bool}
+		fs.Var(fv, f.flag, f.usage)
+		flags[f.flag] = fv
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) {
+		set[fl.Name] = true
+	})
+
+	sources := make(Sources)
+	var errList []error
+
+	// Путь до файла нужен раньше остальных значений, поэтому определяется отдельно.
+	if path := filePath(all, flags, set); path != "" {
+		keys, err := loadFile(path, dst)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range all {
+			if f.key != "" && f.key != "-" && keys[f.key] {
+				sources[f.name] = "file " + path
+			}
+		}
+	}
+
+	for _, f := range all {
+		if f.env == "" {
+			continue
+		}
+		// Пустая переменная окружения считается незаданной.
+		raw, ok := os.LookupEnv(f.env)
+		if !ok || raw == "" {
+			continue
+		}
+		if err := setValue(f.value, raw); err != nil {
+			errList = append(errList, fmt.Errorf("переменная окружения %s: %w", f.env, err))
+			continue
+		}
+		sources[f.name] = "env " + f.env
+	}
+
+	for _, f := range all {
+		if f.flag == "" || !set[f.flag] {
+			continue
+		}
+		if err := setValue(f.value, flags[f.flag].raw); err != nil {
+			errList = append(errList, fmt.Errorf("флаг -%s: %w", f.flag, err))
+			continue
+		}
+		sources[f.name] = "flag -" + f.flag
+	}
+
+	return sources, errors.Join(errList...)
+}
+
+// filePath возвращает путь до конфигурационного файла с учетом приоритета флага над переменной окружения.
+func filePath(all []field, flags map[string]*flagValue, set map[string]bool) string {
+	for _, f := range all {
+		if f.flag != FileFlag {
+			continue
+		}
+		if set[f.flag] {
+			return flags[f.flag].raw
+		}
+		if raw := os.Getenv(f.env); f.env != "" && raw != "" {
+			return raw
+		}
+		return format(f.value)
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConfig конфигурация для тестов загрузчика.
+type testConfig struct {
+	Addr       string  `env:"TEST_ADDRESS" json:"address" flag:"a" usage:"Адрес"`
+	Interval   uint64  `env:"TEST_INTERVAL" json:"interval" flag:"i" usage:"Интервал"`
+	Restore    bool    `env:"TEST_RESTORE" json:"restore" flag:"r" usage:"Восстановление"`
+	Rate       float64 `env:"TEST_RATE" json:"rate" flag:"rate" usage:"Частота"`
+	Key        string  `env:"TEST_KEY" json:"key" flag:"k" usage:"Ключ" secret:"true"`
+	ConfigPath string  `env:"TEST_CONFIG" json:"-" flag:"config" usage:"Путь до файла"`
+}
+
+// defaults возвращает конфигурацию со значениями по умолчанию.
+func defaults() testConfig {
+	return testConfig{Addr: "localhost:8080", Interval: 300, Restore: true}
+}
+
+// newFlagSet создает набор флагов, который не выводит справку и не завершает процесс при ошибке.
+func newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// load загружает конфигурацию из аргументов args.
+func load(t *testing.T, args ...string) (testConfig, Sources, error) {
+	t.Helper()
+	cfg := defaults()
+	sources, err := Load(newFlagSet(), args, &cfg)
+	return cfg, sources, err
+}
+
+// writeFile создает в temp-каталоге теста файл name с содержимым content.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{"address": "json:9000", "interval": 10, "restore": false, "rate": 1.5}`,
+		"config.yaml": "address: json:9000\ninterval: 10\nrestore: false\nrate: 1.5\n",
+		"config.toml": "address = \"json:9000\"\ninterval = 10\nrestore = false\nrate = 1.5\n",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := writeFile(t, name, content)
+			cfg, sources, err := load(t, "-config", path)
+			require.NoError(t, err)
+			assert.Equal(t, "json:9000", cfg.Addr)
+			assert.Equal(t, uint64(10), cfg.Interval)
+			assert.False(t, cfg.Restore)
+			assert.Equal(t, 1.5, cfg.Rate)
+			assert.Equal(t, "file "+path, sources.Source("Addr"))
+			assert.Equal(t, "flag -config", sources.Source("ConfigPath"))
+			assert.Equal(t, "default", sources.Source("Key"))
+		})
+	}
+
+	_, sources, err := load(t, "-config", writeFile(t, "broken.yaml", "address: [\n"))
+	assert.ErrorContains(t, err, "YAML")
+	assert.Nil(t, sources)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.json", `{"address": "file:1", "interval": 10, "restore": false}`)
+	t.Setenv("TEST_CONFIG", path)
+	t.Setenv("TEST_INTERVAL", "20")
+	t.Setenv("TEST_ADDRESS", "")
+
+	// Флаг со значением по умолчанию перекрывает значение из файла.
+	cfg, sources, err := load(t, "-i", "300", "-r")
+	require.NoError(t, err)
+	assert.Equal(t, "file:1", cfg.Addr)
+	assert.Equal(t, uint64(300), cfg.Interval)
+	assert.True(t, cfg.Restore)
+	assert.Equal(t, "file "+path, sources.Source("Addr"))
+	assert.Equal(t, "flag -i", sources.Source("Interval"))
+	assert.Equal(t, "flag -r", sources.Source("Restore"))
+	assert.Equal(t, "env TEST_CONFIG", sources.Source("ConfigPath"))
+
+	cfg, sources, err = load(t)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(20), cfg.Interval)
+	assert.Equal(t, "env TEST_INTERVAL", sources.Source("Interval"))
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Setenv("TEST_INTERVAL", "soon")
+	t.Setenv("TEST_RESTORE", "maybe")
+
+	_, sources, err := load(t, "-rate", "fast")
+	require.Error(t, err)
+	assert.NotNil(t, sources)
+	assert.ErrorContains(t, err, "TEST_INTERVAL")
+	assert.ErrorContains(t, err, "TEST_RESTORE")
+	assert.ErrorContains(t, err, "-rate")
+}
+
+func TestDump(t *testing.T) {
+	cfg, sources, err := load(t, "-a", "0.0.0.0:80", "-k", "secret")
+	require.NoError(t, err)
+	Redact(&cfg)
+
+	var buf bytes.Buffer
+	require.NoError(t, Dump(&buf, &cfg, sources))
+	assert.Equal(t, `address: 0.0.0.0:80 # flag -a
+interval: 300
+restore: true
+rate: 0
+key: '[REDACTED]' # flag -k
+`, buf.String())
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RedactedValue значение, которым заменяются секреты в выводимой конфигурации.
+const RedactedValue = "[REDACTED]"
+
+// Redact скрывает в структуре по указателю v непустые строковые поля с тегом secret:"true".
+func Redact(v any) {
+	rv := reflect.ValueOf(v).Elem()
+	t := rv.Type()
+	for i := range t.NumField() {
+		if t.Field(i).Tag.Get("secret") != "true" {
+			continue
+		}
+		if f := rv.Field(i); f.Kind() == reflect.String && f.String() != "" {
+			f.SetString(RedactedValue)
+		}
+	}
+}
+
+// Dump выводит конфигурацию v в формате YAML в порядке полей структуры. Источник значения,
+// отличного от значения по умолчанию, выводится комментарием. Секреты должны быть скрыты
+// вызывающим кодом.
+func Dump(w io.Writer, v any, sources Sources) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range fields(rv) {
+		if f.key == "" || f.key == "-" {
+			continue
+		}
+		var value yaml.Node
+		if err := value.Encode(f.value.Interface()); err != nil {
+			return fmt.Errorf("config: encode %s: %w", f.key, err)
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: f.key}
+		if src, ok := sources[f.name]; ok {
+			// Комментарий к списку или вложенной структуре выводится после ключа.
+			if value.Kind == yaml.ScalarNode {
+				value.LineComment = src
+			} else {
+				key.LineComment = src
+			}
+		}
+		doc.Content = append(doc.Content, key, &value)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration длительность в конфигурации. Задается строкой в формате time.ParseDuration ("500ms", "5m")
+// или целым числом секунд для совместимости с прежними конфигурациями.
+type Duration time.Duration
+
+// Seconds возвращает длительность в n секунд.
+func Seconds(n uint64) Duration {
+	return Duration(time.Duration(n) * time.Second)
+}
+
+// String возвращает длительность в формате time.Duration.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalText кодирует длительность в формате time.Duration.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText разбирает длительность из строки с единицами измерения или из целого числа секунд.
+func (d *Duration) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		if n > uint64(time.Duration(1<<63-1)/time.Second) {
+			return fmt.Errorf("слишком большая длительность %q", s)
+		}
+		*d = Seconds(n)
+		return nil
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("некорректная длительность %q (например 500ms, 10s или 5m)", s)
+	}
+	if v < 0 {
+		return errors.New("длительность не может быть отрицательной")
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// UnmarshalJSON разбирает длительность из строки или из целого числа секунд.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return d.UnmarshalText([]byte(s))
+	}
+	return d.UnmarshalText(data)
+}
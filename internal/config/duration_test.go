@@ -0,0 +1,77 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDurationUnmarshal(t *testing.T) {
+	tests := []struct {
+		text string
+		want time.Duration
+		err  bool
+	}{
+		{text: "500ms", want: 500 * time.Millisecond},
+		{text: "5m", want: 5 * time.Minute},
+		{text: "1h30m", want: 90 * time.Minute},
+		{text: "10", want: 10 * time.Second},
+		{text: " 0 ", want: 0},
+		{text: "-5s", err: true},
+		{text: "soon", err: true},
+		{text: "1.5", err: true},
+		{text: "99999999999999", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var d Duration
+			err := d.UnmarshalText([]byte(tt.text))
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, time.Duration(d))
+		})
+	}
+
+	var v struct {
+		A Duration `json:"a"`
+		B Duration `json:"b"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"a": 300, "b": "250ms"}`), &v))
+	assert.Equal(t, 5*time.Minute, time.Duration(v.A))
+	assert.Equal(t, 250*time.Millisecond, time.Duration(v.B))
+	assert.Error(t, json.Unmarshal([]byte(`{"a": true}`), &v))
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a": "5m0s", "b": "250ms"}`, string(data))
+}
+
+func TestLoadDuration(t *testing.T) {
+	type durations struct {
+		Poll   Duration `env:"TEST_POLL" json:"poll" flag:"p"`
+		Report Duration `env:"TEST_REPORT" json:"report" flag:"r"`
+		Store  Duration `json:"store" flag:"i"`
+		Path   string   `json:"-" flag:"config"`
+	}
+
+	path := writeFile(t, "config.toml", "report = \"1m\"\nstore = 300\n")
+	t.Setenv("TEST_POLL", "500ms")
+
+	cfg := durations{Poll: Seconds(2), Report: Seconds(10)}
+	sources, err := Load(newFlagSet(), []string{"-config", path, "-i", "2"}, &cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, time.Duration(cfg.Poll))
+	assert.Equal(t, time.Minute, time.Duration(cfg.Report))
+	assert.Equal(t, 2*time.Second, time.Duration(cfg.Store))
+
+	var buf bytes.Buffer
+	require.NoError(t, Dump(&buf, &cfg, sources))
+	assert.Equal(t, "poll: 500ms # env TEST_POLL\nreport: 1m0s # file "+path+"\nstore: 2s # flag -i\n", buf.String())
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile загружает конфигурацию из файла path в dst и возвращает множество заданных в файле ключей.
+// Формат определяется по расширению: .yaml и .yml - YAML, .toml - TOML, остальные - JSON.
+// Файлы YAML и TOML используют те же ключи, что и JSON.
+func loadFile(path string, dst any) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл конфигурации: %w", err)
+	}
+
+	values := make(map[string]any)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("ошибка декодирования YAML: %w", err)
+		}
+	case ".toml":
+		if err := toml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("ошибка декодирования TOML: %w", err)
+		}
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&values); err != nil {
+			return nil, fmt.Errorf("ошибка декодирования JSON: %w", err)
+		}
+	}
+
+	// Значения всех форматов приводятся к JSON, чтобы поля заполнялись по тегам json.
+	normalized, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка декодирования файла конфигурации: %w", err)
+	}
+	if err := json.Unmarshal(normalized, dst); err != nil {
+		return nil, fmt.Errorf("ошибка декодирования файла конфигурации: %w", err)
+	}
+
+	keys := make(map[string]bool, len(values))
+	for k := range values {
+		keys[k] = true
+	}
+	return keys, nil
+}
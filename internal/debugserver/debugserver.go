@@ -0,0 +1,120 @@
+// Package debugserver содержит отладочный HTTP-сервер сервера метрик и агента.
+//
+// Отладочный сервер отдает профили pprof (/debug/pprof/), переменные expvar (/debug/vars)
+// и информацию о сборке (/debug/buildinfo). Он слушает отдельный адрес, чтобы профилирование
+// можно было включить, не открывая его вместе с основным API. Если задан токен, запросы
+// должны передавать его в заголовке Authorization: Bearer.
+package debugserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rtdebug "runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gitslim/monit/internal/httpconst"
+	"github.com/gitslim/monit/internal/logging"
+)
+
+// shutdownTimeout время ожидания завершения запросов при остановке отладочного сервера.
+const shutdownTimeout = time.Second
+
+// BuildInfo информация о сборке, заданная при компоновке бинарного файла.
+type BuildInfo struct {
+	Version string `json:"version"`
+	Date    string `json:"date"`
+	Commit  string `json:"commit"`
+}
+
+// buildInfoResponse ответ /debug/buildinfo.
+type buildInfoResponse struct {
+	BuildInfo
+	GoVersion string            `json:"go_version"`
+	Path      string            `json:"path,omitempty"`
+	Settings  map[string]string `json:"settings,omitempty"`
+}
+
+// IsLoopback проверяет, что адрес host:port доступен только с локальной машины.
+func IsLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// NewHandler создает обработчик отладочного сервера. Пустой token отключает аутентификацию.
+func NewHandler(token string, info BuildInfo) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/buildinfo", func(w http.ResponseWriter, r *http.Request) {
+		resp := buildInfoResponse{BuildInfo: info, GoVersion: runtime.Version()}
+		if bi, ok := rtdebug.ReadBuildInfo(); ok {
+			resp.Path = bi.Path
+			resp.Settings = make(map[string]string, len(bi.Settings))
+			for _, s := range bi.Settings {
+				resp.Settings[s.Key] = s.Value
+			}
+		}
+		w.Header().Set(httpconst.HeaderContentType, httpconst.ContentTypeJSON)
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+
+	if token == "" {
+		return mux
+	}
+	return requireToken(token, mux)
+}
+
+// requireToken пропускает к next только запросы с токеном token в заголовке Authorization: Bearer.
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get(httpconst.HeaderAuthorization), httpconst.AuthSchemeBearer+" ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set(httpconst.HeaderWWWAuthenticate, httpconst.AuthSchemeBearer)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Start запускает отладочный сервер на адресе addr в отдельной горутине и останавливает его при отмене ctx.
+func Start(ctx context.Context, log *logging.Logger, addr, token string, info BuildInfo) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           NewHandler(token, info),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		log.Infof("Debug server listening on %s (auth: %v)", addr, token != "")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Debug server failed: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,63 @@
+package debugserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsLoopback(t *testing.T) {
+	assert.True(t, IsLoopback("localhost:6060"))
+	assert.True(t, IsLoopback("127.0.0.1:6060"))
+	assert.True(t, IsLoopback("[::1]:6060"))
+	assert.False(t, IsLoopback(":6060"))
+	assert.False(t, IsLoopback("0.0.0.0:6060"))
+	assert.False(t, IsLoopback("10.0.0.1:6060"))
+	assert.False(t, IsLoopback("localhost"))
+}
+
+func TestHandler(t *testing.T) {
+	handler := NewHandler("secret", BuildInfo{Version: "1.2.3", Date: "N/A", Commit: "abc"})
+
+	do := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{"/debug/pprof/", "/debug/vars", "/debug/buildinfo"} {
+		w := do(path, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, path)
+		assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+		assert.Equal(t, http.StatusUnauthorized, do(path, "wrong").Code, path)
+	}
+
+	assert.Equal(t, http.StatusOK, do("/debug/pprof/", "secret").Code)
+	assert.Equal(t, http.StatusOK, do("/debug/pprof/goroutine?debug=1", "secret").Code)
+
+	w := do("/debug/vars", "secret")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"memstats"`)
+
+	w = do("/debug/buildinfo", "secret")
+	require.Equal(t, http.StatusOK, w.Code)
+	var info buildInfoResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, "1.2.3", info.Version)
+	assert.Equal(t, "abc", info.Commit)
+	assert.NotEmpty(t, info.GoVersion)
+}
+
+func TestHandlerWithoutToken(t *testing.T) {
+	w := httptest.NewRecorder()
+	NewHandler("", BuildInfo{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/buildinfo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
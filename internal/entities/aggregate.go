@@ -0,0 +1,80 @@
+package entities
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gitslim/monit/internal/errs"
+)
+
+// Функции агрегации значений метрики.
+const (
+	AggregateMin  AggregateFunc = "min"
+	AggregateMax  AggregateFunc = "max"
+	AggregateAvg  AggregateFunc = "avg"
+	AggregateSum  AggregateFunc = "sum"
+	AggregateLast AggregateFunc = "last"
+)
+
+// AggregateFunc определяет функцию агрегации: min, max, avg, sum, last или перцентиль вида p95, p99.9.
+type AggregateFunc string
+
+// Percentile возвращает долю перцентиля в диапазоне [0, 1], если функция является перцентилем.
+func (f AggregateFunc) Percentile() (float64, bool) {
+	s, ok := strings.CutPrefix(string(f), "p")
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	// ParseFloat принимает NaN и Inf, которые не проходят сравнения с границами диапазона.
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) || v < 0 || v > 100 {
+		return 0, false
+	}
+	return v / 100, true
+}
+
+// ParseAggregateFuncs разбирает список функций агрегации, разделенных запятыми.
+func ParseAggregateFuncs(s string) ([]AggregateFunc, error) {
+	var funcs []AggregateFunc
+	for _, part := range strings.Split(s, ",") {
+		f := AggregateFunc(strings.TrimSpace(part))
+		switch f {
+		case AggregateMin, AggregateMax, AggregateAvg, AggregateSum, AggregateLast:
+		default:
+			if _, ok := f.Percentile(); !ok {
+				return nil, errs.ErrInvalidQuery
+			}
+		}
+		funcs = append(funcs, f)
+	}
+	return funcs, nil
+}
+
+// AggregateQuery содержит параметры агрегации значений метрики по временным интервалам.
+type AggregateQuery struct {
+	Name  string          // имя метрики
+	Type  MetricType      // тип метрики
+	From  time.Time       // начало периода (включительно)
+	To    time.Time       // конец периода (не включительно)
+	Step  time.Duration   // длительность интервала
+	Funcs []AggregateFunc // функции агрегации
+}
+
+// AggregateBucket содержит агрегированные значения метрики за интервал.
+type AggregateBucket struct {
+	Start  time.Time                 `json:"start"`  // начало интервала
+	Count  int64                     `json:"count"`  // количество значений в интервале
+	Values map[AggregateFunc]float64 `json:"values"` // значения функций агрегации
+}
+
+// AggregateDTO содержит результат агрегации значений метрики.
+type AggregateDTO struct {
+	ID      string             `json:"id"`      // имя метрики
+	MType   string             `json:"type"`    // тип метрики
+	From    time.Time          `json:"from"`    // начало периода
+	To      time.Time          `json:"to"`      // конец периода
+	Step    string             `json:"step"`    // длительность интервала
+	Buckets []*AggregateBucket `json:"buckets"` // интервалы, содержащие значения
+}
@@ -0,0 +1,47 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateFuncPercentile(t *testing.T) {
+	tests := []struct {
+		f    AggregateFunc
+		want float64
+		ok   bool
+	}{
+		{f: "p95", want: 0.95, ok: true},
+		{f: "p99.9", want: 0.999, ok: true},
+		{f: "p0", want: 0, ok: true},
+		{f: "p100", want: 1, ok: true},
+		{f: "p101"},
+		{f: "p-1"},
+		{f: "pNaN"},
+		{f: "pnan"},
+		{f: "pInf"},
+		{f: "p+Inf"},
+		{f: "p-Inf"},
+		{f: "p"},
+		{f: "avg"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.f), func(t *testing.T) {
+			got, ok := tt.f.Percentile()
+			assert.Equal(t, tt.ok, ok)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestParseAggregateFuncs(t *testing.T) {
+	funcs, err := ParseAggregateFuncs("min, p95,last")
+	assert.NoError(t, err)
+	assert.Equal(t, []AggregateFunc{AggregateMin, "p95", AggregateLast}, funcs)
+
+	for _, s := range []string{"median", "avg,pNaN", "pInf", ""} {
+		_, err := ParseAggregateFuncs(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -39,6 +39,22 @@ func GetMetricType(metricTypeStr string) (MetricType, error) {
 	return -1, errs.ErrInvalidMetricType
 }
 
+// MetricKey идентифицирует метрику в хранилище: метрики разных типов могут иметь одинаковые имена.
+type MetricKey struct {
+	Name string
+	Type MetricType
+}
+
+// String возвращает ключ метрики в виде <type>:<name>.
+func (k MetricKey) String() string {
+	return k.Type.String() + ":" + k.Name
+}
+
+// KeyOf возвращает ключ метрики m.
+func KeyOf(m Metric) MetricKey {
+	return MetricKey{Name: m.GetName(), Type: m.GetType()}
+}
+
 // Metric представляет интерфейс для работы с метриками.
 type Metric interface {
 	// GetName Возвращает имя метрики.
@@ -2,6 +2,7 @@ package entities
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/gitslim/monit/internal/errs"
 )
@@ -12,6 +13,21 @@ type MetricDTO struct {
 	MType string   `json:"type"`            // параметр, принимающий значение gauge или counter
 	Delta *int64   `json:"delta,omitempty"` // значение метрики в случае передачи counter
 	Value *float64 `json:"value,omitempty"` // значение метрики в случае передачи gauge
+
+	UpdatedAt *time.Time `json:"updated_at,omitempty"` // время последнего обновления (только в ответах API чтения)
+	UpdatedBy string     `json:"updated_by,omitempty"` // агент, последним обновивший метрику (только в ответах API чтения)
+}
+
+// MetricUpdate описывает последнее обновление метрики.
+type MetricUpdate struct {
+	At    time.Time `json:"at"`              // время обновления
+	Agent string    `json:"agent,omitempty"` // агент, обновивший метрику; пустая строка - анонимный клиент
+}
+
+// MetricListDTO содержит страницу списка метрик.
+type MetricListDTO struct {
+	Metrics    []*MetricDTO `json:"metrics"`               // метрики текущей страницы
+	NextCursor string       `json:"next_cursor,omitempty"` // курсор следующей страницы
 }
 
 // NewCounterMetricDTO создаёт новую метрику типа counter.
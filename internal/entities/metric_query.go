@@ -0,0 +1,89 @@
+package entities
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/gitslim/monit/internal/errs"
+)
+
+// Поля сортировки списка метрик.
+const (
+	SortByName MetricSortField = iota
+	SortByType
+)
+
+// MetricSortField определяет поле сортировки списка метрик.
+type MetricSortField int
+
+// MetricCursor указывает на последнюю метрику предыдущей страницы.
+type MetricCursor struct {
+	Name string `json:"n"`
+	Type string `json:"t"`
+}
+
+// Encode кодирует курсор в непрозрачную строку.
+func (c *MetricCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeMetricCursor декодирует курсор из строки.
+func DecodeMetricCursor(s string) (*MetricCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errs.ErrInvalidQuery
+	}
+	var c MetricCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Name == "" {
+		return nil, errs.ErrInvalidQuery
+	}
+	if _, err := GetMetricType(c.Type); err != nil {
+		return nil, errs.ErrInvalidQuery
+	}
+	return &c, nil
+}
+
+// MetricQuery содержит параметры выборки списка метрик.
+type MetricQuery struct {
+	Types      []MetricType    // фильтр по типам, пустой - все типы
+	NamePrefix string          // фильтр по префиксу имени
+	NameRegex  string          // фильтр по регулярному выражению имени
+	SortBy     MetricSortField // поле сортировки
+	SortDesc   bool            // сортировка по убыванию
+	After      *MetricCursor   // курсор, после которого начинается выборка
+	Limit      int             // максимальное количество метрик, 0 - без ограничения
+}
+
+// ParseMetricSort разбирает параметр сортировки вида "name", "-name", "type", "-type".
+func ParseMetricSort(s string) (MetricSortField, bool, error) {
+	desc := strings.HasPrefix(s, "-")
+	switch strings.TrimPrefix(s, "-") {
+	case "", "name":
+		return SortByName, desc, nil
+	case "type":
+		return SortByType, desc, nil
+	default:
+		return SortByName, false, errs.ErrInvalidQuery
+	}
+}
+
+// SortKey возвращает ключ сортировки метрики для заданного поля.
+func SortKey(field MetricSortField, name, mType string) [2]string {
+	if field == SortByType {
+		return [2]string{mType, name}
+	}
+	return [2]string{name, mType}
+}
+
+// Less сравнивает ключи сортировки с учетом направления.
+func (q *MetricQuery) Less(a, b [2]string) bool {
+	if q.SortDesc {
+		a, b = b, a
+	}
+	if a[0] != b[0] {
+		return a[0] < b[0]
+	}
+	return a[1] < b[1]
+}
@@ -10,6 +10,12 @@ var (
 	ErrMetricNotFound     = NewError(http.StatusNotFound, "metric not found")
 	ErrInvalidMetricType  = NewError(http.StatusBadRequest, "invalid metric type")
 	ErrInvalidMetricValue = NewError(http.StatusBadRequest, "invalid metric value")
+	ErrInvalidQuery       = NewError(http.StatusBadRequest, "invalid query")
+	ErrHistoryDisabled    = NewError(http.StatusNotImplemented, "metric history disabled")
+	ErrUnauthorized       = NewError(http.StatusUnauthorized, "unauthorized")
+	ErrForbidden          = NewError(http.StatusForbidden, "forbidden")
+	ErrRequestTooLarge    = NewError(http.StatusRequestEntityTooLarge, "request body too large")
+	ErrReservedMetric     = NewError(http.StatusForbidden, "reserved metric name")
 )
 
 // Error определяет сигнальную ошибку.
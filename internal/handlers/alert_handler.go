@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/alerting"
+	"github.com/gitslim/monit/internal/errs"
+	"github.com/gitslim/monit/internal/logging"
+)
+
+// AlertHandler представляет обработчик оповещений.
+type AlertHandler struct {
+	log    *logging.Logger
+	engine *alerting.Engine
+}
+
+// NewAlertHandler создает обработчик оповещений.
+func NewAlertHandler(log *logging.Logger, engine *alerting.Engine) *AlertHandler {
+	return &AlertHandler{log: log, engine: engine}
+}
+
+// ListAlerts возвращает список оповещений в формате JSON.
+// По умолчанию возвращаются оповещения в состоянии firing,
+// параметр state позволяет выбрать другие состояния (можно указать несколько раз, all - все активные).
+func (h *AlertHandler) ListAlerts(c *gin.Context) {
+	var states []alerting.State
+
+	switch params := c.QueryArray("state"); {
+	case len(params) == 0:
+		states = []alerting.State{alerting.StateFiring}
+	case len(params) == 1 && params[0] == "all":
+	default:
+		for _, p := range params {
+			state := alerting.State(p)
+			switch state {
+			case alerting.StatePending, alerting.StateFiring, alerting.StateResolved:
+				states = append(states, state)
+			default:
+				writeError(c, h.log, errs.ErrInvalidQuery)
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, h.engine.Alerts(states...))
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/health"
+)
+
+// HealthHandler представляет обработчик проверок работоспособности и готовности сервера.
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+// NewHealthHandler создает обработчик проверок работоспособности и готовности сервера.
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Healthz сообщает, что сервер жив и обрабатывает запросы.
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK, "phase": h.checker.Phase()})
+}
+
+// Readyz возвращает результаты проверок компонентов сервера в формате JSON
+// с кодом 200, если сервер готов принимать запросы, иначе с кодом 503.
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := h.checker.Check(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
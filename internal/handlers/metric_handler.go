@@ -5,23 +5,29 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/auth"
 	"github.com/gitslim/monit/internal/entities"
 	"github.com/gitslim/monit/internal/errs"
 	"github.com/gitslim/monit/internal/httpconst"
+	"github.com/gitslim/monit/internal/logging"
 	"github.com/gitslim/monit/internal/services"
 )
 
 // MetricHandler представляет обработчик метрик.
 type MetricHandler struct {
-	metricService services.MetricService
+	log           *logging.Logger
+	metricService *services.MetricService
 }
 
 // NewMetricHandler создает обработчик метрик.
-func NewMetricHandler(metricService *services.MetricService) *MetricHandler {
-	return &MetricHandler{metricService: *metricService}
+func NewMetricHandler(log *logging.Logger, metricService *services.MetricService) *MetricHandler {
+	return &MetricHandler{log: log, metricService: metricService}
 }
 
 // isJSONRequest проверяет является ли запрос JSON.
@@ -29,11 +35,16 @@ func isJSONRequest(c *gin.Context) bool {
 	return c.GetHeader(httpconst.HeaderContentType) == httpconst.ContentTypeJSON
 }
 
-// writeError записывает ошибку в ответ сервера.
-func writeError(c *gin.Context, err error) {
-	fmt.Printf("Error: %v\n", err)
+// writeError записывает ошибку в ответ сервера. Ошибки клиента логируются с уровнем debug,
+// внутренние ошибки сервера - с уровнем error.
+func writeError(c *gin.Context, log *logging.Logger, err error) {
 	var e *errs.Error
 	if errors.As(err, &e) {
+		if e.Code >= http.StatusInternalServerError {
+			log.Errorf("%s %s failed: %v", c.Request.Method, c.Request.URL.Path, err)
+		} else {
+			log.Debugf("%s %s rejected: %v", c.Request.Method, c.Request.URL.Path, err)
+		}
 		if isJSONRequest(c) {
 			c.JSON(e.Code, e.Error())
 		} else {
@@ -42,6 +53,7 @@ func writeError(c *gin.Context, err error) {
 		return
 	}
 
+	log.Errorf("%s %s failed: %v", c.Request.Method, c.Request.URL.Path, err)
 	msg := "Internal server error"
 	if isJSONRequest(c) {
 		c.JSON(http.StatusInternalServerError, msg)
@@ -50,6 +62,16 @@ func writeError(c *gin.Context, err error) {
 	}
 }
 
+// decodeError возвращает ошибку ответа для ошибки декодирования тела запроса:
+// ErrRequestTooLarge, если тело превысило допустимый размер, иначе fallback.
+func decodeError(err error, fallback error) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return errs.ErrRequestTooLarge
+	}
+	return fallback
+}
+
 // UpdateMetric обновляет метрику.
 func (h *MetricHandler) UpdateMetric(c *gin.Context) {
 	var mType, mName, mValue string
@@ -57,7 +79,7 @@ func (h *MetricHandler) UpdateMetric(c *gin.Context) {
 	if isJSONRequest(c) {
 		dto := &entities.MetricDTO{}
 		if err := json.NewDecoder(c.Request.Body).Decode(dto); err != nil {
-			writeError(c, errs.ErrBadRequest)
+			writeError(c, h.log, decodeError(err, errs.ErrBadRequest))
 			return
 		}
 
@@ -65,38 +87,39 @@ func (h *MetricHandler) UpdateMetric(c *gin.Context) {
 		switch mType {
 		case "counter":
 			if dto.Delta == nil {
-				writeError(c, errs.ErrBadRequest)
+				writeError(c, h.log, errs.ErrBadRequest)
 				return
 			}
 			mValue = strconv.FormatInt(*dto.Delta, 10)
 		case "gauge":
 			if dto.Value == nil {
-				writeError(c, errs.ErrBadRequest)
+				writeError(c, h.log, errs.ErrBadRequest)
 				return
 			}
 			mValue = strconv.FormatFloat(*dto.Value, 'f', -1, 64)
 		default:
-			writeError(c, errs.ErrBadRequest)
+			writeError(c, h.log, errs.ErrBadRequest)
 			return
 		}
 	} else {
 		mType, mName, mValue = c.Param("type"), c.Param("name"), c.Param("value")
 	}
 
-	if err := h.metricService.UpdateMetric(mName, mType, mValue); err != nil {
-		writeError(c, err)
+	if err := h.metricService.UpdateMetric(c.GetString(auth.AgentKey), mName, mType, mValue); err != nil {
+		writeError(c, h.log, err)
 		return
 	}
+	c.Set(httpconst.ContextKeyMetricsIngested, 1)
 
 	if isJSONRequest(c) {
 		metric, err := h.metricService.GetMetric(mName, mType)
 		if err != nil {
-			writeError(c, err)
+			writeError(c, h.log, err)
 			return
 		}
 		dto, err := entities.NewMetricDTO(metric.GetName(), metric.GetType().String(), metric.GetValue())
 		if err != nil {
-			writeError(c, err)
+			writeError(c, h.log, err)
 			return
 		}
 		c.JSON(http.StatusOK, dto)
@@ -114,15 +137,16 @@ func (h *MetricHandler) BatchUpdateMetrics(c *gin.Context) {
 
 	err := json.NewDecoder(c.Request.Body).Decode(&metrics)
 	if err != nil {
-		writeError(c, fmt.Errorf("error decoding JSON: %w", err))
+		writeError(c, h.log, decodeError(err, fmt.Errorf("error decoding JSON: %w", err)))
 		return
 	}
-	fmt.Printf("Metrics: %v\n", metrics)
+	h.log.Debugf("Batch update of %d metrics", len(metrics))
 
-	if err := h.metricService.BatchUpdateMetrics(metrics); err != nil {
-		writeError(c, err)
+	if err := h.metricService.BatchUpdateMetrics(c.GetString(auth.AgentKey), metrics); err != nil {
+		writeError(c, h.log, err)
 		return
 	}
+	c.Set(httpconst.ContextKeyMetricsIngested, len(metrics))
 
 	c.JSON(http.StatusOK, metrics)
 }
@@ -136,7 +160,7 @@ func (h *MetricHandler) GetMetric(c *gin.Context) {
 
 		err := json.NewDecoder(c.Request.Body).Decode(&dto)
 		if err != nil {
-			writeError(c, err)
+			writeError(c, h.log, decodeError(err, err))
 			return
 		}
 
@@ -150,14 +174,14 @@ func (h *MetricHandler) GetMetric(c *gin.Context) {
 
 	m, err := h.metricService.GetMetric(mName, mType)
 	if err != nil {
-		writeError(c, err)
+		writeError(c, h.log, err)
 		return
 	}
 
 	if isJSONRequest(c) {
 		dto, err := entities.NewMetricDTO(m.GetName(), m.GetType().String(), m.GetValue())
 		if err != nil {
-			writeError(c, err)
+			writeError(c, h.log, err)
 			return
 		}
 		c.JSON(http.StatusOK, dto)
@@ -166,6 +190,34 @@ func (h *MetricHandler) GetMetric(c *gin.Context) {
 	}
 }
 
+// metricGroup группа метрик одного типа для отображения на панели метрик.
+type metricGroup struct {
+	Type    string            // тип метрик
+	Title   string            // заголовок группы
+	Metrics []entities.Metric // метрики, отсортированные по имени
+}
+
+// groupMetrics группирует метрики по типу и сортирует их по имени.
+func groupMetrics(metrics map[entities.MetricKey]entities.Metric) []metricGroup {
+	groups := []metricGroup{
+		{Type: entities.Gauge.String(), Title: "Gauges"},
+		{Type: entities.Counter.String(), Title: "Counters"},
+	}
+	for _, m := range metrics {
+		for i := range groups {
+			if groups[i].Type == m.GetType().String() {
+				groups[i].Metrics = append(groups[i].Metrics, m)
+			}
+		}
+	}
+	for _, g := range groups {
+		slices.SortFunc(g.Metrics, func(a, b entities.Metric) int {
+			return strings.Compare(a.GetName(), b.GetName())
+		})
+	}
+	return groups
+}
+
 // ListMetrics возвращает список метрик в виде HTML-страницы.
 func (h *MetricHandler) ListMetrics(c *gin.Context) {
 	metrics, err := h.metricService.GetAllMetrics()
@@ -174,12 +226,189 @@ func (h *MetricHandler) ListMetrics(c *gin.Context) {
 		return
 	}
 	res := gin.H{
-		"metrics": metrics,
+		"groups": groupMetrics(metrics),
 	}
 
 	c.HTML(http.StatusOK, "metrics.html", res)
 }
 
+// QueryMetrics возвращает список метрик в формате JSON с фильтрацией, сортировкой и пагинацией.
+//
+// Параметры запроса:
+//   - type - тип метрик (можно указать несколько раз);
+//   - prefix - префикс имени метрики;
+//   - regex - регулярное выражение для имени метрики;
+//   - sort - поле сортировки: name, -name, type, -type;
+//   - limit - размер страницы;
+//   - cursor - курсор следующей страницы из предыдущего ответа.
+func (h *MetricHandler) QueryMetrics(c *gin.Context) {
+	// Метрики не содержат меток, поэтому фильтрация по ним невозможна.
+	if _, ok := c.GetQuery("label"); ok {
+		writeError(c, h.log, errs.ErrInvalidQuery)
+		return
+	}
+
+	q := entities.MetricQuery{
+		NamePrefix: c.Query("prefix"),
+		NameRegex:  c.Query("regex"),
+	}
+
+	for _, t := range c.QueryArray("type") {
+		mType, err := entities.GetMetricType(t)
+		if err != nil {
+			writeError(c, h.log, err)
+			return
+		}
+		q.Types = append(q.Types, mType)
+	}
+
+	sortBy, desc, err := entities.ParseMetricSort(c.Query("sort"))
+	if err != nil {
+		writeError(c, h.log, err)
+		return
+	}
+	q.SortBy, q.SortDesc = sortBy, desc
+
+	if limit := c.Query("limit"); limit != "" {
+		q.Limit, err = strconv.Atoi(limit)
+		if err != nil || q.Limit < 0 {
+			writeError(c, h.log, errs.ErrInvalidQuery)
+			return
+		}
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		q.After, err = entities.DecodeMetricCursor(cursor)
+		if err != nil {
+			writeError(c, h.log, err)
+			return
+		}
+	}
+
+	metrics, next, err := h.metricService.ListMetrics(q)
+	if err != nil {
+		writeError(c, h.log, err)
+		return
+	}
+
+	keys := make([]entities.MetricKey, 0, len(metrics))
+	for _, m := range metrics {
+		keys = append(keys, entities.KeyOf(m))
+	}
+	updates, err := h.metricService.LastUpdates(keys)
+	if err != nil {
+		writeError(c, h.log, err)
+		return
+	}
+
+	res := entities.MetricListDTO{Metrics: make([]*entities.MetricDTO, 0, len(metrics))}
+	for _, m := range metrics {
+		dto, err := entities.NewMetricDTO(m.GetName(), m.GetType().String(), m.GetValue())
+		if err != nil {
+			writeError(c, h.log, err)
+			return
+		}
+		if u, ok := updates[entities.KeyOf(m)]; ok {
+			dto.UpdatedAt, dto.UpdatedBy = &u.At, u.Agent
+		}
+		res.Metrics = append(res.Metrics, dto)
+	}
+	if next != nil {
+		res.NextCursor = next.Encode()
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// Значения параметров агрегации по умолчанию.
+const (
+	defaultAggregateRange = time.Hour
+	defaultAggregateStep  = time.Minute
+)
+
+// AggregateMetric возвращает агрегированные по интервалам значения метрики в формате JSON.
+//
+// Параметры запроса:
+//   - from, to - границы периода в формате RFC3339 (по умолчанию to - текущее время);
+//   - range - длительность периода, если from не задан (по умолчанию 1h);
+//   - step - длительность интервала (по умолчанию 1m);
+//   - agg - функции агрегации через запятую: min, max, avg, sum, last, pNN (по умолчанию avg).
+func (h *MetricHandler) AggregateMetric(c *gin.Context) {
+	mName, mTypeStr := c.Param("name"), c.Param("type")
+	mType, err := entities.GetMetricType(mTypeStr)
+	if err != nil {
+		writeError(c, h.log, err)
+		return
+	}
+
+	q := entities.AggregateQuery{
+		Name: mName,
+		Type: mType,
+		To:   time.Now(),
+		Step: defaultAggregateStep,
+	}
+
+	if to := c.Query("to"); to != "" {
+		if q.To, err = time.Parse(time.RFC3339, to); err != nil {
+			writeError(c, h.log, errs.ErrInvalidQuery)
+			return
+		}
+	}
+
+	if from := c.Query("from"); from != "" {
+		if q.From, err = time.Parse(time.RFC3339, from); err != nil {
+			writeError(c, h.log, errs.ErrInvalidQuery)
+			return
+		}
+	} else {
+		period := defaultAggregateRange
+		if r := c.Query("range"); r != "" {
+			if period, err = time.ParseDuration(r); err != nil {
+				writeError(c, h.log, errs.ErrInvalidQuery)
+				return
+			}
+		}
+		q.From = q.To.Add(-period)
+	}
+
+	if step := c.Query("step"); step != "" {
+		if q.Step, err = time.ParseDuration(step); err != nil {
+			writeError(c, h.log, errs.ErrInvalidQuery)
+			return
+		}
+	}
+
+	q.Funcs, err = entities.ParseAggregateFuncs(c.DefaultQuery("agg", string(entities.AggregateAvg)))
+	if err != nil {
+		writeError(c, h.log, err)
+		return
+	}
+
+	buckets, err := h.metricService.AggregateMetric(q)
+	if err != nil {
+		writeError(c, h.log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.AggregateDTO{
+		ID:      mName,
+		MType:   mType.String(),
+		From:    q.From,
+		To:      q.To,
+		Step:    q.Step.String(),
+		Buckets: buckets,
+	})
+}
+
+// SelfMetrics возвращает текущие значения метрик сервера в формате JSON.
+func (h *MetricHandler) SelfMetrics(c *gin.Context) {
+	metrics := h.metricService.SelfMetrics()
+	if metrics == nil {
+		metrics = []*entities.MetricDTO{}
+	}
+	c.JSON(http.StatusOK, entities.MetricListDTO{Metrics: metrics})
+}
+
 // PingStorage проверяет соединение с хранилищем.
 func (h *MetricHandler) PingStorage(c *gin.Context) {
 	if err := h.metricService.PingStorage(); err != nil {
@@ -249,6 +249,25 @@ func updateMetricsBatch(t *testing.T, r *gin.Engine) {
 		err = res.Body.Close()
 		assert.NoError(t, err)
 	})
+	t.Run("MissingValue", func(t *testing.T) {
+		for _, jsonData := range []string{
+			`[{"id":"g2","type":"gauge"}]`,
+			`[{"id":"c2","type":"counter","value":1}]`,
+		} {
+			req, err := http.NewRequest(http.MethodPost, "/updates/", strings.NewReader(jsonData))
+			assert.NoError(t, err)
+
+			req.Header.Add(httpconst.HeaderContentType, httpconst.ContentTypeJSON)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			res := w.Result()
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, jsonData)
+
+			err = res.Body.Close()
+			assert.NoError(t, err)
+		}
+	})
 	t.Run("Get", func(t *testing.T) {
 		url := "/value/"
 		tt := tests[0]
@@ -299,6 +318,178 @@ func updateMetricsBatch(t *testing.T, r *gin.Engine) {
 	})
 }
 
+// TestQueryMetrics тестирует получение списка метрик с фильтрацией, сортировкой и пагинацией.
+func TestQueryMetrics(t *testing.T) {
+	r, teardown, err := testhelpers.CreateServerMock(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer teardown()
+
+	payload := `[
+		{"id":"cpu_user","type":"gauge","value":1.5},
+		{"id":"cpu_system","type":"gauge","value":2.5},
+		{"id":"cpu_count","type":"counter","delta":4},
+		{"id":"mem_free","type":"gauge","value":100}
+	]`
+	req, err := http.NewRequest(http.MethodPost, "/updates/", strings.NewReader(payload))
+	assert.NoError(t, err)
+	req.Header.Add(httpconst.HeaderContentType, httpconst.ContentTypeJSON)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	query := func(params string) (int, entities.MetricListDTO) {
+		req, err := http.NewRequest(http.MethodGet, "/api/metrics?"+params, nil)
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var res entities.MetricListDTO
+		if w.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		}
+		return w.Code, res
+	}
+
+	names := func(res entities.MetricListDTO) []string {
+		var ids []string
+		for _, m := range res.Metrics {
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name       string
+		params     string
+		statusCode int
+		want       []string
+	}{
+		{
+			name:       "all sorted by name",
+			params:     "",
+			statusCode: http.StatusOK,
+			want:       []string{"cpu_count", "cpu_system", "cpu_user", "mem_free"},
+		},
+		{
+			name:       "prefix and type",
+			params:     "prefix=cpu_&type=gauge",
+			statusCode: http.StatusOK,
+			want:       []string{"cpu_system", "cpu_user"},
+		},
+		{
+			name:       "regex desc",
+			params:     "regex=^(cpu_u|mem)&sort=-name",
+			statusCode: http.StatusOK,
+			want:       []string{"mem_free", "cpu_user"},
+		},
+		{
+			name:       "sort by type",
+			params:     "sort=type",
+			statusCode: http.StatusOK,
+			want:       []string{"cpu_count", "cpu_system", "cpu_user", "mem_free"},
+		},
+		{
+			name:       "invalid regex",
+			params:     "regex=(",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid sort",
+			params:     "sort=value",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid cursor",
+			params:     "cursor=foo",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "label matchers",
+			params:     "label=host%3Dweb1",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, res := query(tt.params)
+			assert.Equal(t, tt.statusCode, code)
+			if tt.statusCode == http.StatusOK {
+				assert.Equal(t, tt.want, names(res))
+				assert.Empty(t, res.NextCursor)
+			}
+		})
+	}
+
+	t.Run("pagination", func(t *testing.T) {
+		var got []string
+		params := "limit=3"
+		for pages := 0; pages < 5; pages++ {
+			code, res := query(params)
+			assert.Equal(t, http.StatusOK, code)
+			got = append(got, names(res)...)
+			if res.NextCursor == "" {
+				break
+			}
+			params = "limit=3&cursor=" + res.NextCursor
+		}
+		assert.Equal(t, []string{"cpu_count", "cpu_system", "cpu_user", "mem_free"}, got)
+	})
+}
+
+// TestAggregateMetric тестирует агрегацию значений метрики по интервалам.
+func TestAggregateMetric(t *testing.T) {
+	r, teardown, err := testhelpers.CreateServerMock(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer teardown()
+
+	for _, v := range []string{"1", "2", "6"} {
+		req, err := http.NewRequest(http.MethodPost, "/update/gauge/load/"+v, nil)
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		statusCode int
+	}{
+		{name: "valid", url: "/api/aggregate/gauge/load?range=1m&step=1m&agg=avg,max,last", statusCode: http.StatusOK},
+		{name: "invalid type", url: "/api/aggregate/foo/load", statusCode: http.StatusBadRequest},
+		{name: "invalid func", url: "/api/aggregate/gauge/load?agg=median", statusCode: http.StatusBadRequest},
+		{name: "percentile NaN", url: "/api/aggregate/gauge/load?agg=pNaN", statusCode: http.StatusBadRequest},
+		{name: "percentile Inf", url: "/api/aggregate/gauge/load?agg=pInf", statusCode: http.StatusBadRequest},
+		{name: "invalid step", url: "/api/aggregate/gauge/load?step=0s", statusCode: http.StatusBadRequest},
+		{name: "too many buckets", url: "/api/aggregate/gauge/load?range=24h&step=1s", statusCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			assert.NoError(t, err)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+			var res entities.AggregateDTO
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			if assert.Len(t, res.Buckets, 1) {
+				assert.Equal(t, int64(3), res.Buckets[0].Count)
+				assert.Equal(t, 3.0, res.Buckets[0].Values["avg"])
+				assert.Equal(t, 6.0, res.Buckets[0].Values["max"])
+				assert.Equal(t, 6.0, res.Buckets[0].Values["last"])
+			}
+		})
+	}
+}
+
 // ExampleMetricHandler_ListMetrics пример получения html страницы со списком метрик.
 func ExampleMetricHandler_ListMetrics() {
 	// создаем сервер
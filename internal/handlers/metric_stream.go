@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/errs"
+	"github.com/gitslim/monit/internal/httpconst"
+	"github.com/gitslim/monit/internal/services"
+)
+
+// Интервалы heartbeat потока обновлений метрик.
+const (
+	defaultHeartbeat = 15 * time.Second
+	minHeartbeat     = time.Second
+)
+
+// StreamMetrics передает обновления метрик клиенту в виде Server-Sent Events.
+//
+// Каждое обновление отправляется событием metric с DTO метрики в поле data.
+// Если клиент не успевает читать поток, обновления одной метрики объединяются.
+//
+// Параметры запроса:
+//   - name - имя метрики (можно указать несколько раз);
+//   - prefix - префикс имени метрики;
+//   - type - тип метрик (можно указать несколько раз);
+//   - heartbeat - интервал отправки heartbeat-комментариев (по умолчанию 15s).
+func (h *MetricHandler) StreamMetrics(c *gin.Context) {
+	filter := services.MetricFilter{
+		Names:  c.QueryArray("name"),
+		Prefix: c.Query("prefix"),
+	}
+	for _, t := range c.QueryArray("type") {
+		mType, err := entities.GetMetricType(t)
+		if err != nil {
+			writeError(c, h.log, err)
+			return
+		}
+		filter.Types = append(filter.Types, mType)
+	}
+
+	heartbeat := defaultHeartbeat
+	if hb := c.Query("heartbeat"); hb != "" {
+		d, err := time.ParseDuration(hb)
+		if err != nil || d < minHeartbeat {
+			writeError(c, h.log, errs.ErrInvalidQuery)
+			return
+		}
+		heartbeat = d
+	}
+
+	sub := h.metricService.Subscribe(filter)
+	defer h.metricService.Unsubscribe(sub)
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	c.Header(httpconst.HeaderContentType, httpconst.ContentTypeEventStream)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return false
+			}
+		case <-sub.C():
+			for _, dto := range sub.Next() {
+				c.SSEvent("metric", dto)
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,75 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/httpconst"
+	"github.com/gitslim/monit/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStreamMetrics тестирует получение обновлений метрик через Server-Sent Events.
+func TestStreamMetrics(t *testing.T) {
+	r, teardown, err := testhelpers.CreateServerMock(false)
+	require.NoError(t, err)
+	defer teardown()
+
+	srv, teardown, err := testhelpers.StartServerMock(r)
+	require.NoError(t, err)
+	defer teardown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	url := fmt.Sprintf("http://%s/stream?type=gauge&prefix=stream_&heartbeat=1s", srv.Addr)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set(httpconst.HeaderAccept, httpconst.ContentTypeEventStream)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = res.Body.Close() }()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, httpconst.ContentTypeEventStream, res.Header.Get(httpconst.HeaderContentType))
+
+	// Обновляем метрики после подписки: counter и метрика с другим префиксом отфильтровываются.
+	payload := `[
+		{"id":"stream_c","type":"counter","delta":1},
+		{"id":"other_g","type":"gauge","value":1},
+		{"id":"stream_g","type":"gauge","value":42.5}
+	]`
+	updReq, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/updates/", srv.Addr), strings.NewReader(payload))
+	require.NoError(t, err)
+	updReq.Header.Set(httpconst.HeaderContentType, httpconst.ContentTypeJSON)
+	updRes, err := http.DefaultClient.Do(updReq)
+	require.NoError(t, err)
+	require.NoError(t, updRes.Body.Close())
+
+	var event string
+	var dto entities.MetricDTO
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if v, ok := strings.CutPrefix(line, "event:"); ok {
+			event = v
+		}
+		if v, ok := strings.CutPrefix(line, "data:"); ok {
+			require.NoError(t, json.Unmarshal([]byte(v), &dto))
+			break
+		}
+	}
+
+	assert.Equal(t, "metric", event)
+	assert.Equal(t, "stream_g", dto.ID)
+	require.NotNil(t, dto.Value)
+	assert.Equal(t, 42.5, *dto.Value)
+}
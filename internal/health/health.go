@@ -0,0 +1,177 @@
+// Package health содержит проверки работоспособности и готовности сервера метрик.
+//
+// Сервер жив, пока обрабатывает запросы. Готовность дополнительно зависит от фазы жизненного цикла
+// сервера и результатов проверок компонентов: хранилища, резервного копирования, схемы базы данных.
+// Во время восстановления данных при запуске и при остановке сервер не готов, чтобы балансировщик
+// нагрузки не направлял на него запросы.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Фазы жизненного цикла сервера.
+const (
+	PhaseStarting Phase = "starting"
+	PhaseReady    Phase = "ready"
+	PhaseStopping Phase = "stopping"
+)
+
+// Phase определяет фазу жизненного цикла сервера.
+type Phase string
+
+// Статусы проверок.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// DefaultCheckTimeout время, за которое должна завершиться проверка компонента.
+const DefaultCheckTimeout = 2 * time.Second
+
+// ErrCheckTimeout ошибка проверки, не завершившейся за отведенное время.
+var ErrCheckTimeout = errors.New("check timed out")
+
+// CheckFunc проверяет компонент сервера и возвращает ошибку, если компонент неработоспособен.
+type CheckFunc func(ctx context.Context) error
+
+// ComponentStatus результат проверки компонента.
+type ComponentStatus struct {
+	Status          string  `json:"status"`
+	Error           string  `json:"error,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// Report результат проверки готовности сервера.
+type Report struct {
+	Status     string                     `json:"status"`
+	Phase      Phase                      `json:"phase"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// Ready проверяет, что сервер готов принимать запросы.
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// check именованная проверка компонента.
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker хранит фазу жизненного цикла сервера и проверки его компонентов.
+// Методы безопасны для конкурентного использования, nil-значение всегда готово и не содержит проверок.
+type Checker struct {
+	mu      sync.RWMutex
+	phase   Phase
+	checks  []check
+	timeout time.Duration
+}
+
+// NewChecker создает проверку готовности сервера в фазе запуска.
+func NewChecker() *Checker {
+	return &Checker{
+		phase:   PhaseStarting,
+		timeout: DefaultCheckTimeout,
+	}
+}
+
+// Register добавляет проверку компонента name.
+func (h *Checker) Register(name string, fn CheckFunc) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, check{name: name, fn: fn})
+}
+
+// SetPhase устанавливает фазу жизненного цикла сервера.
+func (h *Checker) SetPhase(phase Phase) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.phase = phase
+}
+
+// Phase возвращает фазу жизненного цикла сервера.
+func (h *Checker) Phase() Phase {
+	if h == nil {
+		return PhaseReady
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.phase
+}
+
+// Check выполняет проверки компонентов параллельно и возвращает отчет о готовности сервера.
+// Сервер готов, если он в фазе ready и все проверки прошли успешно.
+func (h *Checker) Check(ctx context.Context) Report {
+	report := Report{
+		Status:     StatusOK,
+		Phase:      h.Phase(),
+		Components: make(map[string]ComponentStatus),
+	}
+	if h == nil {
+		return report
+	}
+
+	h.mu.RLock()
+	checks := h.checks
+	timeout := h.timeout
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	results := make([]ComponentStatus, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, c.fn)
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		report.Components[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	if report.Phase != PhaseReady {
+		report.Status = StatusFail
+	}
+	return report
+}
+
+// run выполняет проверку fn. Проверка, не завершившаяся до отмены ctx, считается неуспешной.
+func run(ctx context.Context, fn CheckFunc) ComponentStatus {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ErrCheckTimeout
+	}
+
+	status := ComponentStatus{Status: StatusOK, DurationSeconds: time.Since(start).Seconds()}
+	if err != nil {
+		status.Status = StatusFail
+		status.Error = err.Error()
+	}
+	return status
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	h := NewChecker()
+	h.Register("storage", func(context.Context) error { return nil })
+
+	// Запускающийся сервер не готов, даже если все проверки прошли.
+	report := h.Check(context.Background())
+	assert.False(t, report.Ready())
+	assert.Equal(t, PhaseStarting, report.Phase)
+	assert.Equal(t, StatusOK, report.Components["storage"].Status)
+
+	h.SetPhase(PhaseReady)
+	report = h.Check(context.Background())
+	assert.True(t, report.Ready())
+
+	h.Register("backup", func(context.Context) error { return errors.New("disk full") })
+	report = h.Check(context.Background())
+	assert.False(t, report.Ready())
+	assert.Equal(t, StatusFail, report.Components["backup"].Status)
+	assert.Equal(t, "disk full", report.Components["backup"].Error)
+
+	h.SetPhase(PhaseStopping)
+	assert.Equal(t, PhaseStopping, h.Check(context.Background()).Phase)
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	h := NewChecker()
+	h.timeout = 10 * time.Millisecond
+	h.SetPhase(PhaseReady)
+
+	block := make(chan struct{})
+	defer close(block)
+	h.Register("slow", func(context.Context) error {
+		<-block
+		return nil
+	})
+
+	report := h.Check(context.Background())
+	assert.False(t, report.Ready())
+	assert.Equal(t, ErrCheckTimeout.Error(), report.Components["slow"].Error)
+}
+
+func TestNilChecker(t *testing.T) {
+	var h *Checker
+	h.Register("storage", func(context.Context) error { return errors.New("unused") })
+	h.SetPhase(PhaseStopping)
+	assert.Equal(t, PhaseReady, h.Phase())
+	assert.True(t, h.Check(context.Background()).Ready())
+}
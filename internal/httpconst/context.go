@@ -0,0 +1,8 @@
+package httpconst
+
+// Ключи значений в контексте запроса Gin.
+const (
+	ContextKeyRequestID       = "request_id"       // string идентификатор запроса
+	ContextKeyMetricsIngested = "metrics_ingested" // int количество метрик, принятых обработчиком
+	ContextKeySigned          = "signed"           // bool подпись запроса проверена
+)
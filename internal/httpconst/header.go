@@ -4,20 +4,32 @@ package httpconst
 // HTTP header keys.
 const (
 	HeaderContentType     = "Content-Type"
+	HeaderAccept          = "Accept"
 	HeaderContentEncoding = "Content-Encoding"
 	HeaderAcceptEncoding  = "Accept-Encoding"
 	HeaderAuthorization   = "Authorization"
+	HeaderWWWAuthenticate = "WWW-Authenticate"
 	HeaderUserAgent       = "User-Agent"
 	HeaderHashSHA256      = "HashSHA256"
+	HeaderRetryAfter      = "Retry-After"
+	HeaderRequestID       = "X-Request-ID"
+	HeaderAllow           = "Allow"
+
+	HeaderSignatureTimestamp = "X-Signature-Timestamp"
+	HeaderSignatureNonce     = "X-Signature-Nonce"
+	HeaderSignatureEd25519   = "X-Signature-Ed25519"
+	HeaderAgentID            = "X-Agent-ID"
+	HeaderEncryptionKey      = "X-Encryption-Key"
 )
 
 // HTTP header values.
 const (
 	// Content-Type: https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Type
-	ContentTypePlain = "text/plain"
-	ContentTypeHTML  = "text/html"
-	ContentTypeXML   = "application/xml"
-	ContentTypeJSON  = "application/json"
+	ContentTypePlain       = "text/plain"
+	ContentTypeHTML        = "text/html"
+	ContentTypeXML         = "application/xml"
+	ContentTypeJSON        = "application/json"
+	ContentTypeEventStream = "text/event-stream"
 
 	// Content-Encoding: https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Encoding
 	ContentEncodingGzip     = "gzip"
@@ -34,4 +46,7 @@ const (
 	AcceptEncodingZstd     = "zstd"
 	AcceptEncodingIdentity = "identity"
 	AcceptEncodingAll      = "*"
+
+	// Authorization: https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Authorization
+	AuthSchemeBearer = "Bearer"
 )
@@ -1,38 +1,203 @@
 // Package logging предназначен для управления логгированием.
+//
+// Логгер настраивается опциями: уровень, формат (json или console), вывод (stderr, stdout или файл
+// с ротацией по размеру) и сэмплирование повторяющихся сообщений. Без опций логгер пишет в stderr
+// в формате JSON с уровнем info, как zap.NewProduction.
 package logging
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+// Форматы логов.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Выводы логов, отличные от пути до файла.
+const (
+	OutputStderr = "stderr"
+	OutputStdout = "stdout"
+)
+
+// Значения параметров логгера по умолчанию.
+const (
+	DefaultLevel              = "info"
+	DefaultFormat             = FormatJSON
+	DefaultOutput             = OutputStderr
+	DefaultMaxSize            = 100
+	DefaultMaxBackups         = 5
+	DefaultSamplingInitial    = 100
+	DefaultSamplingThereafter = 100
+)
+
+// options параметры логгера.
+type options struct {
+	level              string
+	format             string
+	output             string
+	maxSize            int64
+	maxBackups         int
+	samplingInitial    int
+	samplingThereafter int
+}
+
+// Option задает параметр логгера.
+type Option func(o *options)
+
+// WithLevel задает минимальный уровень сообщений: debug, info, warn или error.
+func WithLevel(level string) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithFormat задает формат логов: json или console.
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithOutput задает вывод логов: stderr, stdout или путь до файла.
+func WithOutput(output string) Option {
+	return func(o *options) {
+		o.output = output
+	}
+}
+
+// WithRotation задает ротацию файла логов: при превышении maxSizeMB мегабайт файл переименовывается,
+// хранится не более maxBackups старых файлов. Нулевой maxSizeMB отключает ротацию.
+func WithRotation(maxSizeMB, maxBackups uint64) Option {
+	return func(o *options) {
+		o.maxSize = int64(maxSizeMB) << 20
+		o.maxBackups = int(maxBackups)
+	}
+}
+
+// WithSampling задает сэмплирование: из одинаковых сообщений за секунду выводятся первые initial,
+// а затем каждое thereafter-е. Нулевой initial отключает сэмплирование.
+func WithSampling(initial, thereafter uint64) Option {
+	return func(o *options) {
+		o.samplingInitial = int(initial)
+		o.samplingThereafter = int(thereafter)
+	}
+}
+
+// ParseLevel проверяет название уровня логгирования и возвращает уровень zap.
+func ParseLevel(level string) (zapcore.Level, error) {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(strings.ToLower(level))); err != nil {
+		return l, fmt.Errorf("unknown log level: %q", level)
+	}
+	return l, nil
+}
+
+// IsFormat проверяет, что format - поддерживаемый формат логов.
+func IsFormat(format string) bool {
+	return format == FormatJSON || format == FormatConsole
+}
+
 // Logger представляет собой логгер, который использует библиотеку zap.
 type Logger struct {
-	sugar *zap.SugaredLogger
+	sugar  *zap.SugaredLogger
+	closer io.Closer
 }
 
-// NewLogger создает новый логгер.
-func NewLogger() (*Logger, error) {
-	logger, err := zap.NewProduction()
+// NewLogger создает новый логгер с параметрами opts.
+func NewLogger(opts ...Option) (*Logger, error) {
+	o := options{
+		level:              DefaultLevel,
+		format:             DefaultFormat,
+		output:             DefaultOutput,
+		maxSize:            DefaultMaxSize << 20,
+		maxBackups:         DefaultMaxBackups,
+		samplingInitial:    DefaultSamplingInitial,
+		samplingThereafter: DefaultSamplingThereafter,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	level, err := ParseLevel(o.level)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder, err := newEncoder(o.format)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		_ = logger.Sync()
-	}()
 
-	sugar := logger.Sugar()
+	var (
+		sink   zapcore.WriteSyncer
+		closer io.Closer
+	)
+	switch o.output {
+	case "", OutputStderr:
+		sink = zapcore.Lock(os.Stderr)
+	case OutputStdout:
+		sink = zapcore.Lock(os.Stdout)
+	default:
+		file, err := openRotatingFile(o.output, o.maxSize, o.maxBackups)
+		if err != nil {
+			return nil, err
+		}
+		sink, closer = file, file
+	}
+
+	core := zapcore.NewCore(encoder, sink, level)
+	if o.samplingInitial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, o.samplingInitial, o.samplingThereafter)
+	}
+
+	// Пропуск одного кадра указывает в caller место вызова метода Logger, а не сам метод.
+	logger := zap.New(core,
+		zap.AddCaller(),
+		zap.AddCallerSkip(1),
+		zap.AddStacktrace(zapcore.ErrorLevel),
+		zap.ErrorOutput(zapcore.Lock(os.Stderr)),
+	)
 
-	return &Logger{sugar: sugar}, nil
+	return &Logger{sugar: logger.Sugar(), closer: closer}, nil
 }
 
-// Close закрывает логгер.
+// newEncoder создает кодировщик сообщений в формате format.
+func newEncoder(format string) (zapcore.Encoder, error) {
+	switch format {
+	case FormatJSON:
+		return zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), nil
+	case FormatConsole:
+		cfg := zap.NewProductionEncoderConfig()
+		cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+		cfg.EncodeLevel = zapcore.CapitalLevelEncoder
+		return zapcore.NewConsoleEncoder(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown log format: %q", format)
+	}
+}
+
+// Close сбрасывает буферы логгера и закрывает файл логов.
 func (l *Logger) Close() {
 	err := l.sugar.Sync()
+	if l.closer == nil {
+		// stderr и stdout, подключенные к терминалу или каналу, не поддерживают синхронизацию.
+		return
+	}
 	if err != nil {
 		fmt.Println("logger sync error:", err)
 	}
+	if err := l.closer.Close(); err != nil {
+		fmt.Println("logger close error:", err)
+	}
 }
 
 func (l *Logger) Debug(msg string, args ...interface{}) {
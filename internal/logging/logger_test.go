@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLoggerOptions(t *testing.T) {
+	_, err := NewLogger(WithLevel("verbose"))
+	assert.Error(t, err)
+
+	_, err = NewLogger(WithFormat("xml"))
+	assert.Error(t, err)
+
+	log, err := NewLogger(WithLevel("WARN"), WithFormat(FormatConsole), WithOutput(OutputStdout), WithSampling(0, 0))
+	require.NoError(t, err)
+	log.Close()
+}
+
+func TestFileOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "server.log")
+
+	log, err := NewLogger(WithLevel("warn"), WithOutput(path))
+	require.NoError(t, err)
+	log.Info("skipped")
+	log.Warnf("disk usage %d%%", 91)
+	log.Close()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "disk usage 91%", entry["msg"])
+	// caller указывает на место вызова, а не на пакет logging.
+	assert.Contains(t, entry["caller"], "logging/logger_test.go")
+}
+
+func TestSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sampled.log")
+
+	log, err := NewLogger(WithOutput(path), WithSampling(2, 5))
+	require.NoError(t, err)
+	for range 11 {
+		log.Info("repeated")
+	}
+	log.Close()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// Первые 2 сообщения, затем каждое 5-е из оставшихся: 7-е.
+	assert.Equal(t, 3, strings.Count(string(data), "repeated"))
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+
+	f, err := openRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+	for _, s := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		_, err := f.Write([]byte(s))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	read := func(name string) string {
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "dddddddd\n", read(path))
+	assert.Equal(t, "cccccccc\n", read(path+".1"))
+	assert.Equal(t, "bbbbbbbb\n", read(path+".2"))
+	assert.NoFileExists(t, path+".3")
+
+	// Дозапись в существующий файл учитывает его размер.
+	f, err = openRotatingFile(path, 10, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("eeeeeeee\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, "eeeeeeee\n", read(path))
+	assert.Equal(t, "cccccccc\n", read(path+".1"))
+}
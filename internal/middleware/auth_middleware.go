@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/auth"
+	"github.com/gitslim/monit/internal/errs"
+	"github.com/gitslim/monit/internal/httpconst"
+	"github.com/gitslim/monit/internal/logging"
+)
+
+// AuthMiddleware проверяет токен из заголовка Authorization: Bearer и сохраняет его в контексте запроса.
+// Запросы без заголовка пропускаются анонимными, права на роуты проверяет RequireScope.
+func AuthMiddleware(log *logging.Logger, store auth.TokenStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(httpconst.HeaderAuthorization)
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, httpconst.AuthSchemeBearer+" ")
+		if !ok || token == "" {
+			abortUnauthorized(c)
+			return
+		}
+
+		t, err := auth.Authenticate(c.Request.Context(), store, token)
+		if err != nil {
+			if !errors.Is(err, errs.ErrUnauthorized) {
+				log.Errorf("Token lookup failed: %v", err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			log.Debug("Rejected token", "remote_addr", c.ClientIP())
+			abortUnauthorized(c)
+			return
+		}
+
+		c.Set(auth.TokenKey, t)
+		c.Set(auth.AgentKey, t.Agent)
+		c.Next()
+	}
+}
+
+// RequireScope пропускает только запросы с токеном, имеющим право scope.
+func RequireScope(scope auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, ok := c.Get(auth.TokenKey)
+		if !ok {
+			abortUnauthorized(c)
+			return
+		}
+		if !v.(*auth.Token).Has(scope) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// abortUnauthorized прерывает запрос с кодом 401 и указанием схемы аутентификации.
+func abortUnauthorized(c *gin.Context) {
+	c.Header(httpconst.HeaderWWWAuthenticate, httpconst.AuthSchemeBearer)
+	c.AbortWithStatus(http.StatusUnauthorized)
+}
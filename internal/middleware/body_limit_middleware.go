@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimitMiddleware ограничивает размер тела запроса limit байтами, нулевой limit снимает ограничение.
+//
+// Запросы с заголовком Content-Length больше limit отклоняются сразу с кодом 413,
+// в остальных случаях чтение тела сверх limit завершается ошибкой *http.MaxBytesError,
+// которую мидлвари и обработчики, читающие тело, превращают в ответ 413.
+// Подключенная после распаковки, мидлварь ограничивает размер распакованного тела.
+func BodyLimitMiddleware(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// isBodyTooLarge проверяет, что ошибка чтения тела запроса вызвана превышением допустимого размера.
+func isBodyTooLarge(err error) bool {
+	var mbe *http.MaxBytesError
+	return errors.As(err, &mbe)
+}
+
+// abortReadError прерывает запрос с ошибкой чтения тела: 413 при превышении размера, иначе status.
+func abortReadError(c *gin.Context, err error, status int) {
+	if isBodyTooLarge(err) {
+		status = http.StatusRequestEntityTooLarge
+	}
+	_ = c.AbortWithError(status, err)
+}
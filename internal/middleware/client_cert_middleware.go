@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/auth"
+	"github.com/gitslim/monit/internal/logging"
+)
+
+// ClientCertMiddleware пропускает только запросы с проверенным сертификатом клиента (mTLS).
+// Сертификат проверяется на уровне TLS, поэтому middleware лишь требует его наличия
+// и сохраняет имя (CommonName) сертификата как имя агента. Если агент уже определен
+// по токену (AuthMiddleware) и не совпадает с именем сертификата, запрос отклоняется с кодом 403.
+func ClientCertMiddleware(log *logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := c.Request.TLS
+		if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+			log.Debug("Client certificate required", "remote_addr", c.ClientIP())
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		name := state.VerifiedChains[0][0].Subject.CommonName
+		if agent, ok := c.Get(auth.AgentKey); ok && agent != name {
+			log.Debug("Client certificate does not match token agent", "remote_addr", c.ClientIP())
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		c.Set(auth.AgentKey, name)
+		c.Next()
+	}
+}
@@ -0,0 +1,67 @@
+package middleware_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/auth"
+	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClientCertMiddlewareAgent тестирует определение агента по сертификату клиента
+// и отклонение запросов, агент которых по токену не совпадает с сертификатом.
+func TestClientCertMiddlewareAgent(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		tokenAgent string // пустая строка - запрос без токена
+		noCert     bool
+		wantStatus int
+		wantAgent  string
+	}{
+		{name: "cert only", wantStatus: http.StatusOK, wantAgent: "agent-1"},
+		{name: "matching token", tokenAgent: "agent-1", wantStatus: http.StatusOK, wantAgent: "agent-1"},
+		{name: "mismatching token", tokenAgent: "agent-2", wantStatus: http.StatusForbidden},
+		{name: "no cert", noCert: true, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tt.tokenAgent != "" {
+					c.Set(auth.AgentKey, tt.tokenAgent)
+				}
+				c.Next()
+			})
+			r.Use(middleware.ClientCertMiddleware(log))
+			var gotAgent string
+			r.POST("/update/", func(c *gin.Context) {
+				gotAgent = c.GetString(auth.AgentKey)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/update/", nil)
+			if !tt.noCert {
+				cert := &x509.Certificate{Subject: pkix.Name{CommonName: "agent-1"}}
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantAgent, gotAgent)
+		})
+	}
+}
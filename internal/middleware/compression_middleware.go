@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/compression"
+	"github.com/gitslim/monit/internal/httpconst"
+)
+
+// isContentTypeCompressable возвращает true, если ответ с типом содержимого ct имеет смысл сжимать.
+// Поток событий (SSE) не сжимается, так как сжатие буферизует события.
+func isContentTypeCompressable(ct string) bool {
+	ct, _, _ = strings.Cut(ct, ";")
+	ct = strings.TrimSpace(ct)
+	switch {
+	case ct == httpconst.ContentTypeEventStream:
+		return false
+	case strings.HasPrefix(ct, "text/"):
+		return true
+	}
+	switch ct {
+	case httpconst.ContentTypeJSON, httpconst.ContentTypeXML, "application/javascript", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// compressResponseWriter сжимает ответ выбранным алгоритмом, если тип его содержимого сжимаемый.
+// Решение принимается при первой записи, когда обработчик уже задал заголовок Content-Type.
+type compressResponseWriter struct {
+	gin.ResponseWriter
+	codec   *compression.Codec
+	writer  *compression.Writer
+	decided bool
+}
+
+// decide включает сжатие, если тип содержимого ответа сжимаемый.
+func (w *compressResponseWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	h := w.ResponseWriter.Header()
+	if h.Get(httpconst.HeaderContentEncoding) != "" || !isContentTypeCompressable(h.Get(httpconst.HeaderContentType)) {
+		return
+	}
+	h.Set(httpconst.HeaderContentEncoding, w.codec.Name())
+	// Длина сжатого ответа заранее неизвестна.
+	h.Del("Content-Length")
+	w.writer = w.codec.NewWriter(w.ResponseWriter)
+}
+
+// Write реализует интерфейс io.Writer.
+func (w *compressResponseWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if w.writer == nil {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.writer.Write(data)
+}
+
+// WriteString реализует интерфейс io.StringWriter.
+func (w *compressResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush отправляет клиенту все сжатые к этому моменту данные.
+func (w *compressResponseWriter) Flush() {
+	if w.writer != nil {
+		_ = w.writer.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// close завершает поток сжатых данных.
+func (w *compressResponseWriter) close() {
+	if w.writer != nil {
+		_ = w.writer.Close()
+	}
+}
+
+// CompressionMiddleware распаковывает тело запроса и сжимает ответ алгоритмами из реестра compression.
+//
+// Тело запроса распаковывается по заголовку Content-Encoding, запросы с неподдерживаемым алгоритмом
+// отклоняются с кодом 415. Алгоритм сжатия ответа выбирается по заголовку Accept-Encoding с учетом q-значений.
+func CompressionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if encoding := c.GetHeader(httpconst.HeaderContentEncoding); !compression.IsIdentity(encoding) {
+			codec, ok := compression.Lookup(encoding)
+			if !ok {
+				c.Header(httpconst.HeaderAcceptEncoding, strings.Join(compression.Names(), ", "))
+				c.AbortWithStatus(http.StatusUnsupportedMediaType)
+				return
+			}
+			body, err := codec.NewReader(c.Request.Body)
+			if err != nil {
+				abortReadError(c, err, http.StatusBadRequest)
+				return
+			}
+			defer func() { _ = body.Close() }()
+			c.Request.Body = body
+		}
+
+		c.Writer.Header().Add("Vary", httpconst.HeaderAcceptEncoding)
+		codec, ok := compression.Negotiate(c.GetHeader(httpconst.HeaderAcceptEncoding))
+		if !ok {
+			c.Next()
+			return
+		}
+
+		w := &compressResponseWriter{ResponseWriter: c.Writer, codec: codec}
+		c.Writer = w
+		defer w.close()
+		c.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package middleware_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/compression"
+	"github.com/gitslim/monit/internal/httpconst"
+	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/middleware"
+	"github.com/gitslim/monit/internal/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompressionWithSignatureConcurrent проверяет, что распаковывающие reader'ы не разделяются
+// между одновременными запросами, когда тело закрывают и SignatureMiddleware, и CompressionMiddleware.
+func TestCompressionWithSignatureConcurrent(t *testing.T) {
+	const key = "secret"
+
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.CompressionMiddleware())
+	r.Use(middleware.BodyLimitMiddleware(1 << 20))
+	r.Use(middleware.SignatureMiddleware(log, middleware.SignatureConfig{Key: key, Window: time.Minute}))
+	r.POST("/echo", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.Data(http.StatusOK, "text/plain", body)
+	})
+
+	codec, ok := compression.Lookup(httpconst.ContentEncodingGzip)
+	require.True(t, ok)
+
+	// newRequest создает сжатый gzip запрос с телом body, подписанный ключом HMAC.
+	newRequest := func(body []byte) *http.Request {
+		var buf bytes.Buffer
+		w := codec.NewWriter(&buf)
+		_, err := w.Write(body)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		nonce, err := security.NewNonce()
+		require.NoError(t, err)
+		signed := security.SignedRequest{Timestamp: time.Now(), Nonce: nonce, Method: http.MethodPost, Path: "/echo", Body: body}
+
+		req := httptest.NewRequest(http.MethodPost, "/echo", &buf)
+		req.Header.Set(httpconst.HeaderContentEncoding, httpconst.ContentEncodingGzip)
+		req.Header.Set(httpconst.HeaderHashSHA256, signed.Sign(key))
+		req.Header.Set(httpconst.HeaderSignatureTimestamp, strconv.FormatInt(signed.Timestamp.Unix(), 10))
+		req.Header.Set(httpconst.HeaderSignatureNonce, nonce)
+		return req
+	}
+
+	bodies := make([][]byte, 200)
+	reqs := make([]*http.Request, len(bodies))
+	for i := range bodies {
+		bodies[i] = bytes.Repeat([]byte(fmt.Sprintf("request-%d;", i)), 5000)
+		reqs[i] = newRequest(bodies[i])
+	}
+
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := bodies[i]
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, body, rec.Body.Bytes())
+		}()
+	}
+	wg.Wait()
+}
@@ -2,32 +2,70 @@ package middleware
 
 import (
 	"bytes"
+	"crypto/rsa"
+	"errors"
 	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/httpconst"
 	"github.com/gitslim/monit/internal/security"
 )
 
+// decryptionKey закрытый ключ расшифровки с отпечатком его открытого ключа.
+type decryptionKey struct {
+	fingerprint string
+	key         *rsa.PrivateKey
+}
+
 // DecryptMiddleware - мидлварь для расшифровки тела запроса.
-func DecryptMiddleware(privateKeyPath string) (gin.HandlerFunc, error) {
-	privateKey, err := security.ReadRSAPrivateKeyFromFile(privateKeyPath)
-	if err != nil {
-		return nil, err
+//
+// Сервер может принимать несколько ключей одновременно, чтобы ключи можно было менять без простоя:
+// если агент передал отпечаток открытого ключа в заголовке X-Encryption-Key, используется соответствующий ключ,
+// иначе ключи перебираются по порядку до успешной расшифровки.
+//
+// Запросы безопасных методов (GET, HEAD, OPTIONS) и запросы с пустым телом пропускаются без расшифровки,
+// чтобы проверки работоспособности, панель метрик и чтение метрик работали при включенном шифровании.
+func DecryptMiddleware(privateKeyPaths ...string) (gin.HandlerFunc, error) {
+	if len(privateKeyPaths) == 0 {
+		return nil, errors.New("no decryption keys")
+	}
+
+	keys := make([]decryptionKey, 0, len(privateKeyPaths))
+	for _, path := range privateKeyPaths {
+		privateKey, err := security.ReadRSAPrivateKeyFromFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fp, err := security.Fingerprint(&privateKey.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, decryptionKey{fingerprint: fp, key: privateKey})
 	}
 
 	// Возвращаем функцию-мидлварь.
 	return func(c *gin.Context) {
+		if isSafeMethod(c.Request.Method) || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
 		// Читаем зашифрованное тело запроса
 		encryptedBody, err := io.ReadAll(c.Request.Body)
 		if err != nil {
-			_ = c.AbortWithError(http.StatusBadRequest, err)
+			abortReadError(c, err, http.StatusBadRequest)
+			return
+		}
+		_ = c.Request.Body.Close()
+		if len(encryptedBody) == 0 {
+			c.Request.Body = http.NoBody
+			c.Next()
 			return
 		}
-		defer func() { _ = c.Request.Body.Close() }()
 
 		// Расшифровываем тело запроса
-		decryptedData, err := security.DecryptRSA(privateKey, encryptedBody)
+		decryptedData, err := decrypt(keys, c.GetHeader(httpconst.HeaderEncryptionKey), encryptedBody)
 		if err != nil {
 			_ = c.AbortWithError(http.StatusBadRequest, err)
 			return
@@ -37,3 +75,25 @@ func DecryptMiddleware(privateKeyPath string) (gin.HandlerFunc, error) {
 		c.Next()
 	}, nil
 }
+
+// decrypt расшифровывает данные ключом с отпечатком fingerprint или, если отпечаток не задан, перебором ключей.
+func decrypt(keys []decryptionKey, fingerprint string, data []byte) ([]byte, error) {
+	if fingerprint != "" {
+		for _, k := range keys {
+			if k.fingerprint == fingerprint {
+				return security.DecryptRSA(k.key, data)
+			}
+		}
+		return nil, errors.New("unknown encryption key")
+	}
+
+	var err error
+	for _, k := range keys {
+		var decrypted []byte
+		decrypted, err = security.DecryptRSA(k.key, data)
+		if err == nil {
+			return decrypted, nil
+		}
+	}
+	return nil, err
+}
@@ -1,2 +1,3 @@
-// Package middleware содержит middleware для логгирования, gzip-сжатия и подписывания сигнатурой http-запросов.
+// Package middleware содержит middleware для логгирования, gzip-сжатия, подписывания сигнатурой,
+// ограничения размера и частоты http-запросов.
 package middleware
@@ -4,29 +4,45 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/auth"
+	"github.com/gitslim/monit/internal/httpconst"
 	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/requestid"
 )
 
-// LoggerMiddleware логгирует запросы и ответы, вычисляет время выполнения запроса и размер ответа.
+// LoggerMiddleware присваивает запросу идентификатор и записывает в лог одну запись о запросе и ответе.
+//
+// Идентификатор берется из заголовка X-Request-ID, если клиент передал корректное значение, иначе генерируется.
+// Он сохраняется в контексте запроса и возвращается клиенту в том же заголовке.
+// Запись лога содержит идентификатор, адрес клиента, агента, код и размер ответа, время выполнения
+// и количество принятых метрик, поэтому мидлварь подключается первой.
 func LoggerMiddleware(log *logging.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 
-		c.Next()
+		id := c.GetHeader(httpconst.HeaderRequestID)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		c.Set(httpconst.ContextKeyRequestID, id)
+		c.Header(httpconst.HeaderRequestID, id)
 
-		latency := time.Since(start)
+		c.Next()
 
-		// Логгируем запрос.
-		log.Info("req",
-			"URI", c.Request.RequestURI,
+		fields := []interface{}{
+			"request_id", id,
 			"method", c.Request.Method,
-			"latency", latency)
-
-		status := c.Writer.Status()
-		size := c.Writer.Size()
-		// Логгируем ответ.
-		log.Info("res",
-			"status", status,
-			"size", size)
+			"uri", c.Request.RequestURI,
+			"client_ip", c.ClientIP(),
+			"agent", c.GetString(auth.AgentKey),
+			"status", c.Writer.Status(),
+			"size", c.Writer.Size(),
+			"latency", time.Since(start),
+			"metrics", c.GetInt(httpconst.ContextKeyMetricsIngested),
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, "errors", c.Errors.String())
+		}
+		log.Info("access", fields...)
 	}
 }
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/selfmetrics"
+)
+
+// unmatchedRoute метка маршрута для запросов, не совпавших ни с одним роутом.
+// Используется вместо пути запроса, чтобы случайные пути не порождали новые метрики.
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware учитывает в метриках сервера число запросов по роутам и кодам ответа
+// и длительность их обработки по роутам.
+func MetricsMiddleware(reg *selfmetrics.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+		reg.Add("http_requests_total", 1, "method", method, "route", route, "status", strconv.Itoa(c.Writer.Status()))
+		reg.ObserveDuration("http_request_duration_seconds", time.Since(start), "method", method, "route", route)
+	}
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/auth"
+	"github.com/gitslim/monit/internal/httpconst"
+	"github.com/gitslim/monit/internal/logging"
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterIdleTTL время, после которого ограничитель неактивного клиента удаляется.
+const rateLimiterIdleTTL = 10 * time.Minute
+
+// clientLimiter ограничитель частоты запросов клиента.
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter хранит ограничители частоты запросов по ключам клиентов.
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+// newRateLimiter создает хранилище ограничителей с заданными частотой и размером всплеска.
+func newRateLimiter(limit rate.Limit, burst int) *rateLimiter {
+	return &rateLimiter{
+		limit:     limit,
+		burst:     burst,
+		clients:   make(map[string]*clientLimiter),
+		lastSweep: time.Now(),
+	}
+}
+
+// reserve резервирует запрос клиента key.
+// Возвращает true, если запрос разрешен, иначе время, через которое его можно повторить.
+func (l *rateLimiter) reserve(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	cl, ok := l.clients[key]
+	if !ok {
+		cl = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = cl
+	}
+	cl.lastSeen = now
+
+	r := cl.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return rateLimiterIdleTTL, false
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		// Отклоненный запрос не расходует токены клиента.
+		r.CancelAt(now)
+		return delay, false
+	}
+	return 0, true
+}
+
+// sweep удаляет ограничители клиентов, неактивных дольше rateLimiterIdleTTL.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimiterIdleTTL {
+		return
+	}
+	for key, cl := range l.clients {
+		if now.Sub(cl.lastSeen) > rateLimiterIdleTTL {
+			delete(l.clients, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// RateLimitMiddleware ограничивает частоту запросов каждого клиента алгоритмом token bucket:
+// в среднем не более limit запросов в секунду со всплесками до burst запросов.
+//
+// Клиент определяется по агенту, аутентифицированному токеном, а для анонимных запросов - по IP-адресу,
+// поэтому мидлварь подключается после AuthMiddleware. Запросы сверх лимита отклоняются с кодом 429
+// и заголовком Retry-After, содержащим число секунд до следующей допустимой попытки.
+func RateLimitMiddleware(log *logging.Logger, limit float64, burst int) gin.HandlerFunc {
+	limiter := newRateLimiter(rate.Limit(limit), burst)
+
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if agent := c.GetString(auth.AgentKey); agent != "" {
+			key = "agent:" + agent
+		}
+
+		delay, ok := limiter.reserve(key, time.Now())
+		if !ok {
+			retryAfter := int(math.Ceil(delay.Seconds()))
+			log.Debug("Rate limit exceeded", "client", key, "retry_after", retryAfter)
+			c.Header(httpconst.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+		c.Next()
+	}
+}
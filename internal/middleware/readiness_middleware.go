@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/health"
+	"github.com/gitslim/monit/internal/httpconst"
+)
+
+// startingRetryAfter время, через которое клиенту предлагается повторить запрос к запускающемуся серверу.
+const startingRetryAfter = time.Second
+
+// ReadinessMiddleware отклоняет запросы с кодом 503 и заголовком Retry-After, пока сервер запускается.
+// Подключается к роутам записи: метрики, записанные во время восстановления данных из резервной копии,
+// были бы перезаписаны восстановленными значениями.
+func ReadinessMiddleware(h *health.Checker) gin.HandlerFunc {
+	retryAfter := strconv.Itoa(int(startingRetryAfter.Seconds()))
+
+	return func(c *gin.Context) {
+		if h.Phase() == health.PhaseStarting {
+			c.Header(httpconst.HeaderRetryAfter, retryAfter)
+			c.AbortWithStatus(http.StatusServiceUnavailable)
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,16 +1,27 @@
 package middleware
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"bytes"
+	"crypto/ed25519"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/auth"
 	"github.com/gitslim/monit/internal/httpconst"
 	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/security"
 )
 
+// SignatureConfig содержит параметры проверки подписи запросов.
+type SignatureConfig struct {
+	Key        string                       // общий ключ подписи HMAC-SHA256, пустой - режим HMAC отключен
+	PublicKeys map[string]ed25519.PublicKey // открытые ключи Ed25519 по идентификатору агента
+	Window     time.Duration                // допустимое отклонение времени подписи от времени сервера
+}
+
 // signatureResponseWriter представляет собой обертку над gin.ResponseWriter, которая добавляет хэш SHA-256 в заголовок ответа.
 type signatureResponseWriter struct {
 	gin.ResponseWriter
@@ -19,44 +30,127 @@ type signatureResponseWriter struct {
 
 // Write реализует интерфейс gin.ResponseWriter.
 func (w *signatureResponseWriter) Write(data []byte) (int, error) {
-	respHash, err := makeHash(&data, w.key)
-	if err != nil {
-		return 0, err
-	}
-	w.ResponseWriter.Header().Set(httpconst.HeaderHashSHA256, respHash)
+	w.ResponseWriter.Header().Set(httpconst.HeaderHashSHA256, security.HashSHA256(data, w.key))
 	return w.ResponseWriter.Write(data)
 }
 
-// SignatureMiddleware добавляет хэш SHA-256 в заголовок ответа, если он указан в запросе.
-func SignatureMiddleware(log *logging.Logger, key string) gin.HandlerFunc {
+// SignatureMiddleware проверяет подпись запросов и добавляет хэш SHA-256 в заголовок ответа, если задан ключ HMAC.
+//
+// Поддерживаются два режима подписи:
+//   - HMAC-SHA256 общим ключом в заголовке HashSHA256;
+//   - Ed25519 закрытым ключом агента в заголовке X-Signature-Ed25519 с идентификатором агента в X-Agent-ID.
+//
+// Подпись проверяется, если передана в запросе; обязательность подписи для роутов записи
+// обеспечивает RequireSignature. Подпись покрывает время, nonce, метод, путь
+// и тело запроса после распаковки и расшифровки. Время подписи должно отличаться от времени сервера
+// не более чем на Window, а nonce не должен повторяться, иначе запрос отклоняется.
+// Для запросов с подписью Ed25519 идентификатор агента сохраняется в контексте как имя агента.
+func SignatureMiddleware(log *logging.Logger, cfg SignatureConfig) gin.HandlerFunc {
+	nonces := security.NewNonceCache(2 * cfg.Window)
+
 	return func(c *gin.Context) {
-		// Проверяем наличие хэша SHA-256 в заголовке запроса.
-		headerHash := c.GetHeader(httpconst.HeaderHashSHA256)
-		if headerHash != "" {
-			var buf []byte
-			_, err := c.Request.Body.Read(buf)
-			if err != nil {
-				c.AbortWithStatus(http.StatusInternalServerError)
+		hmacSig := c.GetHeader(httpconst.HeaderHashSHA256)
+		edSig := c.GetHeader(httpconst.HeaderSignatureEd25519)
+		path := c.Request.URL.Path
+
+		if hmacSig == "" && edSig == "" {
+			signResponse(c, cfg.Key)
+			c.Next()
+			return
+		}
+
+		ts, err := strconv.ParseInt(c.GetHeader(httpconst.HeaderSignatureTimestamp), 10, 64)
+		nonce := c.GetHeader(httpconst.HeaderSignatureNonce)
+		if err != nil || nonce == "" {
+			log.Debug("Signature timestamp or nonce missing", "path", path)
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		signedAt := time.Unix(ts, 0)
+		if signedAt.Before(now.Add(-cfg.Window)) || signedAt.After(now.Add(cfg.Window)) {
+			log.Debug("Stale signature rejected", "path", path, "signed_at", signedAt)
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortReadError(c, err, http.StatusInternalServerError)
+			return
+		}
+		_ = c.Request.Body.Close()
+		// Восстанавливаем тело запроса для обработчиков.
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		req := security.SignedRequest{
+			Timestamp: signedAt,
+			Nonce:     nonce,
+			Method:    c.Request.Method,
+			Path:      path,
+			Body:      body,
+		}
+
+		var agent string
+		if edSig != "" {
+			agent = c.GetHeader(httpconst.HeaderAgentID)
+			pub, ok := cfg.PublicKeys[agent]
+			if !ok || !req.VerifyEd25519(pub, edSig) {
+				log.Debug("Invalid Ed25519 signature rejected", "path", path, "agent", agent)
+				c.AbortWithStatus(http.StatusBadRequest)
 				return
 			}
+		} else if cfg.Key == "" || !req.Verify(cfg.Key, hmacSig) {
+			log.Debug("Invalid signature rejected", "path", path)
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
 
-			reqHash, err := makeHash(&buf, key)
-			if err != nil || reqHash != headerHash {
-				c.AbortWithStatus(http.StatusBadRequest)
+		// Nonce запоминается только для запросов с верной подписью.
+		if !nonces.Use(nonce, now) {
+			log.Debug("Replayed request rejected", "path", path)
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		if agent != "" {
+			// Агент, подписавший запрос, должен совпадать с владельцем токена или сертификата.
+			if prev := c.GetString(auth.AgentKey); prev != "" && prev != agent {
+				log.Debug("Signing agent mismatch", "path", path, "agent", agent, "authenticated", prev)
+				c.AbortWithStatus(http.StatusForbidden)
 				return
 			}
+			c.Set(auth.AgentKey, agent)
 		}
 
-		rrw := &signatureResponseWriter{ResponseWriter: c.Writer, key: key}
-		c.Writer = rrw
+		c.Set(httpconst.ContextKeySigned, true)
+		signResponse(c, cfg.Key)
+		c.Next()
+	}
+}
 
+// RequireSignature пропускает только запросы, подпись которых проверена SignatureMiddleware.
+// Запросы без подписи отклоняются с кодом 400.
+func RequireSignature(log *logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool(httpconst.ContextKeySigned) {
+			log.Debug("Unsigned request rejected", "path", c.Request.URL.Path)
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
 		c.Next()
 	}
 }
 
-// makeHash вычисляет хэш для данных по алгоритму SHA256.
-func makeHash(buf *[]byte, key string) (string, error) {
-	h := hmac.New(sha256.New, []byte(key))
-	h.Write(*buf)
-	return hex.EncodeToString(h.Sum(nil)), nil
+// signResponse включает подпись ответа HMAC-SHA256, если задан ключ.
+func signResponse(c *gin.Context, key string) {
+	if key != "" {
+		c.Writer = &signatureResponseWriter{ResponseWriter: c.Writer, key: key}
+	}
+}
+
+// isSafeMethod проверяет, что метод запроса не изменяет данные.
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
 }
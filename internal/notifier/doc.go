@@ -0,0 +1,5 @@
+// Package notifier доставляет оповещения и события сервера на webhook-получателей.
+//
+// События группируются и отправляются JSON-запросами POST с ограничением частоты для каждого получателя.
+// Тело запроса подписывается HMAC-SHA256 в заголовке HashSHA256, если для получателя задан ключ.
+package notifier
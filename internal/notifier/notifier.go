@@ -0,0 +1,225 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gitslim/monit/internal/alerting"
+	"github.com/gitslim/monit/internal/httpconst"
+	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/retry"
+	"github.com/gitslim/monit/internal/security"
+	"golang.org/x/time/rate"
+)
+
+// Виды событий.
+const (
+	EventAlert = "alert"
+)
+
+// requestTimeout таймаут одного запроса к получателю.
+const requestTimeout = 5 * time.Second
+
+// Event описывает событие для доставки получателям.
+type Event struct {
+	Kind    string          `json:"kind"`              // вид события
+	Time    time.Time       `json:"time"`              // время события
+	Alert   *alerting.Alert `json:"alert,omitempty"`   // оповещение для событий вида alert
+	Message string          `json:"message,omitempty"` // текст события
+}
+
+// Payload описывает тело запроса к получателю.
+type Payload struct {
+	Receiver string  `json:"receiver"` // имя получателя
+	Events   []Event `json:"events"`   // сгруппированные события
+}
+
+// deliveryError ошибка доставки, которую можно повторить.
+type deliveryError struct {
+	err       error
+	retriable bool
+}
+
+// Error реализует интерфейс error.
+func (e *deliveryError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap возвращает исходную ошибку.
+func (e *deliveryError) Unwrap() error {
+	return e.err
+}
+
+// IsRetriable реализует интерфейс retry.IRetriableError.
+func (e *deliveryError) IsRetriable() bool {
+	return e.retriable
+}
+
+// receiverWorker содержит очередь и ограничитель частоты получателя.
+type receiverWorker struct {
+	receiver *Receiver
+	events   chan Event
+	limiter  *rate.Limiter
+}
+
+// Notifier доставляет события webhook-получателям.
+type Notifier struct {
+	log        *logging.Logger
+	client     *http.Client
+	workers    []*receiverWorker
+	maxRetries int
+}
+
+// NewNotifier создает Notifier для заданных получателей.
+func NewNotifier(log *logging.Logger, client *http.Client, receivers []*Receiver) *Notifier {
+	workers := make([]*receiverWorker, 0, len(receivers))
+	for _, r := range receivers {
+		limit := rate.Inf
+		if r.MaxPerMinute > 0 {
+			limit = rate.Every(time.Minute / time.Duration(r.MaxPerMinute))
+		}
+		workers = append(workers, &receiverWorker{
+			receiver: r,
+			events:   make(chan Event, DefaultQueueSize),
+			limiter:  rate.NewLimiter(limit, 1),
+		})
+	}
+
+	return &Notifier{
+		log:        log,
+		client:     client,
+		workers:    workers,
+		maxRetries: 3,
+	}
+}
+
+// Run запускает доставку событий и блокируется до отмены контекста.
+func (n *Notifier) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, w := range n.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.runWorker(ctx, w)
+		}()
+	}
+	wg.Wait()
+	n.log.Debug("Notifier stopped")
+}
+
+// Notify ставит событие в очередь всех получателей.
+// Если очередь получателя переполнена, событие для него отбрасывается.
+func (n *Notifier) Notify(e Event) {
+	for _, w := range n.workers {
+		select {
+		case w.events <- e:
+		default:
+			n.log.Warnf("Notifier queue is full, event dropped for receiver %s", w.receiver.Name)
+		}
+	}
+}
+
+// NotifyAlert отправляет событие об оповещении, перешедшем в состояние firing или resolved.
+func (n *Notifier) NotifyAlert(a alerting.Alert) {
+	if a.State != alerting.StateFiring && a.State != alerting.StateResolved {
+		return
+	}
+	n.Notify(Event{
+		Kind:  EventAlert,
+		Time:  time.Now(),
+		Alert: &a,
+	})
+}
+
+// runWorker группирует события получателя и доставляет их с учетом ограничения частоты.
+func (n *Notifier) runWorker(ctx context.Context, w *receiverWorker) {
+	for {
+		var group []Event
+
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-w.events:
+			group = append(group, e)
+		}
+
+		// Накапливаем события в течение времени группировки.
+		timer := time.NewTimer(w.receiver.groupWait())
+	collect:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case e := <-w.events:
+				group = append(group, e)
+			case <-timer.C:
+				break collect
+			}
+		}
+
+		// Ожидаем разрешения ограничителя частоты.
+		if err := w.limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		// События, поступившие во время ожидания, попадают в ту же группу.
+	drain:
+		for {
+			select {
+			case e := <-w.events:
+				group = append(group, e)
+			default:
+				break drain
+			}
+		}
+
+		if err := n.deliver(ctx, w.receiver, group); err != nil {
+			n.log.Errorf("Webhook delivery to %s failed: %v", w.receiver.Name, err)
+		}
+	}
+}
+
+// deliver отправляет группу событий получателю с повторными попытками.
+func (n *Notifier) deliver(ctx context.Context, r *Receiver, events []Event) error {
+	body, err := json.Marshal(Payload{Receiver: r.Name, Events: events})
+	if err != nil {
+		return err
+	}
+
+	return retry.Retry(func() error {
+		return n.send(ctx, r, body)
+	}, n.maxRetries)
+}
+
+// send выполняет один запрос к получателю.
+func (n *Notifier) send(ctx context.Context, r *Receiver, body []byte) error {
+	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, r.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(httpconst.HeaderContentType, httpconst.ContentTypeJSON)
+	if r.Key != "" {
+		req.Header.Set(httpconst.HeaderHashSHA256, security.HashSHA256(body, r.Key))
+	}
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return &deliveryError{err: fmt.Errorf("failed to send request: %w", err), retriable: ctx.Err() == nil}
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		retriable := res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+		return &deliveryError{err: fmt.Errorf("unexpected status code: %d", res.StatusCode), retriable: retriable}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package notifier_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gitslim/monit/internal/alerting"
+	"github.com/gitslim/monit/internal/httpconst"
+	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/notifier"
+	"github.com/gitslim/monit/internal/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiverMock webhook-получатель для тестов.
+type receiverMock struct {
+	mu       sync.Mutex
+	payloads []notifier.Payload
+	hashes   []string
+	bodies   [][]byte
+	statuses []int // коды ответов по порядку запросов, далее 200
+	calls    int
+}
+
+func (m *receiverMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	status := http.StatusOK
+	if m.calls < len(m.statuses) {
+		status = m.statuses[m.calls]
+	}
+	m.calls++
+
+	if status == http.StatusOK {
+		var p notifier.Payload
+		_ = json.Unmarshal(body, &p)
+		m.payloads = append(m.payloads, p)
+		m.hashes = append(m.hashes, r.Header.Get(httpconst.HeaderHashSHA256))
+		m.bodies = append(m.bodies, body)
+	}
+	w.WriteHeader(status)
+}
+
+func (m *receiverMock) delivered() []notifier.Payload {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]notifier.Payload(nil), m.payloads...)
+}
+
+// startNotifier запускает Notifier и возвращает функцию его остановки.
+func startNotifier(t *testing.T, receivers ...*notifier.Receiver) (*notifier.Notifier, func()) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	n := notifier.NewNotifier(log, &http.Client{}, receivers)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		n.Run(ctx)
+		close(done)
+	}()
+	return n, func() {
+		cancel()
+		<-done
+	}
+}
+
+// TestNotifierGroupingAndSignature тестирует группировку событий и подпись тела запроса.
+func TestNotifierGroupingAndSignature(t *testing.T) {
+	mock := &receiverMock{}
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	n, stop := startNotifier(t, &notifier.Receiver{Name: "ops", URL: srv.URL, Key: "secret", GroupWait: 1})
+	defer stop()
+
+	n.NotifyAlert(alerting.Alert{Rule: "HighCPU", State: alerting.StateFiring})
+	n.NotifyAlert(alerting.Alert{Rule: "LowMemory", State: alerting.StateFiring})
+	n.NotifyAlert(alerting.Alert{Rule: "Pending", State: alerting.StatePending}) // не доставляется
+	n.NotifyAlert(alerting.Alert{Rule: "HighCPU", State: alerting.StateResolved})
+
+	require.Eventually(t, func() bool { return len(mock.delivered()) == 1 }, 3*time.Second, 50*time.Millisecond)
+
+	p := mock.delivered()[0]
+	assert.Equal(t, "ops", p.Receiver)
+	require.Len(t, p.Events, 3)
+	assert.Equal(t, notifier.EventAlert, p.Events[0].Kind)
+	assert.Equal(t, "LowMemory", p.Events[1].Alert.Rule)
+	assert.Equal(t, alerting.StateResolved, p.Events[2].Alert.State)
+
+	mock.mu.Lock()
+	assert.Equal(t, security.HashSHA256(mock.bodies[0], "secret"), mock.hashes[0])
+	mock.mu.Unlock()
+}
+
+// TestNotifierRetry тестирует повторную доставку при ошибке сервера.
+func TestNotifierRetry(t *testing.T) {
+	mock := &receiverMock{statuses: []int{http.StatusServiceUnavailable}}
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	n, stop := startNotifier(t, &notifier.Receiver{Name: "ops", URL: srv.URL})
+	defer stop()
+
+	n.Notify(notifier.Event{Kind: "test", Message: "hello"})
+
+	require.Eventually(t, func() bool { return len(mock.delivered()) == 1 }, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, "hello", mock.delivered()[0].Events[0].Message)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	assert.Equal(t, 2, mock.calls)
+	assert.Empty(t, mock.hashes[0])
+}
+
+// TestNotifierNoRetryOnClientError тестирует отказ от повторов при ошибке клиента.
+func TestNotifierNoRetryOnClientError(t *testing.T) {
+	mock := &receiverMock{statuses: []int{http.StatusBadRequest}}
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	n, stop := startNotifier(t, &notifier.Receiver{Name: "ops", URL: srv.URL})
+
+	n.Notify(notifier.Event{Kind: "test"})
+	time.Sleep(1500 * time.Millisecond)
+	stop()
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	assert.Equal(t, 1, mock.calls)
+}
+
+// TestNotifierRateLimit тестирует ограничение частоты запросов к получателю.
+func TestNotifierRateLimit(t *testing.T) {
+	mock := &receiverMock{}
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	n, stop := startNotifier(t, &notifier.Receiver{Name: "ops", URL: srv.URL, MaxPerMinute: 1})
+	defer stop()
+
+	n.Notify(notifier.Event{Kind: "test", Message: "first"})
+	require.Eventually(t, func() bool { return len(mock.delivered()) == 1 }, 3*time.Second, 50*time.Millisecond)
+
+	// Следующие события ожидают разрешения ограничителя и не доставляются в течение минуты.
+	n.Notify(notifier.Event{Kind: "test", Message: "second"})
+	n.Notify(notifier.Event{Kind: "test", Message: "third"})
+	time.Sleep(500 * time.Millisecond)
+	assert.Len(t, mock.delivered(), 1)
+}
+
+// TestLoadReceivers тестирует загрузку получателей из файла.
+func TestLoadReceivers(t *testing.T) {
+	receivers, err := notifier.LoadReceivers("../../testdata/config/webhooks.json")
+	require.NoError(t, err)
+	require.Len(t, receivers, 1)
+	assert.Equal(t, uint64(6), receivers[0].MaxPerMinute)
+}
@@ -0,0 +1,69 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+// DefaultQueueSize размер очереди событий получателя.
+const DefaultQueueSize = 100
+
+// Receiver описывает webhook-получателя.
+type Receiver struct {
+	Name         string `json:"name"`           // уникальное имя получателя
+	URL          string `json:"url"`            // адрес webhook
+	Key          string `json:"key"`            // ключ подписи тела запроса
+	GroupWait    uint64 `json:"group_wait"`     // время накопления событий в группу (в секундах), 0 - без ожидания
+	MaxPerMinute uint64 `json:"max_per_minute"` // максимальное количество запросов в минуту, 0 - без ограничения
+}
+
+// receiversFile определяет формат файла получателей.
+type receiversFile struct {
+	Receivers []*Receiver `json:"receivers"`
+}
+
+// LoadReceivers загружает получателей из JSON-файла.
+func LoadReceivers(path string) ([]*Receiver, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать файл получателей: %w", err)
+	}
+
+	var f receiversFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("ошибка декодирования JSON: %w", err)
+	}
+
+	names := make(map[string]bool, len(f.Receivers))
+	for _, r := range f.Receivers {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("получатель %q: %w", r.Name, err)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("получатель %q: имя получателя должно быть уникальным", r.Name)
+		}
+		names[r.Name] = true
+	}
+	return f.Receivers, nil
+}
+
+// validate проверяет получателя на корректность.
+func (r *Receiver) validate() error {
+	if r.Name == "" {
+		return errors.New("имя получателя не может быть пустым")
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("некорректный адрес webhook %q", r.URL)
+	}
+	return nil
+}
+
+// groupWait возвращает время накопления событий в группу.
+func (r *Receiver) groupWait() time.Duration {
+	return time.Duration(r.GroupWait) * time.Second
+}
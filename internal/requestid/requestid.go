@@ -0,0 +1,40 @@
+// Package requestid предназначен для генерации и проверки идентификаторов запросов,
+// по которым сопоставляются записи логов агента и сервера.
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// MaxLength максимальная длина идентификатора запроса, принимаемого от клиента.
+const MaxLength = 128
+
+// New генерирует случайный идентификатор запроса.
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// Источник случайных чисел недоступен, идентификатор должен быть хотя бы уникальным.
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Valid проверяет, что идентификатор, полученный от клиента, можно безопасно сохранить и записать в лог:
+// он не пустой, не длиннее MaxLength и состоит только из латинских букв, цифр и символов "-", "_", ".", ":".
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package requestid_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gitslim/monit/internal/requestid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	a, b := requestid.New(), requestid.New()
+	assert.NotEqual(t, a, b)
+	assert.True(t, requestid.Valid(a))
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{id: "0af7651916cd43dd8448eb211c80319c", want: true},
+		{id: "agent-1:42.7_x", want: true},
+		{id: "", want: false},
+		{id: strings.Repeat("a", requestid.MaxLength+1), want: false},
+		{id: "id with spaces", want: false},
+		{id: "id\nforged log line", want: false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, requestid.Valid(tt.id), tt.id)
+	}
+}
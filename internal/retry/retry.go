@@ -2,6 +2,7 @@
 package retry
 
 import (
+	"context"
 	"log"
 	"time"
 )
@@ -15,8 +16,25 @@ type IRetriableError interface {
 	IsRetriable() bool
 }
 
+// IRetryAfterError определяет интерфейс для ошибок, содержащих запрошенное сервером время до повторной попытки.
+type IRetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// MaxRetryAfter максимальное время ожидания перед повторной попыткой, запрошенное сервером.
+const MaxRetryAfter = time.Minute
+
 // Retry выполняет функцию с повторными попытками.
+// Если ошибка содержит запрошенное сервером время до повторной попытки, ожидание длится это время,
+// но не дольше MaxRetryAfter.
 func Retry(operation RetryableFunc, maxRetries int) error {
+	return RetryContext(context.Background(), operation, maxRetries)
+}
+
+// RetryContext выполняет функцию с повторными попытками, как Retry.
+// Ожидание перед повторной попыткой прерывается при отмене ctx, при этом возвращается последняя ошибка.
+func RetryContext(ctx context.Context, operation RetryableFunc, maxRetries int) error {
 	var err error
 	retryIntervals := []time.Duration{1 * time.Second, 3 * time.Second, 5 * time.Second}
 
@@ -28,8 +46,18 @@ func Retry(operation RetryableFunc, maxRetries int) error {
 
 		if retriableErr, ok := err.(IRetriableError); ok && retriableErr.IsRetriable() {
 			if i < maxRetries {
-				log.Printf("Ошибка: %v. Повтор попытки %d через %v...", err, i+1, retryIntervals[i])
-				time.Sleep(retryIntervals[i])
+				delay := retryIntervals[i]
+				if retryAfterErr, ok := err.(IRetryAfterError); ok && retryAfterErr.RetryAfter() > 0 {
+					delay = min(retryAfterErr.RetryAfter(), MaxRetryAfter)
+				}
+				log.Printf("Ошибка: %v. Повтор попытки %d через %v...", err, i+1, delay)
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return err
+				}
 			}
 		} else {
 			return err
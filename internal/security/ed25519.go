@@ -0,0 +1,83 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SignEd25519 вычисляет подпись Ed25519 запроса в кодировке base64.
+func (r *SignedRequest) SignEd25519(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, r.canonical()))
+}
+
+// VerifyEd25519 проверяет подпись Ed25519 запроса в кодировке base64.
+func (r *SignedRequest) VerifyEd25519(key ed25519.PublicKey, signature string) bool {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(key, r.canonical(), sig)
+}
+
+// ReadEd25519PrivateKeyFromFile читает закрытый ключ Ed25519 в формате PEM (PKCS#8) из файла.
+func ReadEd25519PrivateKeyFromFile(filePath string) (ed25519.PrivateKey, error) {
+	block, err := readPemBlockFromFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ParsePrivateKey(block)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an Ed25519 private key")
+	}
+	return priv, nil
+}
+
+// ReadEd25519PublicKeyFromFile читает открытый ключ Ed25519 в формате PEM (PKIX) из файла.
+func ReadEd25519PublicKeyFromFile(filePath string) (ed25519.PublicKey, error) {
+	block, err := readPemBlockFromFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ParsePublicKey(block)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("not an Ed25519 public key")
+	}
+	return pub, nil
+}
+
+// LoadEd25519PublicKeys загружает открытые ключи Ed25519 агентов из каталога.
+// Каждый ключ хранится в файле <agent-id>.pem, имя файла без расширения является идентификатором агента.
+func LoadEd25519PublicKeys(dir string) (map[string]ed25519.PublicKey, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(paths))
+	for _, p := range paths {
+		pub, err := ReadEd25519PublicKeyFromFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key %s: %w", p, err)
+		}
+		keys[strings.TrimSuffix(filepath.Base(p), ".pem")] = pub
+	}
+	return keys, nil
+}
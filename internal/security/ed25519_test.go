@@ -0,0 +1,46 @@
+package security_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gitslim/monit/internal/security"
+	"github.com/gitslim/monit/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSignedRequestEd25519 тестирует подпись Ed25519 запроса ключами из файлов.
+func TestSignedRequestEd25519(t *testing.T) {
+	pubDir, privDir := t.TempDir(), t.TempDir()
+	priv1, err := testhelpers.GenerateEd25519Keys(pubDir, privDir, "agent-1")
+	require.NoError(t, err)
+	_, err = testhelpers.GenerateEd25519Keys(pubDir, privDir, "agent-2")
+	require.NoError(t, err)
+
+	keys, err := security.LoadEd25519PublicKeys(pubDir)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	priv, err := security.ReadEd25519PrivateKeyFromFile(priv1)
+	require.NoError(t, err)
+
+	req := security.SignedRequest{
+		Timestamp: time.Unix(1700000000, 0),
+		Nonce:     "nonce",
+		Method:    "POST",
+		Path:      "/updates/",
+		Body:      []byte(`[]`),
+	}
+	signature := req.SignEd25519(priv)
+	assert.True(t, req.VerifyEd25519(keys["agent-1"], signature))
+	// Подпись одного агента не подходит к ключу другого.
+	assert.False(t, req.VerifyEd25519(keys["agent-2"], signature))
+	assert.False(t, req.VerifyEd25519(keys["agent-1"], "not-base64"))
+
+	req.Body = []byte(`[{}]`)
+	assert.False(t, req.VerifyEd25519(keys["agent-1"], signature))
+
+	_, err = security.LoadEd25519PublicKeys(t.TempDir() + "/missing")
+	assert.Error(t, err)
+}
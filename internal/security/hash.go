@@ -2,20 +2,105 @@ package security
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
-	"io"
+	"strconv"
+	"sync"
+	"time"
 )
 
-// MakeSHA256Hash расчитывает хэш по алгоритму SHA256.
-func MakeSHA256Hash(rc io.Reader, key string) (string, error) {
-	var buf []byte
-	_, err := rc.Read(buf)
+// HashSHA256 расчитывает HMAC-SHA256 данных по ключу и возвращает его в шестнадцатеричном виде.
+func HashSHA256(data []byte, key string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SignedRequest содержит части запроса, которые покрывает подпись HMAC.
+type SignedRequest struct {
+	Timestamp time.Time // время подписи запроса
+	Nonce     string    // одноразовое случайное значение
+	Method    string    // HTTP-метод
+	Path      string    // путь запроса без параметров
+	Body      []byte    // тело запроса до сжатия и шифрования
+}
+
+// canonical возвращает каноническое представление запроса для подписи:
+// время в секундах Unix, nonce, метод и путь, разделенные переводом строки, и следом тело запроса.
+func (r *SignedRequest) canonical() []byte {
+	buf := make([]byte, 0, len(r.Nonce)+len(r.Method)+len(r.Path)+len(r.Body)+24)
+	buf = strconv.AppendInt(buf, r.Timestamp.Unix(), 10)
+	buf = append(buf, '\n')
+	buf = append(buf, r.Nonce...)
+	buf = append(buf, '\n')
+	buf = append(buf, r.Method...)
+	buf = append(buf, '\n')
+	buf = append(buf, r.Path...)
+	buf = append(buf, '\n')
+	return append(buf, r.Body...)
+}
+
+// Sign вычисляет подпись HMAC-SHA256 запроса в шестнадцатеричном виде.
+func (r *SignedRequest) Sign(key string) string {
+	return HashSHA256(r.canonical(), key)
+}
+
+// Verify проверяет подпись запроса за постоянное время.
+func (r *SignedRequest) Verify(key, signature string) bool {
+	got, err := hex.DecodeString(signature)
 	if err != nil {
+		return false
+	}
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(r.canonical())
+	return hmac.Equal(got, h.Sum(nil))
+}
+
+// NewNonce генерирует случайное одноразовое значение для подписи запроса.
+func NewNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
+	return hex.EncodeToString(b), nil
+}
 
-	h := hmac.New(sha256.New, []byte(key))
-	h.Write(buf)
-	return hex.EncodeToString(h.Sum(nil)), nil
+// NonceCache запоминает использованные nonce на время ttl для защиты от повторной отправки запросов.
+type NonceCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	seen      map[string]time.Time
+	nextSweep time.Time
+}
+
+// NewNonceCache создает кэш nonce с временем хранения ttl.
+// ttl должен покрывать весь интервал допустимых времен подписи, то есть двойное окно свежести.
+func NewNonceCache(ttl time.Duration) *NonceCache {
+	return &NonceCache{
+		ttl:  ttl,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// Use отмечает nonce использованным. Возвращает false, если nonce уже использовался.
+func (c *NonceCache) Use(nonce string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Устаревшие nonce удаляются не чаще одного раза за ttl.
+	if now.After(c.nextSweep) {
+		for n, exp := range c.seen {
+			if now.After(exp) {
+				delete(c.seen, n)
+			}
+		}
+		c.nextSweep = now.Add(c.ttl)
+	}
+
+	if exp, ok := c.seen[nonce]; ok && !now.After(exp) {
+		return false
+	}
+	c.seen[nonce] = now.Add(c.ttl)
+	return true
 }
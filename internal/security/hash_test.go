@@ -0,0 +1,54 @@
+package security_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gitslim/monit/internal/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSignedRequest тестирует подпись и проверку подписи запроса.
+func TestSignedRequest(t *testing.T) {
+	nonce, err := security.NewNonce()
+	require.NoError(t, err)
+
+	req := security.SignedRequest{
+		Timestamp: time.Unix(1700000000, 0),
+		Nonce:     nonce,
+		Method:    "POST",
+		Path:      "/updates/",
+		Body:      []byte(`[{"id":"g","type":"gauge","value":1}]`),
+	}
+	signature := req.Sign("key")
+	assert.True(t, req.Verify("key", signature))
+	assert.False(t, req.Verify("other", signature))
+	assert.False(t, req.Verify("key", "not-hex"))
+
+	// Подпись покрывает все части запроса.
+	tampered := []func(r *security.SignedRequest){
+		func(r *security.SignedRequest) { r.Body = []byte(`[]`) },
+		func(r *security.SignedRequest) { r.Path = "/update/" },
+		func(r *security.SignedRequest) { r.Nonce = "other" },
+		func(r *security.SignedRequest) { r.Timestamp = r.Timestamp.Add(time.Second) },
+	}
+	for _, f := range tampered {
+		r := req
+		f(&r)
+		assert.False(t, r.Verify("key", signature))
+	}
+}
+
+// TestNonceCache тестирует отклонение повторных nonce и их истечение.
+func TestNonceCache(t *testing.T) {
+	c := security.NewNonceCache(time.Minute)
+	now := time.Now()
+
+	assert.True(t, c.Use("n1", now))
+	assert.False(t, c.Use("n1", now.Add(30*time.Second)))
+	assert.True(t, c.Use("n2", now))
+
+	// После ttl nonce забывается.
+	assert.True(t, c.Use("n1", now.Add(2*time.Minute)))
+}
@@ -0,0 +1,163 @@
+package security
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Типы блоков PEM.
+const (
+	pemPrivateKey    = "PRIVATE KEY"
+	pemPublicKey     = "PUBLIC KEY"
+	pemRSAPrivateKey = "RSA PRIVATE KEY"
+	pemRSAPublicKey  = "RSA PUBLIC KEY"
+)
+
+// KeyPair содержит пару ключей в формате PEM: закрытый ключ в PKCS#8 и открытый ключ в PKIX.
+type KeyPair struct {
+	Private []byte
+	Public  []byte
+}
+
+// GenerateRSAKeyPair генерирует пару ключей RSA для шифрования тела запросов.
+func GenerateRSAKeyPair(bits int) (*KeyPair, error) {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, err
+	}
+	return encodeKeyPair(key, &key.PublicKey)
+}
+
+// GenerateEd25519KeyPair генерирует пару ключей Ed25519 для подписи запросов агента.
+func GenerateEd25519KeyPair() (*KeyPair, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return encodeKeyPair(priv, pub)
+}
+
+// encodeKeyPair кодирует пару ключей в формат PEM.
+func encodeKeyPair(priv crypto.PrivateKey, pub crypto.PublicKey) (*KeyPair, error) {
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyPair{
+		Private: pem.EncodeToMemory(&pem.Block{Type: pemPrivateKey, Bytes: privDER}),
+		Public:  pem.EncodeToMemory(&pem.Block{Type: pemPublicKey, Bytes: pubDER}),
+	}, nil
+}
+
+// GenerateHMACSecret генерирует случайный ключ HMAC из size байт в шестнадцатеричном виде.
+func GenerateHMACSecret(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Fingerprint вычисляет отпечаток открытого ключа: SHA-256 от ключа в формате PKIX в кодировке base64.
+func Fingerprint(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]), nil
+}
+
+// ParsePrivateKey разбирает закрытый ключ из блока PEM в формате PKCS#8 или PKCS#1 (RSA).
+func ParsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	switch block.Type {
+	case pemRSAPrivateKey:
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case pemPrivateKey:
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
+
+// ParsePublicKey разбирает открытый ключ из блока PEM в формате PKIX или PKCS#1 (RSA).
+func ParsePublicKey(block *pem.Block) (crypto.PublicKey, error) {
+	switch block.Type {
+	case pemRSAPublicKey:
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case pemPublicKey:
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
+
+// ReadKeyFromFile читает закрытый или открытый ключ из файла в формате PEM.
+// Для закрытого ключа возвращается crypto.Signer, для открытого - crypto.PublicKey.
+func ReadKeyFromFile(filePath string) (any, error) {
+	block, err := readPemBlockFromFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == pemPrivateKey || block.Type == pemRSAPrivateKey {
+		return ParsePrivateKey(block)
+	}
+	return ParsePublicKey(block)
+}
+
+// ValidateKeyPair проверяет, что открытый ключ соответствует закрытому и пара пригодна к использованию:
+// для RSA проверяется шифрование и расшифровка, для Ed25519 - подпись и ее проверка.
+func ValidateKeyPair(priv crypto.Signer, pub crypto.PublicKey) error {
+	type equaler interface {
+		Equal(x crypto.PublicKey) bool
+	}
+	p, ok := priv.Public().(equaler)
+	if !ok || !p.Equal(pub) {
+		return errors.New("public key does not match private key")
+	}
+
+	probe := []byte("monit key pair check")
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		enc, err := EncryptRSA(&k.PublicKey, probe)
+		if err != nil {
+			return err
+		}
+		dec, err := DecryptRSA(k, enc)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(dec, probe) {
+			return errors.New("decrypted data does not match")
+		}
+	case ed25519.PrivateKey:
+		if !ed25519.Verify(pub.(ed25519.PublicKey), probe, ed25519.Sign(k, probe)) {
+			return errors.New("signature verification failed")
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", priv)
+	}
+	return nil
+}
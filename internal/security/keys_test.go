@@ -0,0 +1,94 @@
+package security_test
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gitslim/monit/internal/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerateKeyPairs тестирует генерацию и проверку пар ключей.
+func TestGenerateKeyPairs(t *testing.T) {
+	rsaPair, err := security.GenerateRSAKeyPair(2048)
+	require.NoError(t, err)
+	edPair, err := security.GenerateEd25519KeyPair()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+		return path
+	}
+	rsaPriv := write("rsa-key.pem", rsaPair.Private)
+	rsaPub := write("rsa.pem", rsaPair.Public)
+	edPriv := write("ed-key.pem", edPair.Private)
+	edPub := write("ed.pem", edPair.Public)
+
+	// Ключи читаются функциями, которые используют агент и сервер.
+	priv, err := security.ReadRSAPrivateKeyFromFile(rsaPriv)
+	require.NoError(t, err)
+	pub, err := security.ReadRSAPublicKeyFromFile(rsaPub)
+	require.NoError(t, err)
+	assert.NoError(t, security.ValidateKeyPair(priv, pub))
+
+	edKey, err := security.ReadEd25519PrivateKeyFromFile(edPriv)
+	require.NoError(t, err)
+	edPubKey, err := security.ReadEd25519PublicKeyFromFile(edPub)
+	require.NoError(t, err)
+	assert.NoError(t, security.ValidateKeyPair(edKey, edPubKey))
+
+	// Ключи из разных пар не проходят проверку.
+	assert.Error(t, security.ValidateKeyPair(priv, edPubKey))
+
+	// Отпечатки закрытого и открытого ключа совпадают.
+	fp1, err := security.Fingerprint(priv.Public())
+	require.NoError(t, err)
+	fp2, err := security.Fingerprint(pub)
+	require.NoError(t, err)
+	assert.Equal(t, fp1, fp2)
+
+	secret, err := security.GenerateHMACSecret(32)
+	require.NoError(t, err)
+	assert.Len(t, secret, 64)
+}
+
+// TestReadRSAKeysPKCS1 тестирует чтение ключей RSA в формате PKCS#1.
+func TestReadRSAKeysPKCS1(t *testing.T) {
+	pair, err := security.GenerateRSAKeyPair(2048)
+	require.NoError(t, err)
+	block, _ := pem.Decode(pair.Private)
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	require.NoError(t, err)
+	rsaKey := key.(*rsa.PrivateKey)
+
+	dir := t.TempDir()
+	privPath := filepath.Join(dir, "private.pem")
+	pubPath := filepath.Join(dir, "public.pem")
+	require.NoError(t, os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{
+		Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey),
+	}), 0o600))
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{
+		Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey),
+	}), 0o600))
+
+	priv, err := security.ReadRSAPrivateKeyFromFile(privPath)
+	require.NoError(t, err)
+	pub, err := security.ReadRSAPublicKeyFromFile(pubPath)
+	require.NoError(t, err)
+	assert.True(t, priv.PublicKey.Equal(pub))
+
+	// Ключ другого типа отклоняется без паники.
+	edPair, err := security.GenerateEd25519KeyPair()
+	require.NoError(t, err)
+	edPath := filepath.Join(dir, "ed.pem")
+	require.NoError(t, os.WriteFile(edPath, edPair.Private, 0o600))
+	_, err = security.ReadRSAPrivateKeyFromFile(edPath)
+	assert.Error(t, err)
+}
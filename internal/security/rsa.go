@@ -3,10 +3,10 @@ package security
 import (
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/x509"
+	"errors"
 )
 
-// ReadRSAPublicKeyFromFile читает открытый ключ RSA из файла
+// ReadRSAPublicKeyFromFile читает открытый ключ RSA из файла (PKIX или PKCS#1)
 func ReadRSAPublicKeyFromFile(filePath string) (*rsa.PublicKey, error) {
 	// Читаем PEM-блок из файла
 	block, err := readPemBlockFromFile(filePath)
@@ -14,15 +14,19 @@ func ReadRSAPublicKeyFromFile(filePath string) (*rsa.PublicKey, error) {
 		return nil, err
 	}
 
-	//
-	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	// Парсим открытый ключ в формате PKIX или PKCS#1
+	pub, err := ParsePublicKey(block)
 	if err != nil {
 		return nil, err
 	}
-	return pub.(*rsa.PublicKey), nil
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA public key")
+	}
+	return rsaPub, nil
 }
 
-// ReadRSAPrivateKeyFromFile читает закрытый ключ RSA из файла
+// ReadRSAPrivateKeyFromFile читает закрытый ключ RSA из файла (PKCS#8 или PKCS#1)
 func ReadRSAPrivateKeyFromFile(filePath string) (*rsa.PrivateKey, error) {
 	// Читаем PEM-блок из файла
 	block, err := readPemBlockFromFile(filePath)
@@ -30,12 +34,16 @@ func ReadRSAPrivateKeyFromFile(filePath string) (*rsa.PrivateKey, error) {
 		return nil, err
 	}
 
-	// Парсим закрытый ключ из PEM-блока
-	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	// Парсим закрытый ключ из PEM-блока в формате PKCS#8 или PKCS#1
+	key, err := ParsePrivateKey(block)
 	if err != nil {
 		return nil, err
 	}
-	return key.(*rsa.PrivateKey), nil
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	return rsaKey, nil
 }
 
 // EncryptRSA шифрует данные с использованием публичного ключа
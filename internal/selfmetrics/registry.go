@@ -0,0 +1,223 @@
+// Package selfmetrics содержит метрики самого сервера мониторинга: число и длительность запросов,
+// скорость приема метрик, длительность операций с хранилищем и т.п.
+//
+// Имена метрик сервера начинаются с зарезервированного префикса Prefix. Метки записываются
+// в имени в фигурных скобках: monit_server_http_requests_total{method=POST,route=/updates/,status=200}.
+// Методы Registry безопасны для конкурентного использования и ничего не делают для nil-реестра,
+// поэтому инструментированный код не проверяет, включены ли метрики сервера.
+package selfmetrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gitslim/monit/internal/entities"
+)
+
+// Prefix зарезервированный префикс имен метрик сервера.
+const Prefix = "monit_server_"
+
+// Name возвращает полное имя метрики сервера с префиксом и метками, заданными парами ключ-значение.
+func Name(name string, labels ...string) string {
+	return PrefixedName(Prefix, name, labels...)
+}
+
+// PrefixedName возвращает полное имя метрики с префиксом prefix и метками, заданными парами ключ-значение.
+// Используется для метрик агента, имена которых записываются в том же формате под другим префиксом.
+func PrefixedName(prefix, name string, labels ...string) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	b.WriteString(name)
+	if len(labels) < 2 {
+		return b.String()
+	}
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i])
+		b.WriteByte('=')
+		b.WriteString(labels[i+1])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// IsReserved проверяет, что имя метрики относится к зарезервированному пространству имен сервера.
+func IsReserved(name string) bool {
+	return strings.HasPrefix(name, Prefix)
+}
+
+// Collector возвращает текущие значения метрик, которые не обновляются инструментированным кодом,
+// а считываются при каждом снимке, например статистику пула соединений.
+type Collector func() []*entities.MetricDTO
+
+// Registry хранит метрики сервера.
+type Registry struct {
+	mu         sync.Mutex
+	now        func() time.Time
+	counters   map[string]int64
+	gauges     map[string]float64
+	summaries  map[string]*summary
+	meters     map[string]*meter
+	collectors []Collector
+}
+
+// NewRegistry создает пустой реестр метрик сервера.
+func NewRegistry() *Registry {
+	return &Registry{
+		now:       time.Now,
+		counters:  make(map[string]int64),
+		gauges:    make(map[string]float64),
+		summaries: make(map[string]*summary),
+		meters:    make(map[string]*meter),
+	}
+}
+
+// Add увеличивает счетчик name с метками labels на delta.
+func (r *Registry) Add(name string, delta int64, labels ...string) {
+	if r == nil {
+		return
+	}
+	key := Name(name, labels...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[key] += delta
+}
+
+// Set устанавливает значение gauge name с метками labels.
+func (r *Registry) Set(name string, value float64, labels ...string) {
+	if r == nil {
+		return
+	}
+	key := Name(name, labels...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges[key] = value
+}
+
+// Observe добавляет наблюдение value в сводку name с метками labels.
+// Сводка отображается в метрики name_count (число наблюдений), name_avg и name_max
+// (среднее и максимальное значение за последнюю минуту).
+func (r *Registry) Observe(name string, value float64, labels ...string) {
+	if r == nil {
+		return
+	}
+	key := name + "\x00" + strings.Join(labels, "\x00")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.summaries[key]
+	if !ok {
+		s = &summary{name: name, labels: labels}
+		r.summaries[key] = s
+	}
+	s.observe(r.now(), value)
+}
+
+// ObserveDuration добавляет длительность d в секундах в сводку name с метками labels.
+func (r *Registry) ObserveDuration(name string, d time.Duration, labels ...string) {
+	r.Observe(name, d.Seconds(), labels...)
+}
+
+// Mark учитывает n событий в измерителе name с метками labels.
+// Измеритель отображается в метрики name_total (число событий) и name_per_second
+// (средняя скорость за последнюю минуту).
+func (r *Registry) Mark(name string, n int64, labels ...string) {
+	if r == nil {
+		return
+	}
+	key := name + "\x00" + strings.Join(labels, "\x00")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.meters[key]
+	if !ok {
+		m = &meter{name: name, labels: labels}
+		r.meters[key] = m
+	}
+	m.mark(r.now(), n)
+}
+
+// RegisterCollector добавляет сборщик метрик, вызываемый при каждом снимке.
+func (r *Registry) RegisterCollector(c Collector) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Snapshot возвращает текущие значения всех метрик сервера, отсортированные по имени.
+func (r *Registry) Snapshot() []*entities.MetricDTO {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	now := r.now()
+	var metrics []*entities.MetricDTO
+	for name, v := range r.counters {
+		metrics = append(metrics, NewCounter(name, v))
+	}
+	for name, v := range r.gauges {
+		metrics = append(metrics, NewGauge(name, v))
+	}
+	for _, s := range r.summaries {
+		count, sum, maxValue := s.window.stats(now)
+		avg := 0.0
+		if count > 0 {
+			avg = sum / float64(count)
+		}
+		metrics = append(metrics,
+			NewCounter(Name(s.name+"_count", s.labels...), s.total),
+			NewGauge(Name(s.name+"_avg", s.labels...), avg),
+			NewGauge(Name(s.name+"_max", s.labels...), maxValue),
+		)
+	}
+	for _, m := range r.meters {
+		_, sum, _ := m.window.stats(now)
+		metrics = append(metrics,
+			NewCounter(Name(m.name+"_total", m.labels...), m.total),
+			NewGauge(Name(m.name+"_per_second", m.labels...), sum/windowSize),
+		)
+	}
+	collectors := r.collectors
+	r.mu.Unlock()
+
+	// Сборщики вызываются без блокировки: они могут обращаться к хранилищу.
+	for _, c := range collectors {
+		metrics = append(metrics, c()...)
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].ID < metrics[j].ID
+	})
+	return metrics
+}
+
+// NewCounter создает DTO счетчика с полным именем name.
+func NewCounter(name string, v int64) *entities.MetricDTO {
+	return &entities.MetricDTO{ID: name, MType: entities.Counter.String(), Delta: &v}
+}
+
+// NewGauge создает DTO gauge с полным именем name.
+func NewGauge(name string, v float64) *entities.MetricDTO {
+	return &entities.MetricDTO{ID: name, MType: entities.Gauge.String(), Value: &v}
+}
+
+// Counter создает DTO счетчика сервера для сборщиков метрик.
+func Counter(name string, v int64, labels ...string) *entities.MetricDTO {
+	return NewCounter(Name(name, labels...), v)
+}
+
+// Gauge создает DTO gauge сервера для сборщиков метрик.
+func Gauge(name string, v float64, labels ...string) *entities.MetricDTO {
+	return NewGauge(Name(name, labels...), v)
+}
@@ -0,0 +1,86 @@
+package selfmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// snapshotMap возвращает значения снимка по именам метрик.
+func snapshotMap(t *testing.T, r *Registry) map[string]any {
+	t.Helper()
+	values := make(map[string]any)
+	for _, m := range r.Snapshot() {
+		switch {
+		case m.Delta != nil:
+			values[m.ID] = *m.Delta
+		case m.Value != nil:
+			values[m.ID] = *m.Value
+		default:
+			t.Fatalf("metric %s without value", m.ID)
+		}
+	}
+	return values
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "monit_server_up", Name("up"))
+	assert.Equal(t, "monit_server_requests{method=GET,status=200}", Name("requests", "method", "GET", "status", "200"))
+	assert.True(t, IsReserved(Name("up")))
+	assert.False(t, IsReserved("Alloc"))
+}
+
+func TestRegistry(t *testing.T) {
+	now := time.Unix(1000, 0)
+	r := NewRegistry()
+	r.now = func() time.Time { return now }
+
+	r.Add("requests_total", 2, "status", "200")
+	r.Add("requests_total", 1, "status", "200")
+	r.Set("up", 1)
+	r.Observe("batch_size", 10)
+	r.Observe("batch_size", 30)
+	r.Mark("ingested_metrics", 40)
+	r.Mark("ingested_metrics", 20)
+	r.RegisterCollector(func() []*entities.MetricDTO {
+		return []*entities.MetricDTO{Gauge("pool_conns", 4)}
+	})
+
+	values := snapshotMap(t, r)
+	assert.Equal(t, int64(3), values["monit_server_requests_total{status=200}"])
+	assert.Equal(t, 1.0, values["monit_server_up"])
+	assert.Equal(t, int64(2), values["monit_server_batch_size_count"])
+	assert.Equal(t, 20.0, values["monit_server_batch_size_avg"])
+	assert.Equal(t, 30.0, values["monit_server_batch_size_max"])
+	assert.Equal(t, int64(60), values["monit_server_ingested_metrics_total"])
+	assert.Equal(t, 1.0, values["monit_server_ingested_metrics_per_second"])
+	assert.Equal(t, 4.0, values["monit_server_pool_conns"])
+
+	// Статистика за минуту устаревает, общие счетчики сохраняются.
+	now = now.Add(2 * time.Minute)
+	values = snapshotMap(t, r)
+	assert.Equal(t, int64(2), values["monit_server_batch_size_count"])
+	assert.Equal(t, 0.0, values["monit_server_batch_size_avg"])
+	assert.Equal(t, 0.0, values["monit_server_ingested_metrics_per_second"])
+
+	// Снимок отсортирован по имени.
+	snapshot := r.Snapshot()
+	require.NotEmpty(t, snapshot)
+	for i := 1; i < len(snapshot); i++ {
+		assert.Less(t, snapshot[i-1].ID, snapshot[i].ID)
+	}
+}
+
+func TestNilRegistry(t *testing.T) {
+	var r *Registry
+	r.Add("requests_total", 1)
+	r.Set("up", 1)
+	r.Observe("batch_size", 1)
+	r.ObserveDuration("latency", time.Second)
+	r.Mark("ingested_metrics", 1)
+	r.RegisterCollector(func() []*entities.MetricDTO { return nil })
+	assert.Nil(t, r.Snapshot())
+}
@@ -0,0 +1,77 @@
+package selfmetrics
+
+import "time"
+
+// windowSize длина скользящего окна в секундах, за которое считаются средние и максимальные значения.
+const windowSize = 60
+
+// bucket наблюдения за одну секунду.
+type bucket struct {
+	sec   int64
+	count int64
+	sum   float64
+	max   float64
+}
+
+// window скользящее окно наблюдений за последние windowSize секунд.
+type window struct {
+	buckets [windowSize]bucket
+}
+
+// add добавляет count наблюдений с суммой sum и максимумом maxValue в секунду now.
+func (w *window) add(now time.Time, count int64, sum, maxValue float64) {
+	sec := now.Unix()
+	b := &w.buckets[sec%windowSize]
+	if b.sec != sec {
+		*b = bucket{sec: sec, max: maxValue}
+	}
+	b.count += count
+	b.sum += sum
+	if maxValue > b.max {
+		b.max = maxValue
+	}
+}
+
+// stats возвращает число, сумму и максимум наблюдений за последние windowSize секунд.
+func (w *window) stats(now time.Time) (count int64, sum, maxValue float64) {
+	sec := now.Unix()
+	for _, b := range w.buckets {
+		if b.count == 0 || sec-b.sec >= windowSize || b.sec > sec {
+			continue
+		}
+		if count == 0 || b.max > maxValue {
+			maxValue = b.max
+		}
+		count += b.count
+		sum += b.sum
+	}
+	return count, sum, maxValue
+}
+
+// summary сводка наблюдений: общее число и статистика за последнюю минуту.
+type summary struct {
+	name   string
+	labels []string
+	total  int64
+	window window
+}
+
+// observe добавляет наблюдение value.
+func (s *summary) observe(now time.Time, value float64) {
+	s.total++
+	s.window.add(now, 1, value, value)
+}
+
+// meter измеритель скорости событий: общее число и скорость за последнюю минуту.
+type meter struct {
+	name   string
+	labels []string
+	total  int64
+	window window
+}
+
+// mark учитывает n событий.
+func (m *meter) mark(now time.Time, n int64) {
+	m.total += n
+	m.window.add(now, 1, float64(n), float64(n))
+}
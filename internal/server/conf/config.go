@@ -2,132 +2,288 @@
 package conf
 
 import (
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"strings"
+	"time"
 
-	env "github.com/caarlos0/env/v6"
+	"github.com/gitslim/monit/internal/config"
+	"github.com/gitslim/monit/internal/debugserver"
+	"github.com/gitslim/monit/internal/logging"
 )
 
 // Значения по умолчанию для конфигурации.
 const (
-	DefaultAddr            = "localhost:8080"
-	DefaultStoreInterval   = 300
-	DefaultFileStoragePath = "/tmp/.monit/memstorage.json"
-	DefaultRestore         = true
-	DefaultDatabaseDSN     = ""
-	DefaultKey             = ""
-	DefaultCryptoKey       = ""
-	DefaultConfig          = ""
+	DefaultAddr                  = "localhost:8080"
+	DefaultStoreInterval         = config.Duration(300 * time.Second)
+	DefaultFileStoragePath       = "/tmp/.monit/memstorage.json"
+	DefaultRestore               = true
+	DefaultDatabaseDSN           = ""
+	DefaultRedisAddr             = ""
+	DefaultHistoryRetention      = config.Duration(3600 * time.Second)
+	DefaultAlertRules            = ""
+	DefaultAlertInterval         = config.Duration(15 * time.Second)
+	DefaultWebhooks              = ""
+	DefaultTemplatesDir          = ""
+	DefaultTLSCert               = ""
+	DefaultTLSKey                = ""
+	DefaultTLSClientCA           = ""
+	DefaultAuthTokens            = ""
+	DefaultAuthTokensDB          = false
+	DefaultAuthRequireRead       = false
+	DefaultKey                   = ""
+	DefaultSignatureWindow       = config.Duration(300 * time.Second)
+	DefaultSignatureKeys         = ""
+	DefaultCryptoKey             = ""
+	DefaultMaxBodySize           = 10 << 20
+	DefaultMaxDecompressedSize   = 50 << 20
+	DefaultRateLimit             = 0
+	DefaultRateBurst             = 100
+	DefaultSelfMetricsInterval   = config.Duration(0)
+	DefaultShutdownDelay         = config.Duration(0)
+	DefaultShutdownTimeout       = config.Duration(5 * time.Second)
+	DefaultDebugAddr             = ""
+	DefaultDebugToken            = ""
+	DefaultLogLevel              = logging.DefaultLevel
+	DefaultLogFormat             = logging.DefaultFormat
+	DefaultLogOutput             = logging.DefaultOutput
+	DefaultLogMaxSize            = logging.DefaultMaxSize
+	DefaultLogMaxBackups         = logging.DefaultMaxBackups
+	DefaultLogSamplingInitial    = logging.DefaultSamplingInitial
+	DefaultLogSamplingThereafter = logging.DefaultSamplingThereafter
+	DefaultConfig                = ""
 )
 
 // Config представляет конфигурацию сервера.
 type Config struct {
-	Addr            string `env:"ADDRESS" json:"address"`
-	StoreInterval   uint64 `env:"STORE_INTERVAL" json:"store_interval"`
-	FileStoragePath string `env:"FILE_STORAGE_PATH" json:"file_storage_path"`
-	Restore         bool   `env:"RESTORE" json:"restore"`
-	DatabaseDSN     string `env:"DATABASE_DSN" json:"database_dsn"`
-	Key             string `env:"KEY" json:"key"`
-	CryptoKey       string `env:"CRYPTO_KEY" json:"crypto_key"`
-	ConfigPath      string `env:"CONFIG" json:"-"`
+	Addr                  string          `env:"ADDRESS" json:"address" flag:"a" usage:"Адрес сервера (в формате host:port)"`
+	StoreInterval         config.Duration `env:"STORE_INTERVAL" json:"store_interval" flag:"i" usage:"Интервал сохранения данных на диск (например 500ms, 10s или 5m, число без единиц - секунды)"`
+	FileStoragePath       string          `env:"FILE_STORAGE_PATH" json:"file_storage_path" flag:"f" usage:"Путь до файла сохранения данных"`
+	Restore               bool            `env:"RESTORE" json:"restore" flag:"r" usage:"Флаг загрузки сохраненных данных при старте сервера"`
+	DatabaseDSN           string          `env:"DATABASE_DSN" json:"database_dsn" flag:"d" usage:"Строка подключения к базе данных (DSN)" secret:"true"`
+	RedisAddr             string          `env:"REDIS_ADDR" json:"redis_addr" flag:"redis-addr" usage:"Адрес сервера Redis (в формате host:port)"`
+	HistoryRetention      config.Duration `env:"HISTORY_RETENTION" json:"history_retention" flag:"history-retention" usage:"Окно хранения истории значений метрик (например 500ms, 10s или 5m, число без единиц - секунды)"`
+	AlertRules            string          `env:"ALERT_RULES" json:"alert_rules" flag:"alert-rules" usage:"Путь до файла правил оповещений (JSON)"`
+	AlertInterval         config.Duration `env:"ALERT_INTERVAL" json:"alert_interval" flag:"alert-interval" usage:"Интервал вычисления правил оповещений (например 500ms, 10s или 5m, число без единиц - секунды)"`
+	Webhooks              string          `env:"WEBHOOKS" json:"webhooks" flag:"webhooks" usage:"Путь до файла webhook-получателей оповещений (JSON)"`
+	TemplatesDir          string          `env:"TEMPLATES_DIR" json:"templates_dir" flag:"templates" usage:"Каталог с HTML-шаблонами и статическими файлами вместо встроенных"`
+	TLSCert               string          `env:"TLS_CERT" json:"tls_cert" flag:"tls-cert" usage:"Путь до сертификата сервера (PEM) для HTTPS"`
+	TLSKey                string          `env:"TLS_KEY" json:"tls_key" flag:"tls-key" usage:"Путь до приватного ключа сертификата сервера (PEM)"`
+	TLSClientCA           string          `env:"TLS_CLIENT_CA" json:"tls_client_ca" flag:"tls-client-ca" usage:"Путь до сертификата CA для проверки сертификатов агентов (mTLS)"`
+	AuthTokens            string          `env:"AUTH_TOKENS" json:"auth_tokens" flag:"auth-tokens" usage:"Путь до файла токенов агентов (JSON)"`
+	AuthTokensDB          bool            `env:"AUTH_TOKENS_DB" json:"auth_tokens_db" flag:"auth-tokens-db" usage:"Хранить токены агентов в таблице auth_tokens базы данных"`
+	AuthRequireRead       bool            `env:"AUTH_REQUIRE_READ" json:"auth_require_read" flag:"auth-require-read" usage:"Требовать токен с правом read для чтения метрик"`
+	Key                   string          `env:"KEY" json:"key" flag:"k" usage:"Ключ шифрования" secret:"true"`
+	SignatureWindow       config.Duration `env:"SIGNATURE_WINDOW" json:"signature_window" flag:"signature-window" usage:"Допустимое отклонение времени подписи запроса (например 500ms, 10s или 5m, число без единиц - секунды)"`
+	SignatureKeys         string          `env:"SIGNATURE_KEYS" json:"signature_keys" flag:"signature-keys" usage:"Каталог открытых ключей Ed25519 агентов (<agent-id>.pem)"`
+	CryptoKey             string          `env:"CRYPTO_KEY" json:"crypto_key" flag:"crypto-key" usage:"Приватные ключи шифрования через запятую (для смены ключей без простоя)"`
+	MaxBodySize           uint64          `env:"MAX_BODY_SIZE" json:"max_body_size" flag:"max-body-size" usage:"Максимальный размер тела запроса до распаковки (в байтах, 0 - без ограничения)"`
+	MaxDecompressedSize   uint64          `env:"MAX_DECOMPRESSED_SIZE" json:"max_decompressed_size" flag:"max-decompressed-size" usage:"Максимальный размер тела запроса после распаковки (в байтах, 0 - без ограничения)"`
+	RateLimit             float64         `env:"RATE_LIMIT" json:"rate_limit" flag:"rate-limit" usage:"Допустимая частота запросов одного клиента (запросов в секунду, 0 - без ограничения)"`
+	RateBurst             uint64          `env:"RATE_BURST" json:"rate_burst" flag:"rate-burst" usage:"Допустимый всплеск запросов одного клиента сверх частоты"`
+	SelfMetricsInterval   config.Duration `env:"SELF_METRICS_INTERVAL" json:"self_metrics_interval" flag:"self-metrics-interval" usage:"Интервал записи метрик сервера в хранилище (например 500ms, 10s или 5m, число без единиц - секунды; 0 - не записывать)"`
+	ShutdownDelay         config.Duration `env:"SHUTDOWN_DELAY" json:"shutdown_delay" flag:"shutdown-delay" usage:"Задержка остановки сервера после сигнала, в течение которой /readyz сообщает о неготовности (например 500ms, 10s или 5m, число без единиц - секунды)"`
+	ShutdownTimeout       config.Duration `env:"SHUTDOWN_TIMEOUT" json:"shutdown_timeout" flag:"shutdown-timeout" usage:"Время ожидания завершения обрабатываемых запросов при остановке сервера (например 500ms, 10s или 5m, число без единиц - секунды)"`
+	DebugAddr             string          `env:"DEBUG_ADDR" json:"debug_addr" flag:"debug-addr" usage:"Адрес отладочного сервера pprof/expvar (host:port), пустое значение выключает его"`
+	DebugToken            string          `env:"DEBUG_TOKEN" json:"debug_token" flag:"debug-token" usage:"Токен доступа к отладочному серверу" secret:"true"`
+	LogLevel              string          `env:"LOG_LEVEL" json:"log_level" flag:"log-level" usage:"Уровень логгирования: debug, info, warn или error"`
+	LogFormat             string          `env:"LOG_FORMAT" json:"log_format" flag:"log-format" usage:"Формат логов: json или console"`
+	LogOutput             string          `env:"LOG_OUTPUT" json:"log_output" flag:"log-output" usage:"Вывод логов: stderr, stdout или путь до файла"`
+	LogMaxSize            uint64          `env:"LOG_MAX_SIZE" json:"log_max_size" flag:"log-max-size" usage:"Размер файла логов, после которого выполняется ротация (в мегабайтах, 0 - без ротации)"`
+	LogMaxBackups         uint64          `env:"LOG_MAX_BACKUPS" json:"log_max_backups" flag:"log-max-backups" usage:"Число хранимых старых файлов логов"`
+	LogSamplingInitial    uint64          `env:"LOG_SAMPLING_INITIAL" json:"log_sampling_initial" flag:"log-sampling-initial" usage:"Число одинаковых сообщений в секунду, выводимых без сэмплирования (0 - без сэмплирования)"`
+	LogSamplingThereafter uint64          `env:"LOG_SAMPLING_THEREAFTER" json:"log_sampling_thereafter" flag:"log-sampling-thereafter" usage:"Сверх этого выводится каждое N-е одинаковое сообщение"`
+	ConfigPath            string          `env:"CONFIG" json:"-" flag:"config" usage:"Путь до конфигурационного файла (JSON, YAML или TOML)"`
+	PrintConfig           bool            `json:"-" flag:"print-config" usage:"Вывести итоговую конфигурацию с источниками значений и завершить работу"`
+
+	// sources источники значений полей конфигурации.
+	sources config.Sources
+}
+
+// UseTLS проверяет, должен ли сервер принимать соединения по HTTPS.
+func (cfg *Config) UseTLS() bool {
+	return cfg.TLSCert != ""
+}
+
+// UseAuth проверяет, включена ли аутентификация агентов по токенам.
+func (cfg *Config) UseAuth() bool {
+	return cfg.AuthTokens != "" || cfg.AuthTokensDB
+}
+
+// CryptoKeys возвращает пути до приватных ключей шифрования, заданных через запятую.
+func (cfg *Config) CryptoKeys() []string {
+	var keys []string
+	for _, k := range strings.Split(cfg.CryptoKey, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Redacted возвращает копию конфигурации, в которой скрыты секреты: ключ HMAC, строка подключения
+// к базе данных с паролем и токен отладочного сервера.
+func (cfg *Config) Redacted() Config {
+	c := *cfg
+	config.Redact(&c)
+	return c
+}
+
+// LogOptions возвращает параметры логгера из конфигурации.
+func (cfg *Config) LogOptions() []logging.Option {
+	return []logging.Option{
+		logging.WithLevel(cfg.LogLevel),
+		logging.WithFormat(cfg.LogFormat),
+		logging.WithOutput(cfg.LogOutput),
+		logging.WithRotation(cfg.LogMaxSize, cfg.LogMaxBackups),
+		logging.WithSampling(cfg.LogSamplingInitial, cfg.LogSamplingThereafter),
+	}
 }
 
-// ParseConfig парсит конфигурацию из флагов и переменных окружения.
+// ParseConfig парсит конфигурацию сервера из конфигурационного файла, переменных окружения и флагов.
 func ParseConfig() (*Config, error) {
-	configPath := flag.String("config", DefaultConfig, "Путь до конфигурационного файла (JSON)")
-	addr := flag.String("a", DefaultAddr, "Адрес сервера (в формате host:port)")
-	storeInterval := flag.Uint64("i", DefaultStoreInterval, "Интервал сохранения данных на диск (в секундах)")
-	fileStoragePath := flag.String("f", DefaultFileStoragePath, "Путь до файла сохранения данных")
-	restore := flag.Bool("r", DefaultRestore, "Флаг загрузки сохраненных данных при старте сервера")
-	databaseDSN := flag.String("d", DefaultDatabaseDSN, "Строка подключения к базе данных (DSN)")
-	key := flag.String("k", DefaultKey, "Ключ шифрования")
-	cryptoKey := flag.String("crypto-key", DefaultCryptoKey, "Приватный ключ шифрования")
-
-	// Парсим флаги
-	flag.Parse()
-
-	// Загружаем конфиг из JSON если путь указан
+	return parseConfig(flag.CommandLine, os.Args[1:])
+}
+
+// parseConfig парсит конфигурацию с флагами fs из аргументов args.
+// Ошибки значений переменных окружения и флагов возвращаются вместе с ошибками валидации.
+func parseConfig(fs *flag.FlagSet, args []string) (*Config, error) {
 	cfg := Config{
-		Addr:            DefaultAddr,
-		StoreInterval:   DefaultStoreInterval,
-		FileStoragePath: DefaultFileStoragePath,
-		Restore:         DefaultRestore,
-		DatabaseDSN:     DefaultDatabaseDSN,
-		Key:             DefaultKey,
-		CryptoKey:       DefaultCryptoKey,
-		ConfigPath:      *configPath,
-	}
-
-	if *configPath != "" {
-		if err := loadConfigFromJSON(*configPath, &cfg); err != nil {
-			return nil, err
-		}
+		Addr:                  DefaultAddr,
+		StoreInterval:         DefaultStoreInterval,
+		FileStoragePath:       DefaultFileStoragePath,
+		Restore:               DefaultRestore,
+		DatabaseDSN:           DefaultDatabaseDSN,
+		RedisAddr:             DefaultRedisAddr,
+		HistoryRetention:      DefaultHistoryRetention,
+		AlertRules:            DefaultAlertRules,
+		AlertInterval:         DefaultAlertInterval,
+		Webhooks:              DefaultWebhooks,
+		TemplatesDir:          DefaultTemplatesDir,
+		TLSCert:               DefaultTLSCert,
+		TLSKey:                DefaultTLSKey,
+		TLSClientCA:           DefaultTLSClientCA,
+		AuthTokens:            DefaultAuthTokens,
+		AuthTokensDB:          DefaultAuthTokensDB,
+		AuthRequireRead:       DefaultAuthRequireRead,
+		Key:                   DefaultKey,
+		SignatureWindow:       DefaultSignatureWindow,
+		SignatureKeys:         DefaultSignatureKeys,
+		CryptoKey:             DefaultCryptoKey,
+		MaxBodySize:           DefaultMaxBodySize,
+		MaxDecompressedSize:   DefaultMaxDecompressedSize,
+		RateLimit:             DefaultRateLimit,
+		RateBurst:             DefaultRateBurst,
+		SelfMetricsInterval:   DefaultSelfMetricsInterval,
+		ShutdownDelay:         DefaultShutdownDelay,
+		ShutdownTimeout:       DefaultShutdownTimeout,
+		DebugAddr:             DefaultDebugAddr,
+		DebugToken:            DefaultDebugToken,
+		LogLevel:              DefaultLogLevel,
+		LogFormat:             DefaultLogFormat,
+		LogOutput:             DefaultLogOutput,
+		LogMaxSize:            DefaultLogMaxSize,
+		LogMaxBackups:         DefaultLogMaxBackups,
+		LogSamplingInitial:    DefaultLogSamplingInitial,
+		LogSamplingThereafter: DefaultLogSamplingThereafter,
+		ConfigPath:            DefaultConfig,
+	}
+
+	sources, err := config.Load(fs, args, &cfg)
+	if sources == nil {
+		return nil, err
+	}
+	cfg.sources = sources
+
+	if err := errors.Join(err, validateConfig(&cfg)); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// Dump выводит итоговую конфигурацию со скрытыми секретами и источниками значений в формате YAML.
+func (cfg *Config) Dump(w io.Writer) error {
+	c := cfg.Redacted()
+	return config.Dump(w, &c, cfg.sources)
+}
+
+// validateConfig проверяет конфигурацию и возвращает все найденные ошибки.
+func validateConfig(cfg *Config) error {
+	var errList []error
+
+	// Проверка конфига.
+	if cfg.Addr == "" {
+		errList = append(errList, errors.New("адрес сервера не может быть пустым"))
 	}
 
-	// Перезаписываем значениями из переменных окружения
-	if err := env.Parse(&cfg); err != nil {
-		return nil, fmt.Errorf("ошибка парсинга env: %w", err)
+	if cfg.FileStoragePath == "" {
+		errList = append(errList, errors.New("путь до файла сохранения данных не может быть пустым"))
 	}
 
-	// Перезаписываем значениями флагов (если они были переданы)
-	if flag.Lookup("a").Value.String() != DefaultAddr {
-		cfg.Addr = *addr
+	if cfg.AlertRules != "" && cfg.AlertInterval == 0 {
+		errList = append(errList, errors.New("интервал вычисления правил оповещений не может быть равен 0"))
 	}
-	if flag.Lookup("i").Value.String() != fmt.Sprint(DefaultStoreInterval) {
-		cfg.StoreInterval = *storeInterval
+
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		errList = append(errList, errors.New("сертификат и ключ TLS должны быть заданы вместе"))
 	}
-	if flag.Lookup("f").Value.String() != fmt.Sprint(DefaultFileStoragePath) {
-		cfg.FileStoragePath = *fileStoragePath
+
+	if cfg.TLSClientCA != "" && cfg.TLSCert == "" {
+		errList = append(errList, errors.New("проверка сертификатов агентов требует сертификата TLS сервера"))
 	}
-	if flag.Lookup("r").Value.String() != fmt.Sprint(DefaultRestore) {
-		cfg.Restore = *restore
+
+	if (cfg.Key != "" || cfg.SignatureKeys != "") && cfg.SignatureWindow == 0 {
+		errList = append(errList, errors.New("допустимое отклонение времени подписи запроса не может быть равно 0"))
 	}
-	if flag.Lookup("d").Value.String() != DefaultDatabaseDSN {
-		cfg.DatabaseDSN = *databaseDSN
+
+	if cfg.AuthTokens != "" && cfg.AuthTokensDB {
+		errList = append(errList, errors.New("токены агентов должны храниться либо в файле, либо в базе данных"))
 	}
-	if flag.Lookup("k").Value.String() != DefaultKey {
-		cfg.Key = *key
+
+	if cfg.AuthTokensDB && cfg.DatabaseDSN == "" {
+		errList = append(errList, errors.New("хранение токенов агентов в базе данных требует строки подключения к базе данных"))
 	}
-	if flag.Lookup("crypto-key").Value.String() != DefaultCryptoKey {
-		cfg.CryptoKey = *cryptoKey
+
+	if cfg.AuthRequireRead && !cfg.UseAuth() {
+		errList = append(errList, errors.New("проверка прав на чтение метрик требует хранилища токенов агентов"))
 	}
 
-	// Валидация
-	if err := validateConfig(&cfg); err != nil {
-		return nil, err
+	if cfg.MaxBodySize > math.MaxInt64 || cfg.MaxDecompressedSize > math.MaxInt64 {
+		errList = append(errList, errors.New("максимальный размер тела запроса слишком велик"))
 	}
 
-	return &cfg, nil
-}
+	if cfg.RateLimit < 0 {
+		errList = append(errList, errors.New("допустимая частота запросов не может быть отрицательной"))
+	}
 
-// loadConfigFromJSON загружает конфигурацию из JSON-файла.
-func loadConfigFromJSON(path string, cfg *Config) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return fmt.Errorf("не удалось открыть файл конфигурации: %w", err)
+	if cfg.RateLimit > 0 && (cfg.RateBurst == 0 || cfg.RateBurst > math.MaxInt32) {
+		errList = append(errList, errors.New("допустимый всплеск запросов должен быть от 1 до 2147483647"))
 	}
-	defer func() { _ = file.Close() }()
 
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(cfg); err != nil {
-		return fmt.Errorf("ошибка декодирования JSON: %w", err)
+	if cfg.ShutdownTimeout == 0 {
+		errList = append(errList, errors.New("время ожидания завершения запросов при остановке сервера не может быть равно 0"))
 	}
-	return nil
-}
 
-// valiadteConfig - проверка конфига на корректность.
-func validateConfig(cfg *Config) error {
-	// Проверка конфига.
-	if cfg.Addr == "" {
-		return errors.New("адрес сервера не может быть пустым")
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		errList = append(errList, fmt.Errorf("неизвестный уровень логгирования: %s", cfg.LogLevel))
 	}
 
-	if cfg.FileStoragePath == "" {
-		return errors.New("путь до файла сохранения данных не может быть пустым")
+	if !logging.IsFormat(cfg.LogFormat) {
+		errList = append(errList, fmt.Errorf("неизвестный формат логов: %s", cfg.LogFormat))
+	}
+
+	if cfg.LogOutput == "" {
+		errList = append(errList, errors.New("вывод логов не может быть пустым"))
+	}
+
+	if cfg.DebugAddr != "" && cfg.DebugToken == "" && !debugserver.IsLoopback(cfg.DebugAddr) {
+		errList = append(errList, errors.New("отладочный сервер на внешнем адресе требует токена доступа"))
 	}
 
-	return nil
+	return errors.Join(errList...)
 }
@@ -0,0 +1,53 @@
+package conf
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parse парсит конфигурацию сервера из аргументов args.
+func parse(args ...string) (*Config, error) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return parseConfig(fs, args)
+}
+
+func TestParseConfigYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("address: 0.0.0.0:9090\nrestore: false\ndatabase_dsn: postgres://monit:secret@db/monit\n"), 0o600))
+
+	cfg, err := parse("-config", path, "-r=true")
+	require.NoError(t, err)
+	assert.Equal(t, "0.0.0.0:9090", cfg.Addr)
+	assert.True(t, cfg.Restore)
+	assert.Equal(t, DefaultStoreInterval, cfg.StoreInterval)
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.Dump(&buf))
+	assert.Contains(t, buf.String(), "address: 0.0.0.0:9090 # file "+path)
+	assert.Contains(t, buf.String(), "restore: true # flag -r")
+	assert.Contains(t, buf.String(), "database_dsn: '[REDACTED]'")
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	t.Setenv("RATE_LIMIT", "often")
+
+	_, err := parse("-a", "", "-shutdown-timeout", "0", "-log-format", "xml")
+	require.Error(t, err)
+	for _, msg := range []string{
+		"RATE_LIMIT",
+		"адрес сервера не может быть пустым",
+		"время ожидания завершения запросов при остановке сервера не может быть равно 0",
+		"неизвестный формат логов: xml",
+	} {
+		assert.ErrorContains(t, err, msg)
+	}
+}
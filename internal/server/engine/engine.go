@@ -3,34 +3,148 @@ package engine
 
 import (
 	"fmt"
-	"path/filepath"
-	"runtime"
-	"strings"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/alerting"
+	"github.com/gitslim/monit/internal/auth"
 	"github.com/gitslim/monit/internal/handlers"
+	"github.com/gitslim/monit/internal/health"
 	"github.com/gitslim/monit/internal/logging"
 	"github.com/gitslim/monit/internal/middleware"
+	"github.com/gitslim/monit/internal/security"
 	"github.com/gitslim/monit/internal/server/conf"
 	"github.com/gitslim/monit/internal/services"
+	"github.com/gitslim/monit/templates"
 )
 
-func getTemplateGlob() (string, error) {
-	// Получаем путь к файлу, из которого вызывается функция.
-	_, file, _, ok := runtime.Caller(0)
-	if !ok {
-		return "", fmt.Errorf("failed to get runtime caller file path")
+// templatesFS возвращает файловую систему с шаблонами веб-интерфейса:
+// каталог из конфигурации, если он задан, иначе встроенные в бинарный файл шаблоны.
+func templatesFS(cfg *conf.Config) (fs.FS, error) {
+	if cfg.TemplatesDir == "" {
+		return templates.FS, nil
+	}
+	info, err := os.Stat(cfg.TemplatesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open templates dir: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("templates dir %q is not a directory", cfg.TemplatesDir)
+	}
+	return os.DirFS(cfg.TemplatesDir), nil
+}
+
+// loadTemplates загружает HTML-шаблоны и подключает раздачу статических файлов веб-интерфейса из fsys.
+// Если в fsys нет каталога static, используются встроенные статические файлы.
+func loadTemplates(r *gin.Engine, fsys fs.FS) error {
+	t, err := template.New("").Funcs(r.FuncMap).ParseFS(fsys, "*.html")
+	if err != nil {
+		return fmt.Errorf("failed to parse templates: %w", err)
+	}
+	r.SetHTMLTemplate(t)
+
+	if _, err := fs.Stat(fsys, "static"); err != nil {
+		fsys = templates.FS
+	}
+	static, err := fs.Sub(fsys, "static")
+	if err != nil {
+		return fmt.Errorf("failed to open static files: %w", err)
+	}
+	r.StaticFS("/static", http.FS(static))
+	return nil
+}
+
+// Routes содержит группы роутов сервера с разными правами доступа.
+type Routes struct {
+	Read  *gin.RouterGroup // роуты чтения метрик
+	Write *gin.RouterGroup // роуты записи метрик
+	Admin *gin.RouterGroup // административные роуты
+}
+
+// engineOptions содержит параметры опциональных подсистем сервера.
+type engineOptions struct {
+	tokens auth.TokenStore
+	health *health.Checker
+	routes []func(rt *Routes)
+}
+
+// EngineConf дополнительный конфиг для Gin engine, подключающий опциональные подсистемы сервера.
+type EngineConf func(o *engineOptions) error
+
+// WithTokenStore включает аутентификацию по токенам агентов из хранилища store.
+func WithTokenStore(store auth.TokenStore) EngineConf {
+	return func(o *engineOptions) error {
+		o.tokens = store
+		return nil
+	}
+}
+
+// WithHealth подключает проверку готовности сервера checker к роуту /readyz.
+// Пока сервер запускается, запросы на запись метрик отклоняются.
+func WithHealth(checker *health.Checker) EngineConf {
+	return func(o *engineOptions) error {
+		o.health = checker
+		return nil
+	}
+}
+
+// WithAlerts подключает к Gin engine роут списка оповещений.
+func WithAlerts(log *logging.Logger, alertEngine *alerting.Engine) EngineConf {
+	return func(o *engineOptions) error {
+		alertHandler := handlers.NewAlertHandler(log, alertEngine)
+		o.routes = append(o.routes, func(rt *Routes) {
+			rt.Read.GET("/api/alerts", alertHandler.ListAlerts)
+		})
+		return nil
+	}
+}
+
+// newRoutes создает группы роутов с проверкой прав доступа.
+//
+// При заданных ключах подписи запросы записи метрик должны быть подписаны, подпись запросов
+// чтения проверяется, только если она передана.
+// При включенном mTLS записывать метрики могут только агенты с проверенным сертификатом.
+// При заданном хранилище токенов запись требует права write, административные роуты - права admin,
+// а чтение - права read, если это включено в конфигурации.
+func newRoutes(r *gin.Engine, cfg *conf.Config, log *logging.Logger, tokens auth.TokenStore) *Routes {
+	rt := &Routes{
+		Read:  r.Group("/"),
+		Write: r.Group("/"),
+		Admin: r.Group("/"),
+	}
+
+	if cfg.Key != "" || cfg.SignatureKeys != "" {
+		rt.Write.Use(middleware.RequireSignature(log))
+	}
+
+	if cfg.TLSClientCA != "" {
+		log.Debug("Using client certificate middleware")
+		rt.Write.Use(middleware.ClientCertMiddleware(log))
 	}
 
-	dir := filepath.Dir(file)
-	// Получаем путь к папке с шаблонами.
-	parts := strings.Split(dir, "/")
-	dir = strings.Join(parts[:len(parts)-3], "/")
-	return fmt.Sprintf("%s/templates/*", dir), nil
+	if tokens != nil {
+		rt.Write.Use(middleware.RequireScope(auth.ScopeWrite))
+		rt.Admin.Use(middleware.RequireScope(auth.ScopeAdmin))
+		if cfg.AuthRequireRead {
+			rt.Read.Use(middleware.RequireScope(auth.ScopeRead))
+		}
+	}
+	return rt
 }
 
 // CreateGinEngine создает и настраивает Gin engine с использованием конфигурации, логгера, режима Gin и шаблонов HTML.
-func CreateGinEngine(cfg *conf.Config, log *logging.Logger, ginMode string, metricService *services.MetricService) (g *gin.Engine, e error) {
+func CreateGinEngine(cfg *conf.Config, log *logging.Logger, ginMode string, metricService *services.MetricService, confs ...EngineConf) (g *gin.Engine, e error) {
+	var opts engineOptions
+	for _, c := range confs {
+		if err := c(&opts); err != nil {
+			return nil, err
+		}
+	}
+
 	// Создаем gin engine.
 	gin.SetMode(ginMode)
 	r := gin.New()
@@ -43,39 +157,99 @@ func CreateGinEngine(cfg *conf.Config, log *logging.Logger, ginMode string, metr
 	}()
 
 	// Middlewares.
-	r.Use(middleware.GzipMiddleware())
+	// Размер тела ограничивается до и после распаковки, а частота запросов - до расшифровки,
+	// чтобы отклонять лишние запросы как можно раньше.
+	r.Use(middleware.LoggerMiddleware(log))
+	reg := metricService.SelfMetricsRegistry()
+	if reg != nil {
+		log.Debug("Using self metrics middleware")
+		r.Use(middleware.MetricsMiddleware(reg))
+	}
+	r.Use(middleware.BodyLimitMiddleware(int64(cfg.MaxBodySize)))
+	r.Use(middleware.CompressionMiddleware())
+	r.Use(middleware.BodyLimitMiddleware(int64(cfg.MaxDecompressedSize)))
+	if opts.tokens != nil {
+		log.Debug("Using auth middleware")
+		r.Use(middleware.AuthMiddleware(log, opts.tokens))
+	}
+	if cfg.RateLimit > 0 {
+		log.Debug("Using rate limit middleware")
+		r.Use(middleware.RateLimitMiddleware(log, cfg.RateLimit, int(cfg.RateBurst)))
+	}
 	if cfg.CryptoKey != "" {
 		log.Debug("Using decrypt middleware")
-		dmw, err := middleware.DecryptMiddleware(cfg.CryptoKey)
+		dmw, err := middleware.DecryptMiddleware(cfg.CryptoKeys()...)
 		if err != nil {
 			return nil, err
 		}
 		r.Use(dmw)
 	}
-	r.Use(middleware.LoggerMiddleware(log))
-	if cfg.Key != "" {
+	if cfg.Key != "" || cfg.SignatureKeys != "" {
 		log.Debug("Using signature middleware")
-		r.Use(middleware.SignatureMiddleware(log, cfg.Key))
+		sigCfg := middleware.SignatureConfig{
+			Key:    cfg.Key,
+			Window: time.Duration(cfg.SignatureWindow),
+		}
+		if cfg.SignatureKeys != "" {
+			keys, err := security.LoadEd25519PublicKeys(cfg.SignatureKeys)
+			if err != nil {
+				return nil, err
+			}
+			log.Debugf("Loaded %d agent public keys", len(keys))
+			sigCfg.PublicKeys = keys
+		}
+		r.Use(middleware.SignatureMiddleware(log, sigCfg))
 	}
 
 	// Загрузка шаблонов HTML.
-	t, err := getTemplateGlob()
+	fsys, err := templatesFS(cfg)
 	if err != nil {
 		return nil, err
 	}
-	r.LoadHTMLGlob(t)
+	if cfg.TemplatesDir != "" {
+		log.Debug("Using templates from directory", "dir", cfg.TemplatesDir)
+	}
+	if err := loadTemplates(r, fsys); err != nil {
+		return nil, err
+	}
 
 	// Создание хендлера.
-	metricHandler := handlers.NewMetricHandler(metricService)
-
-	// Роуты.
-	r.GET("/", metricHandler.ListMetrics)
-	r.POST("/update/", metricHandler.UpdateMetric)
-	r.POST("/updates/", metricHandler.BatchUpdateMetrics)
-	r.POST("/value/", metricHandler.GetMetric)
-	r.GET("/value/:type/:name", metricHandler.GetMetric)
-	r.POST("/update/:type/:name/:value", metricHandler.UpdateMetric)
+	metricHandler := handlers.NewMetricHandler(log, metricService)
+
+	rt := newRoutes(r, cfg, log, opts.tokens)
+	if opts.health != nil {
+		rt.Write.Use(middleware.ReadinessMiddleware(opts.health))
+	}
+
+	// Роуты записи метрик.
+	rt.Write.POST("/update/", metricHandler.UpdateMetric)
+	rt.Write.POST("/updates/", metricHandler.BatchUpdateMetrics)
+	rt.Write.POST("/update/:type/:name/:value", metricHandler.UpdateMetric)
+
+	// Роуты чтения метрик.
+	rt.Read.GET("/", metricHandler.ListMetrics)
+	rt.Read.POST("/value/", metricHandler.GetMetric)
+	rt.Read.GET("/value/:type/:name", metricHandler.GetMetric)
+	rt.Read.GET("/api/metrics", metricHandler.QueryMetrics)
+	rt.Read.GET("/api/aggregate/:type/:name", metricHandler.AggregateMetric)
+	rt.Read.GET("/stream", metricHandler.StreamMetrics)
+
 	r.GET("/ping", metricHandler.PingStorage)
 
-	return r, err
+	// Проверки работоспособности и готовности сервера.
+	healthHandler := handlers.NewHealthHandler(opts.health)
+	r.GET("/healthz", healthHandler.Healthz)
+	r.GET("/readyz", healthHandler.Readyz)
+
+	// Административные роуты.
+	if reg != nil {
+		rt.Admin.GET("/api/admin/metrics", metricHandler.SelfMetrics)
+	}
+
+	// Опциональные подсистемы.
+	for _, f := range opts.routes {
+		f(rt)
+	}
+
+	return r, nil
 }
@@ -2,16 +2,34 @@
 package engine_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/auth"
+	"github.com/gitslim/monit/internal/compression"
+	"github.com/gitslim/monit/internal/config"
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/health"
 	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/security"
+	"github.com/gitslim/monit/internal/selfmetrics"
 	"github.com/gitslim/monit/internal/server/conf"
 	"github.com/gitslim/monit/internal/server/engine"
 	"github.com/gitslim/monit/internal/services"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateGinEngine(t *testing.T) {
@@ -67,3 +85,538 @@ func TestCreateGinEngine(t *testing.T) {
 		})
 	}
 }
+
+// TestDashboard тестирует отображение панели метрик из встроенных шаблонов и статических файлов.
+func TestDashboard(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	cfg := &conf.Config{FileStoragePath: t.TempDir() + "/memstorage.json"}
+
+	svcCfg, err := services.WithMemStorage(context.Background(), log, cfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+	require.NoError(t, metricService.UpdateMetric("", "Alloc", "gauge", "12.5"))
+	require.NoError(t, metricService.UpdateMetric("", "PollCount", "counter", "3"))
+
+	r, err := engine.CreateGinEngine(cfg, log, gin.TestMode, metricService)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `<section class="group" data-type="gauge">`)
+	assert.Contains(t, w.Body.String(), `<tr data-name="Alloc" data-value="12.5">`)
+	assert.Contains(t, w.Body.String(), `<tr data-name="PollCount" data-value="3">`)
+
+	for _, path := range []string{"/static/dashboard.js", "/static/dashboard.css"} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+}
+
+// TestTemplatesDir тестирует замену встроенных шаблонов шаблонами из каталога.
+func TestTemplatesDir(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "metrics.html"),
+		[]byte(`custom{{ range .groups }} {{ .Type }}{{ end }}`), 0o600))
+	cfg := &conf.Config{FileStoragePath: filepath.Join(dir, "memstorage.json"), TemplatesDir: dir}
+
+	svcCfg, err := services.WithMemStorage(context.Background(), log, cfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+
+	r, err := engine.CreateGinEngine(cfg, log, gin.TestMode, metricService)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "custom gauge counter", w.Body.String())
+
+	// Статические файлы отсутствуют в каталоге, поэтому используются встроенные.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/dashboard.js", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	cfg.TemplatesDir = filepath.Join(dir, "missing")
+	_, err = engine.CreateGinEngine(cfg, log, gin.TestMode, metricService)
+	assert.Error(t, err)
+}
+
+// TestTokenAuth тестирует проверку токенов агентов и запись агента, последним обновившего метрику.
+func TestTokenAuth(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	cfg := &conf.Config{
+		FileStoragePath: filepath.Join(t.TempDir(), "memstorage.json"),
+		AuthTokens:      "../../../testdata/config/auth_tokens.json",
+		AuthRequireRead: true,
+	}
+
+	svcCfg, err := services.WithMemStorage(context.Background(), log, cfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+
+	store, err := auth.NewFileTokenStore(cfg.AuthTokens)
+	require.NoError(t, err)
+	r, err := engine.CreateGinEngine(cfg, log, gin.TestMode, metricService, engine.WithTokenStore(store))
+	require.NoError(t, err)
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{name: "write without token", method: http.MethodPost, path: "/update/gauge/g1/1", want: http.StatusUnauthorized},
+		{name: "write with unknown token", method: http.MethodPost, path: "/update/gauge/g1/1", token: "unknown", want: http.StatusUnauthorized},
+		{name: "write with revoked token", method: http.MethodPost, path: "/update/gauge/g1/1", token: "old-agent-token", want: http.StatusUnauthorized},
+		{name: "write with read token", method: http.MethodPost, path: "/update/gauge/g1/1", token: "dashboard-token", want: http.StatusForbidden},
+		{name: "write with write token", method: http.MethodPost, path: "/update/gauge/g1/1", token: "agent-1-token", want: http.StatusOK},
+		{name: "read without token", method: http.MethodGet, path: "/value/gauge/g1", want: http.StatusUnauthorized},
+		{name: "read with write token", method: http.MethodGet, path: "/value/gauge/g1", token: "agent-1-token", want: http.StatusForbidden},
+		{name: "read with read token", method: http.MethodGet, path: "/value/gauge/g1", token: "dashboard-token", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, do(tt.method, tt.path, tt.token).Code)
+		})
+	}
+
+	u, ok := metricService.LastUpdate("g1", entities.Gauge)
+	require.True(t, ok)
+	assert.Equal(t, "agent-1", u.Agent)
+
+	w := do(http.MethodGet, "/api/metrics", "dashboard-token")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"updated_by":"agent-1"`)
+}
+
+// TestSignature тестирует проверку подписи запросов и защиту от их повторной отправки.
+func TestSignature(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	cfg := &conf.Config{
+		FileStoragePath: filepath.Join(t.TempDir(), "memstorage.json"),
+		Key:             "secret",
+		SignatureWindow: config.Seconds(60),
+	}
+
+	svcCfg, err := services.WithMemStorage(context.Background(), log, cfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+	r, err := engine.CreateGinEngine(cfg, log, gin.TestMode, metricService)
+	require.NoError(t, err)
+
+	body := []byte(`{"id":"g1","type":"gauge","value":1.5}`)
+	signed := func(key, nonce string, ts time.Time) *http.Request {
+		sr := security.SignedRequest{Timestamp: ts, Nonce: nonce, Method: http.MethodPost, Path: "/update/", Body: body}
+		req := httptest.NewRequest(http.MethodPost, "/update/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("HashSHA256", sr.Sign(key))
+		req.Header.Set("X-Signature-Timestamp", strconv.FormatInt(ts.Unix(), 10))
+		req.Header.Set("X-Signature-Nonce", nonce)
+		return req
+	}
+	unsigned := httptest.NewRequest(http.MethodPost, "/update/", bytes.NewReader(body))
+	unsigned.Header.Set("Content-Type", "application/json")
+	unsignedRead := httptest.NewRequest(http.MethodPost, "/value/", strings.NewReader(`{"id":"g1","type":"gauge"}`))
+	unsignedRead.Header.Set("Content-Type", "application/json")
+
+	now := time.Now()
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{name: "valid", req: signed("secret", "n1", now), want: http.StatusOK},
+		{name: "replay", req: signed("secret", "n1", now), want: http.StatusBadRequest},
+		{name: "wrong key", req: signed("other", "n2", now), want: http.StatusBadRequest},
+		{name: "stale", req: signed("secret", "n3", now.Add(-2*time.Minute)), want: http.StatusBadRequest},
+		{name: "future", req: signed("secret", "n4", now.Add(2*time.Minute)), want: http.StatusBadRequest},
+		{name: "unsigned write", req: unsigned, want: http.StatusBadRequest},
+		{name: "unsigned read", req: httptest.NewRequest(http.MethodGet, "/value/gauge/g1", nil), want: http.StatusOK},
+		{name: "unsigned json read", req: unsignedRead, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, tt.req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+
+	// Обработчик получает восстановленное тело подписанного запроса.
+	m, err := metricService.GetMetric("g1", "gauge")
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, m.GetValue())
+}
+
+// TestBodyLimit тестирует ограничение размера тела запроса до и после распаковки.
+func TestBodyLimit(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	cfg := &conf.Config{
+		FileStoragePath:     filepath.Join(t.TempDir(), "memstorage.json"),
+		MaxBodySize:         1024,
+		MaxDecompressedSize: 4096,
+	}
+
+	svcCfg, err := services.WithMemStorage(context.Background(), log, cfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+	r, err := engine.CreateGinEngine(cfg, log, gin.TestMode, metricService)
+	require.NoError(t, err)
+
+	// Батч из одной метрики с длинным именем, хорошо сжимаемый gzip.
+	batch := func(nameLen int) []byte {
+		return []byte(`[{"id":"` + strings.Repeat("g", nameLen) + `","type":"gauge","value":1}]`)
+	}
+	compressed := func(data []byte) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name string
+		body []byte
+		gzip bool
+		want int
+	}{
+		{name: "small", body: batch(100), want: http.StatusOK},
+		{name: "too large", body: batch(2048), want: http.StatusRequestEntityTooLarge},
+		{name: "small compressed", body: compressed(batch(2048)), gzip: true, want: http.StatusOK},
+		{name: "too large decompressed", body: compressed(batch(64 << 10)), gzip: true, want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+
+	// Тело без Content-Length ограничивается при чтении.
+	req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(batch(2048)))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+// TestRateLimit тестирует ограничение частоты запросов клиентов.
+func TestRateLimit(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	cfg := &conf.Config{
+		FileStoragePath: filepath.Join(t.TempDir(), "memstorage.json"),
+		AuthTokens:      "../../../testdata/config/auth_tokens.json",
+		RateLimit:       0.5,
+		RateBurst:       2,
+	}
+
+	svcCfg, err := services.WithMemStorage(context.Background(), log, cfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+	store, err := auth.NewFileTokenStore(cfg.AuthTokens)
+	require.NoError(t, err)
+	r, err := engine.CreateGinEngine(cfg, log, gin.TestMode, metricService, engine.WithTokenStore(store))
+	require.NoError(t, err)
+
+	do := func(remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/value/gauge/g1", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Всплеск из двух запросов разрешен, третий отклоняется.
+	assert.Equal(t, http.StatusNotFound, do("10.0.0.1:1234", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("10.0.0.1:1234", "").Code)
+	w := do("10.0.0.1:1234", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	// Лимиты других клиентов не затронуты: другой IP-адрес и агент с токеном с того же адреса.
+	assert.Equal(t, http.StatusNotFound, do("10.0.0.2:1234", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("10.0.0.1:1234", "dashboard-token").Code)
+}
+
+// TestRequestID тестирует прием и генерацию идентификатора запроса.
+func TestRequestID(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	cfg := &conf.Config{FileStoragePath: filepath.Join(t.TempDir(), "memstorage.json")}
+
+	svcCfg, err := services.WithMemStorage(context.Background(), log, cfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+	r, err := engine.CreateGinEngine(cfg, log, gin.TestMode, metricService)
+	require.NoError(t, err)
+
+	do := func(id string) string {
+		req := httptest.NewRequest(http.MethodPost, "/update/gauge/g1/1", nil)
+		if id != "" {
+			req.Header.Set("X-Request-ID", id)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Header().Get("X-Request-ID")
+	}
+
+	assert.Equal(t, "agent-req-1", do("agent-req-1"))
+
+	generated := do("")
+	assert.Len(t, generated, 32)
+	assert.NotEqual(t, generated, do(""))
+
+	// Некорректный идентификатор заменяется сгенерированным.
+	replaced := do("bad id\n")
+	assert.NotEqual(t, "bad id\n", replaced)
+	assert.Len(t, replaced, 32)
+}
+
+// TestCompression тестирует распаковку запросов и выбор алгоритма сжатия ответа.
+func TestCompression(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	cfg := &conf.Config{FileStoragePath: filepath.Join(t.TempDir(), "memstorage.json")}
+
+	svcCfg, err := services.WithMemStorage(context.Background(), log, cfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+	r, err := engine.CreateGinEngine(cfg, log, gin.TestMode, metricService)
+	require.NoError(t, err)
+
+	for i, name := range compression.Names() {
+		t.Run("request "+name, func(t *testing.T) {
+			codec, ok := compression.Lookup(name)
+			require.True(t, ok)
+			var buf bytes.Buffer
+			zw := codec.NewWriter(&buf)
+			_, err := fmt.Fprintf(zw, `[{"id":"g%d","type":"gauge","value":%d}]`, i, i)
+			require.NoError(t, err)
+			require.NoError(t, zw.Close())
+
+			req := httptest.NewRequest(http.MethodPost, "/updates/", &buf)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Encoding", name)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			m, err := metricService.GetMetric(fmt.Sprintf("g%d", i), "gauge")
+			require.NoError(t, err)
+			assert.Equal(t, float64(i), m.GetValue())
+		})
+	}
+
+	t.Run("unsupported request encoding", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader("[]"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "compress")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	})
+
+	t.Run("invalid request stream", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader("not gzip"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	responses := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: ""},
+		{accept: "gzip", want: "gzip"},
+		{accept: "gzip;q=0.5, zstd", want: "zstd"},
+		{accept: "br, deflate;q=0.1", want: "br"},
+		{accept: "identity", want: ""},
+	}
+	for _, tt := range responses {
+		t.Run("response "+tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/metrics", nil)
+			req.Header.Set("Accept-Encoding", tt.accept)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.want, w.Header().Get("Content-Encoding"))
+
+			body := io.Reader(w.Body)
+			if tt.want != "" {
+				codec, ok := compression.Lookup(tt.want)
+				require.True(t, ok)
+				body, err = codec.NewReader(w.Body)
+				require.NoError(t, err)
+			}
+			data, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Contains(t, string(data), `"id":"g0"`)
+		})
+	}
+}
+
+// TestSelfMetricsEndpoint тестирует учет запросов в метриках сервера и административный роут их просмотра.
+func TestSelfMetricsEndpoint(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	cfg := &conf.Config{FileStoragePath: filepath.Join(t.TempDir(), "memstorage.json")}
+
+	svcCfg, err := services.WithMemStorage(context.Background(), log, cfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg, services.WithSelfMetrics(selfmetrics.NewRegistry()))
+	require.NoError(t, err)
+	r, err := engine.CreateGinEngine(cfg, log, gin.TestMode, metricService)
+	require.NoError(t, err)
+
+	for _, path := range []string{"/update/gauge/g1/1", "/update/gauge/monit_server_up/1", "/no/such/route"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `"id":"monit_server_http_requests_total{method=POST,route=/update/:type/:name/:value,status=200}","type":"counter","delta":1`)
+	assert.Contains(t, body, `"id":"monit_server_http_requests_total{method=POST,route=/update/:type/:name/:value,status=403}"`)
+	assert.Contains(t, body, `"id":"monit_server_http_requests_total{method=POST,route=unmatched,status=404}"`)
+	assert.Contains(t, body, `"id":"monit_server_ingested_metrics_total","type":"counter","delta":1`)
+	assert.Contains(t, body, `"id":"monit_server_backup_total"`)
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	cfg := &conf.Config{FileStoragePath: filepath.Join(t.TempDir(), "memstorage.json")}
+
+	svcCfg, err := services.WithMemStorage(context.Background(), log, cfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+
+	checker := health.NewChecker()
+	metricService.RegisterHealthChecks(checker, 0)
+	r, err := engine.CreateGinEngine(cfg, log, gin.TestMode, metricService, engine.WithHealth(checker))
+	require.NoError(t, err)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	// Пока сервер запускается, он жив, но не готов и не принимает метрики.
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/healthz").Code)
+	w := do(http.MethodGet, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"phase":"starting"`)
+	w = do(http.MethodPost, "/update/gauge/g1/1")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	metricService.Start()
+	checker.SetPhase(health.PhaseReady)
+	w = do(http.MethodGet, "/readyz")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"storage":{"status":"ok"`)
+	assert.Contains(t, w.Body.String(), `"backup":{"status":"ok"`)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/update/gauge/g1/1").Code)
+
+	// При остановке сервер не готов, но продолжает обрабатывать запросы.
+	checker.SetPhase(health.PhaseStopping)
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodGet, "/readyz").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/update/gauge/g1/2").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/healthz").Code)
+}
+
+// TestDecryptPassesBodylessRequests тестирует, что при включенном шифровании проверки работоспособности
+// и чтение метрик работают без расшифровки, а тело запросов записи расшифровывается.
+func TestDecryptPassesBodylessRequests(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	cfg := &conf.Config{
+		FileStoragePath: filepath.Join(t.TempDir(), "memstorage.json"),
+		CryptoKey:       "../../../testdata/keys/private.pem",
+	}
+
+	svcCfg, err := services.WithMemStorage(context.Background(), log, cfg, make(chan<- error))
+	require.NoError(t, err)
+	metricService, err := services.NewMetricService(svcCfg)
+	require.NoError(t, err)
+	metricService.Start()
+
+	checker := health.NewChecker()
+	metricService.RegisterHealthChecks(checker, 0)
+	checker.SetPhase(health.PhaseReady)
+	r, err := engine.CreateGinEngine(cfg, log, gin.TestMode, metricService, engine.WithHealth(checker))
+	require.NoError(t, err)
+
+	do := func(req *http.Request) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, path := range []string{"/healthz", "/readyz", "/ping", "/api/metrics", "/static/dashboard.js"} {
+		assert.Equal(t, http.StatusOK, do(httptest.NewRequest(http.MethodGet, path, nil)), path)
+	}
+
+	body := []byte(`{"id":"g1","type":"gauge","value":1.5}`)
+	pub, err := security.ReadRSAPublicKeyFromFile("../../../testdata/keys/public.pem")
+	require.NoError(t, err)
+	encrypted, err := security.EncryptRSA(pub, body)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/update/", bytes.NewReader(encrypted))
+	req.Header.Set("Content-Type", "application/json")
+	assert.Equal(t, http.StatusOK, do(req))
+
+	req = httptest.NewRequest(http.MethodPost, "/update/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	assert.Equal(t, http.StatusBadRequest, do(req))
+}
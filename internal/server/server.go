@@ -3,6 +3,7 @@ package server
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,52 +11,101 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gitslim/monit/internal/health"
 	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/security"
 	"github.com/gitslim/monit/internal/server/conf"
 	"github.com/gitslim/monit/internal/server/engine"
 	"github.com/gitslim/monit/internal/services"
 )
 
 // Start запускает сервер.
-func Start(ctx context.Context, cfg *conf.Config, log *logging.Logger, metricService *services.MetricService) {
+func Start(ctx context.Context, cfg *conf.Config, log *logging.Logger, metricService *services.MetricService, confs ...engine.EngineConf) {
+	// Проверка готовности сервера. Резервная копия считается устаревшей, если пропущено два сохранения подряд.
+	checker := health.NewChecker()
+	metricService.RegisterHealthChecks(checker, 2*time.Duration(cfg.StoreInterval))
+	confs = append(confs, engine.WithHealth(checker))
+
 	// Создание gin engine.
-	r, err := engine.CreateGinEngine(cfg, log, gin.ReleaseMode, metricService)
+	r, err := engine.CreateGinEngine(cfg, log, gin.ReleaseMode, metricService, confs...)
 	if err != nil {
 		log.Fatalf("Failed to create gin engine: %v\n", err)
 	}
 
+	// Базовый контекст запросов отменяется при остановке сервера,
+	// чтобы долгоживущие потоки обновлений метрик завершались.
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+
 	// Создаем сервер.
 	srv := &http.Server{
-		Addr:    cfg.Addr,
-		Handler: r,
+		Addr:        cfg.Addr,
+		Handler:     r,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+	srv.RegisterOnShutdown(cancelBase)
+
+	if cfg.UseTLS() {
+		srv.TLSConfig, err = security.ServerTLSConfig(cfg.TLSCert, cfg.TLSKey, cfg.TLSClientCA)
+		if err != nil {
+			log.Fatalf("Failed to configure TLS: %v\n", err)
+		}
 	}
 
+	// Канал для получения сигналов. Подписка выполняется до восстановления данных,
+	// чтобы сигнал во время запуска тоже приводил к корректной остановке.
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer signal.Stop(quit)
+
 	// Запуск сервера в горутине.
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if cfg.UseTLS() {
+			// Сертификат уже загружен в srv.TLSConfig.
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed to start: %v\n", err)
 		}
 	}()
 
-	log.Infof("Server is running on %v\n", cfg.Addr)
+	log.Infof("Server is running on %v (tls: %v, mtls: %v)\n", cfg.Addr, cfg.UseTLS(), cfg.TLSClientCA != "")
 
-	// Gracefull shutdown.
-	// Таймаут ожидания завершения работы сервера.
-	gracefulTimeout := 5 * time.Second
+	// Восстановление данных выполняется при запущенном сервере, чтобы /readyz сообщал о неготовности.
+	metricService.Start()
+	checker.SetPhase(health.PhaseReady)
+	log.Info("Server is ready")
 
-	// Создаем контекст с тайм-аутом для завершения работы сервера.
-	ctx, cancel := context.WithTimeout(ctx, gracefulTimeout)
-	defer cancel()
+	// Сервер останавливается по сигналу или при отмене контекста, например после ошибки резервного копирования.
+	select {
+	case sig := <-quit:
+		log.Infof("Received signal: %v, shutting down server...", sig)
+	case <-ctx.Done():
+		log.Info("Context canceled, shutting down server...")
+	}
 
-	// Канал для получения сигналов.
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
-	<-quit
-	log.Info("Shutting down server...")
+	// Балансировщику нагрузки дается время заметить неготовность сервера и перестать направлять на него запросы.
+	checker.SetPhase(health.PhaseStopping)
+	if cfg.ShutdownDelay > 0 {
+		delay := time.Duration(cfg.ShutdownDelay)
+		log.Infof("Waiting %v before shutdown", delay)
+		time.Sleep(delay)
+	}
+
+	// Сервер перестает принимать соединения и ожидает завершения обрабатываемых запросов.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("Server forced to shutdown: %v", err)
+		_ = srv.Close()
+	}
 
-	// Инициируем graceful shutdown.
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown:", err)
+	// После завершения запросов сохраняется последняя резервная копия и закрываются соединения с хранилищем.
+	if err := metricService.Close(); err != nil {
+		log.Errorf("Storage close failed: %v", err)
 	}
 
 	log.Info("Monit server stopped")
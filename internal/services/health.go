@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gitslim/monit/internal/health"
+	"github.com/gitslim/monit/internal/storage"
+)
+
+// RegisterHealthChecks добавляет в h проверки хранилища метрик: соединения, резервного копирования
+// и схемы базы данных, если хранилище их поддерживает.
+// Резервная копия считается устаревшей, если последнее успешное сохранение было раньше backupMaxAge назад,
+// нулевое значение отключает проверку.
+func (s *MetricService) RegisterHealthChecks(h *health.Checker, backupMaxAge time.Duration) {
+	h.Register("storage", func(context.Context) error {
+		return s.storage.Ping()
+	})
+
+	if b, ok := s.storage.(storage.BackupStatter); ok {
+		h.Register("backup", func(context.Context) error {
+			return b.BackupStat().LastError
+		})
+
+		if backupMaxAge > 0 {
+			// До первого сохранения возраст копии отсчитывается от регистрации проверки.
+			registered := time.Now()
+			h.Register("backup_freshness", func(context.Context) error {
+				last := b.BackupStat().LastSuccess
+				if last.IsZero() {
+					last = registered
+				}
+				if age := time.Since(last); age > backupMaxAge {
+					return fmt.Errorf("last backup is %s old", age.Round(time.Second))
+				}
+				return nil
+			})
+		}
+	}
+
+	if sc, ok := s.storage.(storage.SchemaChecker); ok {
+		h.Register("schema", sc.CheckSchema)
+	}
+}
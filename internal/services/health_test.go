@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gitslim/monit/internal/health"
+	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingWriter io.Writer, запись в который всегда завершается ошибкой.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+// TestHealthChecks тестирует проверки хранилища в памяти.
+func TestHealthChecks(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	stor := storage.NewMemStorage(log, true, failingWriter{})
+	svc, err := NewMetricService(WithStorage(stor))
+	require.NoError(t, err)
+
+	h := health.NewChecker()
+	svc.RegisterHealthChecks(h, time.Minute)
+	h.SetPhase(health.PhaseReady)
+
+	report := h.Check(context.Background())
+	assert.True(t, report.Ready())
+	assert.Equal(t, health.StatusOK, report.Components["storage"].Status)
+	assert.Equal(t, health.StatusOK, report.Components["backup"].Status)
+	assert.Equal(t, health.StatusOK, report.Components["backup_freshness"].Status)
+	assert.NotContains(t, report.Components, "schema")
+
+	// Ошибка сохранения резервной копии делает сервер неготовым.
+	require.Error(t, svc.UpdateMetric("", "Alloc", "gauge", "1"))
+	report = h.Check(context.Background())
+	assert.False(t, report.Ready())
+	assert.Equal(t, "disk full", report.Components["backup"].Error)
+}
+
+// TestHealthChecksStaleBackup тестирует проверку свежести резервной копии.
+func TestHealthChecksStaleBackup(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	svc, err := NewMetricService(WithStorage(storage.NewMemStorage(log, false, nil)))
+	require.NoError(t, err)
+
+	h := health.NewChecker()
+	svc.RegisterHealthChecks(h, time.Nanosecond)
+	h.SetPhase(health.PhaseReady)
+	time.Sleep(time.Millisecond)
+
+	report := h.Check(context.Background())
+	assert.False(t, report.Ready())
+	assert.Equal(t, health.StatusFail, report.Components["backup_freshness"].Status)
+	assert.Contains(t, report.Components["backup_freshness"].Error, "last backup is")
+}
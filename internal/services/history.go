@@ -0,0 +1,139 @@
+package services
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gitslim/monit/internal/entities"
+)
+
+// metricSample значение метрики в момент времени.
+type metricSample struct {
+	ts    time.Time
+	value float64
+}
+
+// metricHistory хранит историю значений метрик в памяти в пределах окна хранения.
+type metricHistory struct {
+	mu        sync.RWMutex
+	samples   map[string][]metricSample
+	retention time.Duration
+	now       func() time.Time
+}
+
+// newMetricHistory создает историю значений метрик с заданным окном хранения.
+func newMetricHistory(retention time.Duration) *metricHistory {
+	return &metricHistory{
+		samples:   make(map[string][]metricSample),
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// historyKey формирует ключ истории метрики.
+func historyKey(mName string, mType entities.MetricType) string {
+	return mType.String() + ":" + mName
+}
+
+// record добавляет значение метрики в историю и удаляет устаревшие значения.
+func (h *metricHistory) record(mName string, mType entities.MetricType, value float64) {
+	key := historyKey(mName, mType)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Время берется под блокировкой, чтобы значения оставались упорядоченными.
+	now := h.now()
+
+	samples := append(h.samples[key], metricSample{ts: now, value: value})
+
+	// Значения добавляются в порядке времени, поэтому устаревшие находятся в начале.
+	cutoff := now.Add(-h.retention)
+	i, _ := slices.BinarySearchFunc(samples, cutoff, func(s metricSample, t time.Time) int {
+		return s.ts.Compare(t)
+	})
+	h.samples[key] = samples[i:]
+}
+
+// aggregate агрегирует значения метрики по интервалам запроса.
+func (h *metricHistory) aggregate(q *entities.AggregateQuery) []*entities.AggregateBucket {
+	h.mu.RLock()
+	var values [][]float64
+	var starts []time.Time
+	for _, s := range h.samples[historyKey(q.Name, q.Type)] {
+		if s.ts.Before(q.From) || !s.ts.Before(q.To) {
+			continue
+		}
+		start := q.From.Add(s.ts.Sub(q.From) / q.Step * q.Step)
+		if len(starts) == 0 || !starts[len(starts)-1].Equal(start) {
+			starts = append(starts, start)
+			values = append(values, nil)
+		}
+		values[len(values)-1] = append(values[len(values)-1], s.value)
+	}
+	h.mu.RUnlock()
+
+	buckets := make([]*entities.AggregateBucket, 0, len(starts))
+	for i, start := range starts {
+		buckets = append(buckets, aggregateBucket(start, values[i], q.Funcs))
+	}
+	return buckets
+}
+
+// aggregateBucket вычисляет значения функций агрегации для значений интервала в порядке их записи.
+func aggregateBucket(start time.Time, values []float64, funcs []entities.AggregateFunc) *entities.AggregateBucket {
+	b := &entities.AggregateBucket{
+		Start:  start,
+		Count:  int64(len(values)),
+		Values: make(map[entities.AggregateFunc]float64, len(funcs)),
+	}
+
+	var sorted []float64
+	for _, f := range funcs {
+		switch f {
+		case entities.AggregateMin:
+			b.Values[f] = slices.Min(values)
+		case entities.AggregateMax:
+			b.Values[f] = slices.Max(values)
+		case entities.AggregateSum:
+			b.Values[f] = sum(values)
+		case entities.AggregateAvg:
+			b.Values[f] = sum(values) / float64(len(values))
+		case entities.AggregateLast:
+			b.Values[f] = values[len(values)-1]
+		default:
+			p, ok := f.Percentile()
+			if !ok {
+				continue
+			}
+			if sorted == nil {
+				sorted = slices.Clone(values)
+				slices.Sort(sorted)
+			}
+			b.Values[f] = percentile(sorted, p)
+		}
+	}
+	return b
+}
+
+// sum возвращает сумму значений.
+func sum(values []float64) float64 {
+	var s float64
+	for _, v := range values {
+		s += v
+	}
+	return s
+}
+
+// percentile вычисляет перцентиль с линейной интерполяцией, как percentile_cont в PostgreSQL.
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lower := math.Floor(pos)
+	i := int(lower)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (sorted[i+1]-sorted[i])*(pos-lower)
+}
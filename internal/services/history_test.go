@@ -0,0 +1,76 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMetricHistoryAggregate тестирует агрегацию истории значений по интервалам.
+func TestMetricHistoryAggregate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+
+	h := newMetricHistory(time.Hour)
+	h.now = func() time.Time { return now }
+
+	// Первая минута: 1, 2, 3, 4; вторая минута пустая; третья минута: 10.
+	for i, v := range []float64{1, 2, 3, 4} {
+		now = start.Add(time.Duration(i*10) * time.Second)
+		h.record("cpu", entities.Gauge, v)
+	}
+	now = start.Add(2*time.Minute + 5*time.Second)
+	h.record("cpu", entities.Gauge, 10)
+	h.record("cpu", entities.Counter, 100)
+
+	buckets := h.aggregate(&entities.AggregateQuery{
+		Name:  "cpu",
+		Type:  entities.Gauge,
+		From:  start,
+		To:    start.Add(time.Hour),
+		Step:  time.Minute,
+		Funcs: []entities.AggregateFunc{"min", "max", "avg", "sum", "last", "p50", "p90"},
+	})
+	require.Len(t, buckets, 2)
+
+	assert.Equal(t, start, buckets[0].Start)
+	assert.Equal(t, int64(4), buckets[0].Count)
+	assert.Equal(t, map[entities.AggregateFunc]float64{
+		"min": 1, "max": 4, "avg": 2.5, "sum": 10, "last": 4, "p50": 2.5, "p90": 3.7,
+	}, roundValues(buckets[0].Values))
+
+	assert.Equal(t, start.Add(2*time.Minute), buckets[1].Start)
+	assert.Equal(t, int64(1), buckets[1].Count)
+	assert.Equal(t, 10.0, buckets[1].Values["p90"])
+}
+
+// TestMetricHistoryRetention тестирует удаление значений за пределами окна хранения.
+func TestMetricHistoryRetention(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+
+	h := newMetricHistory(time.Minute)
+	h.now = func() time.Time { return now }
+
+	h.record("m", entities.Gauge, 1)
+	now = start.Add(30 * time.Second)
+	h.record("m", entities.Gauge, 2)
+	now = start.Add(90 * time.Second)
+	h.record("m", entities.Gauge, 3)
+
+	samples := h.samples[historyKey("m", entities.Gauge)]
+	require.Len(t, samples, 2)
+	assert.Equal(t, 2.0, samples[0].value)
+}
+
+// roundValues округляет значения для сравнения без погрешности вычислений.
+func roundValues(values map[entities.AggregateFunc]float64) map[entities.AggregateFunc]float64 {
+	res := make(map[entities.AggregateFunc]float64, len(values))
+	for k, v := range values {
+		res[k] = float64(int64(v*1e9+0.5)) / 1e9
+	}
+	return res
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gitslim/monit/internal/entities"
+)
+
+// MetricFilter определяет фильтр обновлений метрик для подписки.
+type MetricFilter struct {
+	Names  []string              // точные имена метрик, пустой - все имена
+	Prefix string                // префикс имени метрики
+	Types  []entities.MetricType // типы метрик, пустой - все типы
+}
+
+// match проверяет, проходит ли метрика фильтр.
+func (f *MetricFilter) match(mName string, mType entities.MetricType) bool {
+	if len(f.Names) > 0 && !slices.Contains(f.Names, mName) {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, mType) {
+		return false
+	}
+	return strings.HasPrefix(mName, f.Prefix)
+}
+
+// Subscription подписка на обновления метрик.
+//
+// Обновления не блокируют запись метрик: если подписчик не успевает их читать,
+// обновления одной метрики объединяются и подписчик получает только последнее значение.
+type Subscription struct {
+	filter  MetricFilter
+	mu      sync.Mutex
+	pending map[string]*entities.MetricDTO
+	order   []string
+	notify  chan struct{}
+}
+
+// C возвращает канал, сигнализирующий о наличии новых обновлений.
+func (s *Subscription) C() <-chan struct{} {
+	return s.notify
+}
+
+// Next забирает накопленные обновления в порядке их поступления.
+func (s *Subscription) Next() []*entities.MetricDTO {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updates := make([]*entities.MetricDTO, 0, len(s.order))
+	for _, key := range s.order {
+		updates = append(updates, s.pending[key])
+	}
+	s.pending = make(map[string]*entities.MetricDTO)
+	s.order = s.order[:0]
+	return updates
+}
+
+// push добавляет обновление, объединяя его с еще не прочитанным обновлением той же метрики.
+func (s *Subscription) push(key string, dto *entities.MetricDTO) {
+	s.mu.Lock()
+	if _, ok := s.pending[key]; !ok {
+		s.order = append(s.order, key)
+	}
+	s.pending[key] = dto
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// metricBroker рассылает обновления метрик подписчикам.
+type metricBroker struct {
+	mu    sync.RWMutex
+	subs  map[*Subscription]struct{}
+	count atomic.Int32
+}
+
+// newMetricBroker создает рассыльщик обновлений метрик.
+func newMetricBroker() *metricBroker {
+	return &metricBroker{
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// subscribe создает подписку с заданным фильтром.
+func (b *metricBroker) subscribe(filter MetricFilter) *Subscription {
+	sub := &Subscription{
+		filter:  filter,
+		pending: make(map[string]*entities.MetricDTO),
+		notify:  make(chan struct{}, 1),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	b.count.Add(1)
+
+	return sub
+}
+
+// unsubscribe удаляет подписку.
+func (b *metricBroker) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		b.count.Add(-1)
+	}
+	b.mu.Unlock()
+}
+
+// hasSubscribers проверяет наличие подписчиков.
+func (b *metricBroker) hasSubscribers() bool {
+	return b.count.Load() > 0
+}
+
+// publish рассылает обновление метрики подходящим подписчикам.
+func (b *metricBroker) publish(m entities.Metric) {
+	dto, err := entities.NewMetricDTO(m.GetName(), m.GetType().String(), m.GetValue())
+	if err != nil {
+		return
+	}
+	key := historyKey(m.GetName(), m.GetType())
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		if sub.filter.match(m.GetName(), m.GetType()) {
+			sub.push(key, dto)
+		}
+	}
+}
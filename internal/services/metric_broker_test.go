@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMetricBrokerCoalescing тестирует фильтрацию и объединение обновлений для медленного подписчика.
+func TestMetricBrokerCoalescing(t *testing.T) {
+	b := newMetricBroker()
+	assert.False(t, b.hasSubscribers())
+
+	sub := b.subscribe(MetricFilter{Prefix: "cpu", Types: []entities.MetricType{entities.Gauge}})
+	all := b.subscribe(MetricFilter{})
+	assert.True(t, b.hasSubscribers())
+
+	// Подписчик не читает обновления, публикация не блокируется.
+	for i := 0; i < 1000; i++ {
+		b.publish(&entities.GaugeMetric{Name: "cpu1", Value: float64(i)})
+	}
+	b.publish(&entities.GaugeMetric{Name: "mem", Value: 1})
+	b.publish(&entities.CounterMetric{Name: "cpu_count", Value: 5})
+	b.publish(&entities.GaugeMetric{Name: "cpu2", Value: 2})
+
+	<-sub.C()
+	updates := sub.Next()
+	require.Len(t, updates, 2)
+	assert.Equal(t, "cpu1", updates[0].ID)
+	assert.Equal(t, 999.0, *updates[0].Value)
+	assert.Equal(t, "cpu2", updates[1].ID)
+	assert.Empty(t, sub.Next())
+
+	<-all.C()
+	assert.Len(t, all.Next(), 4)
+
+	b.unsubscribe(sub)
+	b.unsubscribe(all)
+	assert.False(t, b.hasSubscribers())
+}
@@ -3,19 +3,44 @@ package services
 
 import (
 	"context"
+	"io"
+	"regexp"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gitslim/monit/internal/entities"
 	"github.com/gitslim/monit/internal/errs"
 	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/selfmetrics"
 	"github.com/gitslim/monit/internal/server/conf"
 	"github.com/gitslim/monit/internal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Ограничения размера страницы списка метрик.
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+// DefaultHistoryRetention окно хранения истории значений метрик по умолчанию.
+const DefaultHistoryRetention = time.Hour
+
+// MaxAggregateBuckets максимальное количество интервалов в запросе агрегации.
+const MaxAggregateBuckets = 10000
+
 // MetricService сервис для работы с метриками.
 type MetricService struct {
-	storage storage.Storager
+	storage   storage.Storager
+	history   *metricHistory
+	broker    *metricBroker
+	metrics   *selfmetrics.Registry
+	startup   func()
+	shutdown  func()
+	startOnce sync.Once
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // MetricServiceConf конфиг для MetricService.
@@ -23,7 +48,10 @@ type MetricServiceConf func(svc *MetricService) error
 
 // NewMetricService создает новый сервис MetricService, применяя к нему все конфиги.
 func NewMetricService(cfgs ...MetricServiceConf) (*MetricService, error) {
-	svc := &MetricService{}
+	svc := &MetricService{
+		history: newMetricHistory(DefaultHistoryRetention),
+		broker:  newMetricBroker(),
+	}
 
 	for _, cfg := range cfgs {
 		err := cfg(svc)
@@ -31,6 +59,7 @@ func NewMetricService(cfgs ...MetricServiceConf) (*MetricService, error) {
 			return nil, err
 		}
 	}
+	svc.metrics.RegisterCollector(svc.collectStorageStats)
 	return svc, nil
 }
 
@@ -42,7 +71,31 @@ func WithStorage(stor storage.Storager) MetricServiceConf {
 	}
 }
 
+// WithHistory конфигурирует окно хранения истории значений метрик в памяти.
+// История используется для агрегации, если хранилище не агрегирует значения самостоятельно.
+// Нулевое окно отключает историю.
+func WithHistory(retention time.Duration) MetricServiceConf {
+	return func(svc *MetricService) error {
+		if retention == 0 {
+			svc.history = nil
+			return nil
+		}
+		svc.history = newMetricHistory(retention)
+		return nil
+	}
+}
+
+// WithSelfMetrics включает учет метрик сервера в реестре reg:
+// числа принятых метрик, размеров батчей, длительности и ошибок операций с хранилищем.
+func WithSelfMetrics(reg *selfmetrics.Registry) MetricServiceConf {
+	return func(svc *MetricService) error {
+		svc.metrics = reg
+		return nil
+	}
+}
+
 // WithMemStorage конфигурирует MetricService c MemStorage.
+// Восстановление данных из файла и периодическое сохранение резервных копий запускаются методом Start.
 func WithMemStorage(ctx context.Context, log *logging.Logger, cfg *conf.Config, backupErrChan chan<- error) (MetricServiceConf, error) {
 	shouldBackupSync := cfg.StoreInterval == 0
 
@@ -51,50 +104,135 @@ func WithMemStorage(ctx context.Context, log *logging.Logger, cfg *conf.Config,
 		return nil, err
 	}
 
-	stor := storage.NewMemStorage(shouldBackupSync, file)
-	if cfg.Restore {
-		// Загружаем данные при запуске.
-		err := stor.LoadFromFile(cfg.FileStoragePath)
-		if err != nil {
-			log.Debugf("Failed to load metrics from file: %v", err)
+	stor := storage.NewMemStorage(log, shouldBackupSync, file)
+	backupCtx, stopBackup := context.WithCancel(ctx)
+	backupDone := make(chan struct{})
+	started := false
+
+	startup := func() {
+		started = true
+		if cfg.Restore {
+			// Загружаем данные при запуске.
+			err := stor.LoadFromFile(cfg.FileStoragePath)
+			if err != nil {
+				log.Debugf("Failed to load metrics from file: %v", err)
+			}
+		}
+
+		// Резервное копирование начинается после восстановления, чтобы не перезаписать файл неполными данными.
+		if cfg.StoreInterval > 0 {
+			go func() {
+				defer close(backupDone)
+				stor.StartPeriodicBackup(backupCtx, log, file, time.Duration(cfg.StoreInterval), backupErrChan)
+			}()
+		} else {
+			close(backupDone)
 		}
 	}
 
-	if cfg.StoreInterval > 0 {
-		go stor.StartPeriodicBackup(ctx, log, file, time.Duration(cfg.StoreInterval)*time.Second, backupErrChan)
+	// Периодическое копирование останавливается до сохранения последней резервной копии в Close.
+	shutdown := func() {
+		stopBackup()
+		if started {
+			<-backupDone
+		}
 	}
-	return WithStorage(stor), nil
+
+	return func(svc *MetricService) error {
+		svc.storage = stor
+		svc.startup = startup
+		svc.shutdown = shutdown
+		return nil
+	}, nil
 }
 
-// WithPGStorage конфигурирует MetricService c MemStorage.
-func WithPGStorage(ctx context.Context, log *logging.Logger, cfg *conf.Config) (MetricServiceConf, error) {
-	pool, err := storage.CreateConnPool(cfg.DatabaseDSN)
-	if err != nil {
+// WithPGStorage конфигурирует MetricService c PGStorage на пуле соединений pool.
+// Пул может использоваться и другими компонентами сервера, закрывается он при закрытии хранилища.
+func WithPGStorage(ctx context.Context, log *logging.Logger, cfg *conf.Config, pool *pgxpool.Pool) (MetricServiceConf, error) {
+	if err := storage.CreatePGSchema(ctx, pool); err != nil {
 		return nil, err
 	}
-	if err := storage.CreatePGSchema(ctx, pool); err != nil {
+	stor := storage.NewPGStorage(log, pool)
+	pruneCtx, stopPrune := context.WithCancel(ctx)
+	pruneDone := make(chan struct{})
+	if cfg.HistoryRetention > 0 {
+		retention := time.Duration(cfg.HistoryRetention)
+		go func() {
+			defer close(pruneDone)
+			stor.StartPeriodicPrune(pruneCtx, log, retention, time.Minute)
+		}()
+	} else {
+		close(pruneDone)
+	}
+
+	return func(svc *MetricService) error {
+		svc.storage = stor
+		svc.shutdown = func() {
+			stopPrune()
+			<-pruneDone
+		}
+		return nil
+	}, nil
+}
+
+// WithRedisStorage конфигурирует MetricService c RedisStorage.
+func WithRedisStorage(cfg *conf.Config) (MetricServiceConf, error) {
+	client, err := storage.CreateRedisClient(cfg.RedisAddr)
+	if err != nil {
 		return nil, err
 	}
-	stor := storage.NewPGStorage(pool)
+	stor := storage.NewRedisStorage(client)
 	return WithStorage(stor), nil
 }
 
+// Start выполняет подготовку хранилища при запуске сервера, например восстановление данных из резервной копии.
+// Повторные вызовы ничего не делают.
+func (s *MetricService) Start() {
+	s.startOnce.Do(func() {
+		if s.startup != nil {
+			s.startup()
+		}
+	})
+}
+
+// Close останавливает фоновые задачи хранилища и закрывает его: сохраняет последнюю резервную копию
+// и закрывает соединения с базой данных. Вызывается после завершения обработки запросов,
+// повторные вызовы возвращают результат первого.
+func (s *MetricService) Close() error {
+	s.closeOnce.Do(func() {
+		// После закрытия хранилище не запускается.
+		s.startOnce.Do(func() {})
+		if s.shutdown != nil {
+			s.shutdown()
+		}
+		if c, ok := s.storage.(io.Closer); ok {
+			s.closeErr = c.Close()
+		}
+	})
+	return s.closeErr
+}
+
 // GetMetric получает метрику из хранилища по имени и типу.
 func (s *MetricService) GetMetric(mName string, mType string) (entities.Metric, error) {
+	start := time.Now()
 	val, err := s.storage.GetMetric(mName, mType)
+	s.observeStorage("get", start, err)
 	if err != nil {
 		return nil, err
 	}
 	return val, nil
 }
 
-// UpdateMetric обновляет метрику.
-func (s *MetricService) UpdateMetric(mName, mType, mValue string) error {
+// UpdateMetric обновляет метрику от имени агента agent (пустая строка - анонимный клиент).
+func (s *MetricService) UpdateMetric(agent, mName, mType, mValue string) error {
 	var v interface{}
 
 	if mName == "" || mType == "" || mValue == "" {
 		return errs.ErrMetricNotFound
 	}
+	if selfmetrics.IsReserved(mName) {
+		return errs.ErrReservedMetric
+	}
 
 	t, err := entities.GetMetricType(mType)
 	if err != nil {
@@ -118,20 +256,196 @@ func (s *MetricService) UpdateMetric(mName, mType, mValue string) error {
 		return errs.ErrInvalidMetricType
 	}
 
-	return s.storage.UpdateOrCreateMetric(mName, t, v)
+	if err := s.updateMetric(agent, mName, t, v); err != nil {
+		return err
+	}
+	s.metrics.Mark("ingested_metrics", 1)
+	return nil
+}
+
+// updateMetric записывает значение метрики в хранилище и обрабатывает обновление.
+func (s *MetricService) updateMetric(agent, mName string, mType entities.MetricType, value interface{}) error {
+	start := time.Now()
+	err := s.storage.UpdateOrCreateMetric(mName, mType, value, entities.MetricUpdate{At: start, Agent: agent})
+	s.observeStorage("update", start, err)
+	if err != nil {
+		return err
+	}
+
+	s.afterUpdate(mName, mType)
+	return nil
+}
+
+// BatchUpdateMetrics обновляет метрики в хранилище батчами от имени агента agent.
+func (s *MetricService) BatchUpdateMetrics(agent string, metrics []*entities.MetricDTO) error {
+	for _, dto := range metrics {
+		if selfmetrics.IsReserved(dto.ID) {
+			return errs.ErrReservedMetric
+		}
+		// Метрики неизвестного типа хранилища пропускают, метрики без значения отклоняются.
+		if (dto.MType == entities.Gauge.String() && dto.Value == nil) ||
+			(dto.MType == entities.Counter.String() && dto.Delta == nil) {
+			return errs.ErrBadRequest
+		}
+	}
+
+	start := time.Now()
+	err := s.storage.BatchUpdateOrCreateMetrics(metrics, entities.MetricUpdate{At: start, Agent: agent})
+	s.observeStorage("batch_update", start, err)
+	if err != nil {
+		return err
+	}
+	s.metrics.Mark("ingested_metrics", int64(len(metrics)))
+	s.metrics.Observe("batch_size", float64(len(metrics)))
+
+	// Метрика может встречаться в батче несколько раз, обрабатываем ее итоговое значение однократно.
+	applied := make(map[string]bool, len(metrics))
+	for _, dto := range metrics {
+		t, err := entities.GetMetricType(dto.MType)
+		if err != nil || applied[historyKey(dto.ID, t)] {
+			continue
+		}
+		applied[historyKey(dto.ID, t)] = true
+		s.afterUpdate(dto.ID, t)
+	}
+	return nil
 }
 
-// BatchUpdateMetrics обновляет метрики в хранилище батчами.
-func (s *MetricService) BatchUpdateMetrics(metrics []*entities.MetricDTO) error {
-	return s.storage.BatchUpdateOrCreateMetrics(metrics)
+// LastUpdated возвращает время последнего обновления метрики, сохраненное в хранилище.
+func (s *MetricService) LastUpdated(mName string, mType entities.MetricType) (time.Time, bool) {
+	u, ok := s.LastUpdate(mName, mType)
+	return u.At, ok
+}
+
+// LastUpdate возвращает время и агента последнего обновления метрики, сохраненные в хранилище.
+func (s *MetricService) LastUpdate(mName string, mType entities.MetricType) (entities.MetricUpdate, bool) {
+	key := entities.MetricKey{Name: mName, Type: mType}
+	updates, err := s.LastUpdates([]entities.MetricKey{key})
+	if err != nil {
+		return entities.MetricUpdate{}, false
+	}
+	u, ok := updates[key]
+	return u, ok
+}
+
+// LastUpdates возвращает время и агента последнего обновления метрик keys одним запросом к хранилищу.
+// Метрики без сведений об обновлении в результат не попадают.
+func (s *MetricService) LastUpdates(keys []entities.MetricKey) (map[entities.MetricKey]entities.MetricUpdate, error) {
+	start := time.Now()
+	updates, err := s.storage.GetMetricUpdates(keys)
+	s.observeStorage("get_updates", start, err)
+	return updates, err
+}
+
+// afterUpdate записывает значение обновленной метрики в историю, если хранилище не ведет
+// историю самостоятельно, и рассылает обновление подписчикам.
+func (s *MetricService) afterUpdate(mName string, mType entities.MetricType) {
+	_, isAggregator := s.storage.(storage.Aggregator)
+	shouldRecord := s.history != nil && !isAggregator
+	shouldPublish := s.broker.hasSubscribers()
+	if !shouldRecord && !shouldPublish {
+		return
+	}
+
+	start := time.Now()
+	m, err := s.storage.GetMetric(mName, mType.String())
+	s.observeStorage("get", start, err)
+	if err != nil {
+		return
+	}
+
+	if shouldRecord {
+		switch v := m.GetValue().(type) {
+		case float64:
+			s.history.record(mName, mType, v)
+		case int64:
+			s.history.record(mName, mType, float64(v))
+		}
+	}
+	if shouldPublish {
+		s.broker.publish(m)
+	}
+}
+
+// Subscribe создает подписку на обновления метрик с заданным фильтром.
+func (s *MetricService) Subscribe(filter MetricFilter) *Subscription {
+	return s.broker.subscribe(filter)
+}
+
+// Unsubscribe удаляет подписку на обновления метрик.
+func (s *MetricService) Unsubscribe(sub *Subscription) {
+	s.broker.unsubscribe(sub)
+}
+
+// AggregateMetric агрегирует историю значений метрики по интервалам.
+func (s *MetricService) AggregateMetric(q entities.AggregateQuery) ([]*entities.AggregateBucket, error) {
+	if q.Step <= 0 || !q.From.Before(q.To) || len(q.Funcs) == 0 {
+		return nil, errs.ErrInvalidQuery
+	}
+	if q.To.Sub(q.From)/q.Step > MaxAggregateBuckets {
+		return nil, errs.ErrInvalidQuery
+	}
+
+	if aggregator, ok := s.storage.(storage.Aggregator); ok {
+		start := time.Now()
+		buckets, err := aggregator.AggregateMetric(&q)
+		s.observeStorage("aggregate", start, err)
+		return buckets, err
+	}
+
+	if s.history == nil {
+		return nil, errs.ErrHistoryDisabled
+	}
+	return s.history.aggregate(&q), nil
 }
 
 // GetAllMetrics получает все метрики из хранилища.
-func (s *MetricService) GetAllMetrics() (map[string]entities.Metric, error) {
-	return s.storage.GetAllMetrics()
+func (s *MetricService) GetAllMetrics() (map[entities.MetricKey]entities.Metric, error) {
+	start := time.Now()
+	metrics, err := s.storage.GetAllMetrics()
+	s.observeStorage("get_all", start, err)
+	return metrics, err
+}
+
+// ListMetrics получает страницу отфильтрованного и отсортированного списка метрик.
+// Возвращает курсор следующей страницы или nil, если страница последняя.
+func (s *MetricService) ListMetrics(q entities.MetricQuery) ([]entities.Metric, *entities.MetricCursor, error) {
+	if q.NameRegex != "" {
+		if _, err := regexp.Compile(q.NameRegex); err != nil {
+			return nil, nil, errs.ErrInvalidQuery
+		}
+	}
+
+	switch {
+	case q.Limit <= 0:
+		q.Limit = DefaultListLimit
+	case q.Limit > MaxListLimit:
+		q.Limit = MaxListLimit
+	}
+	limit := q.Limit
+
+	// Запрашиваем на одну метрику больше, чтобы узнать, есть ли следующая страница.
+	q.Limit++
+	start := time.Now()
+	metrics, err := s.storage.ListMetrics(&q)
+	s.observeStorage("list", start, err)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(metrics) <= limit {
+		return metrics, nil, nil
+	}
+
+	metrics = metrics[:limit]
+	last := metrics[limit-1]
+	return metrics, &entities.MetricCursor{Name: last.GetName(), Type: last.GetType().String()}, nil
 }
 
 // PingStorage проверяет соединение с хранилищем.
 func (s *MetricService) PingStorage() error {
-	return s.storage.Ping()
+	start := time.Now()
+	err := s.storage.Ping()
+	s.observeStorage("ping", start, err)
+	return err
 }
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gitslim/monit/internal/config"
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/server/conf"
+	"github.com/gitslim/monit/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCloseWritesFinalBackup тестирует сохранение последней резервной копии при закрытии сервиса.
+func TestCloseWritesFinalBackup(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	cfg := &conf.Config{
+		FileStoragePath: filepath.Join(t.TempDir(), "memstorage.json"),
+		StoreInterval:   config.Seconds(3600),
+	}
+
+	svcCfg, err := WithMemStorage(context.Background(), log, cfg, make(chan error))
+	require.NoError(t, err)
+	svc, err := NewMetricService(svcCfg)
+	require.NoError(t, err)
+	svc.Start()
+
+	require.NoError(t, svc.UpdateMetric("agent-1", "Alloc", "gauge", "42"))
+	require.NoError(t, svc.Close())
+	require.NoError(t, svc.Close())
+
+	// Данные восстанавливаются из последней резервной копии.
+	restored := storage.NewMemStorage(log, false, nil)
+	require.NoError(t, restored.LoadFromFile(cfg.FileStoragePath))
+	metric, err := restored.GetMetric("Alloc", "gauge")
+	require.NoError(t, err)
+	assert.Equal(t, 42.0, metric.GetValue())
+
+	// Сведения о последнем обновлении тоже восстанавливаются.
+	updates, err := restored.GetMetricUpdates([]entities.MetricKey{{Name: "Alloc", Type: entities.Gauge}})
+	require.NoError(t, err)
+	assert.Equal(t, "agent-1", updates[entities.MetricKey{Name: "Alloc", Type: entities.Gauge}].Agent)
+}
+
+// TestLastUpdateSharedStorage тестирует, что агент, обновивший метрику, сохраняется
+// в хранилище и доступен другим репликам сервера.
+func TestLastUpdateSharedStorage(t *testing.T) {
+	addr := miniredis.RunT(t).Addr()
+	newService := func() *MetricService {
+		client, err := storage.CreateRedisClient(addr)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = client.Close() })
+
+		svc, err := NewMetricService(WithStorage(storage.NewRedisStorage(client)))
+		require.NoError(t, err)
+		return svc
+	}
+
+	require.NoError(t, newService().UpdateMetric("agent-1", "Alloc", "gauge", "42"))
+
+	replica := newService()
+	u, ok := replica.LastUpdate("Alloc", entities.Gauge)
+	require.True(t, ok)
+	assert.Equal(t, "agent-1", u.Agent)
+
+	last, ok := replica.LastUpdated("Alloc", entities.Gauge)
+	require.True(t, ok)
+	assert.Equal(t, u.At, last)
+
+	_, ok = replica.LastUpdate("Alloc", entities.Counter)
+	assert.False(t, ok)
+}
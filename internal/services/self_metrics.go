@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/errs"
+	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/selfmetrics"
+	"github.com/gitslim/monit/internal/storage"
+)
+
+// SelfMetricsAgent имя, от которого в хранилище записываются метрики сервера.
+const SelfMetricsAgent = "monit-server"
+
+// observeStorage учитывает длительность и ошибку операции op с хранилищем.
+// Отсутствие метрики ошибкой хранилища не считается.
+func (s *MetricService) observeStorage(op string, start time.Time, err error) {
+	s.metrics.ObserveDuration("storage_duration_seconds", time.Since(start), "op", op)
+	if err != nil && !errors.Is(err, errs.ErrMetricNotFound) {
+		s.metrics.Add("storage_errors_total", 1, "op", op)
+	}
+}
+
+// collectStorageStats возвращает статистику резервного копирования и пула соединений хранилища.
+func (s *MetricService) collectStorageStats() []*entities.MetricDTO {
+	var metrics []*entities.MetricDTO
+
+	if b, ok := s.storage.(storage.BackupStatter); ok {
+		stat := b.BackupStat()
+		metrics = append(metrics,
+			selfmetrics.Counter("backup_total", stat.Count),
+			selfmetrics.Counter("backup_errors_total", stat.Errors),
+			selfmetrics.Gauge("backup_duration_seconds", stat.LastDuration.Seconds()),
+		)
+		if !stat.LastSuccess.IsZero() {
+			metrics = append(metrics, selfmetrics.Gauge("backup_last_success_timestamp_seconds", float64(stat.LastSuccess.Unix())))
+		}
+	}
+
+	if p, ok := s.storage.(storage.PoolStatter); ok {
+		stat := p.PoolStat()
+		metrics = append(metrics,
+			selfmetrics.Gauge("pg_pool_acquired_conns", float64(stat.AcquiredConns())),
+			selfmetrics.Gauge("pg_pool_idle_conns", float64(stat.IdleConns())),
+			selfmetrics.Gauge("pg_pool_total_conns", float64(stat.TotalConns())),
+			selfmetrics.Gauge("pg_pool_max_conns", float64(stat.MaxConns())),
+			selfmetrics.Counter("pg_pool_acquire_total", stat.AcquireCount()),
+			selfmetrics.Counter("pg_pool_empty_acquire_total", stat.EmptyAcquireCount()),
+			selfmetrics.Counter("pg_pool_canceled_acquire_total", stat.CanceledAcquireCount()),
+			selfmetrics.Gauge("pg_pool_acquire_duration_seconds", stat.AcquireDuration().Seconds()),
+		)
+	}
+	return metrics
+}
+
+// SelfMetricsRegistry возвращает реестр метрик сервера или nil, если учет метрик сервера выключен.
+func (s *MetricService) SelfMetricsRegistry() *selfmetrics.Registry {
+	return s.metrics
+}
+
+// SelfMetrics возвращает текущие значения метрик сервера.
+func (s *MetricService) SelfMetrics() []*entities.MetricDTO {
+	return s.metrics.Snapshot()
+}
+
+// StartSelfMetricsPublisher периодически записывает метрики сервера в хранилище, чтобы они были доступны
+// через API чтения, панель метрик и правила оповещений наравне с метриками агентов.
+// Счетчики хранилища накапливают значения, поэтому для счетчиков записывается прирост с прошлой записи.
+func (s *MetricService) StartSelfMetricsPublisher(ctx context.Context, log *logging.Logger, interval time.Duration) {
+	published := make(map[string]int64)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug("Self metrics publisher stopped")
+			return
+		case <-ticker.C:
+			if err := s.publishSelfMetrics(published); err != nil {
+				log.Errorf("Self metrics publishing failed: %v", err)
+			}
+		}
+	}
+}
+
+// publishSelfMetrics записывает текущие значения метрик сервера в хранилище.
+// published содержит значения счетчиков, записанные ранее.
+func (s *MetricService) publishSelfMetrics(published map[string]int64) error {
+	for _, m := range s.metrics.Snapshot() {
+		switch {
+		case m.Delta != nil:
+			prev, ok := published[m.ID]
+			delta := *m.Delta - prev
+			if ok && delta == 0 {
+				continue
+			}
+			// Счетчик источника мог сброситься, например при пересоздании пула соединений.
+			if delta < 0 {
+				delta = *m.Delta
+			}
+			if err := s.updateMetric(SelfMetricsAgent, m.ID, entities.Counter, delta); err != nil {
+				return err
+			}
+			published[m.ID] = *m.Delta
+		case m.Value != nil:
+			if err := s.updateMetric(SelfMetricsAgent, m.ID, entities.Gauge, *m.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/errs"
+	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/selfmetrics"
+	"github.com/gitslim/monit/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSelfMetrics тестирует учет метрик сервера и их запись в хранилище.
+func TestSelfMetrics(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+	stor := storage.NewMemStorage(log, false, nil)
+	svc, err := NewMetricService(WithStorage(stor), WithSelfMetrics(selfmetrics.NewRegistry()))
+	require.NoError(t, err)
+
+	// Агенты не могут записывать метрики в зарезервированное пространство имен.
+	assert.ErrorIs(t, svc.UpdateMetric("agent-1", "monit_server_up", "gauge", "1"), errs.ErrReservedMetric)
+	assert.ErrorIs(t, svc.BatchUpdateMetrics("agent-1", []*entities.MetricDTO{
+		{ID: "monit_server_up", MType: "gauge", Value: new(float64)},
+	}), errs.ErrReservedMetric)
+
+	require.NoError(t, svc.UpdateMetric("agent-1", "Alloc", "gauge", "1"))
+	require.NoError(t, svc.BatchUpdateMetrics("agent-1", []*entities.MetricDTO{
+		{ID: "Alloc", MType: "gauge", Value: new(float64)},
+		{ID: "Free", MType: "gauge", Value: new(float64)},
+	}))
+
+	ingested := selfmetrics.Name("ingested_metrics_total")
+	values := make(map[string]*entities.MetricDTO)
+	for _, m := range svc.SelfMetrics() {
+		values[m.ID] = m
+	}
+	require.Contains(t, values, ingested)
+	assert.Equal(t, int64(3), *values[ingested].Delta)
+	assert.Contains(t, values, selfmetrics.Name("batch_size_count"))
+	assert.Contains(t, values, selfmetrics.Name("storage_duration_seconds_count", "op", "update"))
+
+	// Счетчики записываются приростом, поэтому повторная запись не удваивает значение.
+	published := make(map[string]int64)
+	require.NoError(t, svc.publishSelfMetrics(published))
+	require.NoError(t, svc.publishSelfMetrics(published))
+	m, err := stor.GetMetric(ingested, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), m.GetValue())
+
+	require.NoError(t, svc.UpdateMetric("agent-1", "Alloc", "gauge", "2"))
+	require.NoError(t, svc.publishSelfMetrics(published))
+	m, err = stor.GetMetric(ingested, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), m.GetValue())
+
+	u, ok := svc.LastUpdate(ingested, entities.Counter)
+	require.True(t, ok)
+	assert.Equal(t, SelfMetricsAgent, u.Agent)
+}
@@ -1,5 +1,5 @@
 // Package storage определяет общий интерфейс работы с хранилищем метрик.
 //
-// Содержит реализации интерфейса хранилища для хранения в памяти и в postgresql.
+// Содержит реализации интерфейса хранилища для хранения в памяти, в postgresql и в redis.
 // Содержит функционал периодического сохранения данных на диск для харнилища в памяти.
 package storage
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -17,75 +18,92 @@ import (
 
 // MemStorage хранилище метрик в памяти.
 type MemStorage struct {
-	metrics          sync.Map
+	metrics          sync.Map // Метрики по ключам entities.MetricKey
+	updates          sync.Map // Последние обновления метрик по ключам entities.MetricKey
 	shouldBackupSync bool
 	backupWriter     io.Writer
+	log              *logging.Logger
+
+	writeMu    sync.Mutex // сериализует сохранения резервной копии
+	backupMu   sync.Mutex
+	backupStat BackupStat
 }
 
-// MarshalJSON сериализует данные в json.
+// MarshalJSON сериализует данные в json. Метрики записываются по ключам вида <type>:<name>.
 func (s *MemStorage) MarshalJSON() ([]byte, error) {
 	tmp := make(map[string]interface{})
 	s.metrics.Range(func(key, value interface{}) bool {
 		metric := value.(entities.Metric)
-		tmp[key.(string)] = map[string]interface{}{
+		entry := map[string]interface{}{
 			"name":  metric.GetName(),
 			"value": metric.GetValue(),
 			"type":  metric.GetType(),
 		}
+		if update, ok := s.updates.Load(key); ok {
+			entry["update"] = update
+		}
+		tmp[key.(entities.MetricKey).String()] = entry
 		return true
 	})
 	return json.Marshal(tmp)
 }
 
-// UnmarshalJSON десериализует данные из json.
+// UnmarshalJSON десериализует данные из json. Имя и тип метрики берутся из ее записи,
+// поэтому читаются и резервные копии с ключами-именами метрик.
 func (s *MemStorage) UnmarshalJSON(data []byte) error {
 	var temp map[string]json.RawMessage
 	if err := json.Unmarshal(data, &temp); err != nil {
 		return err
 	}
 
-	for name, raw := range temp {
+	for _, raw := range temp {
 		var metricType struct {
-			Type entities.MetricType `json:"type"`
+			Type   entities.MetricType    `json:"type"`
+			Update *entities.MetricUpdate `json:"update"`
 		}
 		if err := json.Unmarshal(raw, &metricType); err != nil {
 			return err
 		}
 
+		var m entities.Metric
 		switch metricType.Type {
 		case entities.Gauge:
 			var gauge entities.GaugeMetric
-			if err := json.Unmarshal(raw, &gauge); err == nil {
-				s.metrics.Store(name, &gauge)
-			} else {
+			if err := json.Unmarshal(raw, &gauge); err != nil {
 				return err
 			}
+			m = &gauge
 		case entities.Counter:
 			var counter entities.CounterMetric
-			if err := json.Unmarshal(raw, &counter); err == nil {
-				s.metrics.Store(name, &counter)
-			} else {
+			if err := json.Unmarshal(raw, &counter); err != nil {
 				return err
 			}
+			m = &counter
 		default:
 			return fmt.Errorf("unknown metric type: %s", metricType.Type)
 		}
+
+		s.metrics.Store(entities.KeyOf(m), m)
+		if metricType.Update != nil {
+			s.updates.Store(entities.KeyOf(m), *metricType.Update)
+		}
 	}
 
 	return nil
 }
 
 // NewMemStorage - создает новое хранилище метрик в памяти.
-func NewMemStorage(shouldBackupSync bool, backupWriter io.Writer) *MemStorage {
+func NewMemStorage(log *logging.Logger, shouldBackupSync bool, backupWriter io.Writer) *MemStorage {
 	return &MemStorage{
 		metrics:          sync.Map{},
 		shouldBackupSync: shouldBackupSync,
 		backupWriter:     backupWriter,
+		log:              log,
 	}
 }
 
 // UpdateOrCreateMetric обновляет значение метрики, если метрика отстутствует то создает ее.
-func (s *MemStorage) UpdateOrCreateMetric(mName string, mType entities.MetricType, value interface{}) error {
+func (s *MemStorage) UpdateOrCreateMetric(mName string, mType entities.MetricType, value interface{}, update entities.MetricUpdate) error {
 	var m entities.Metric
 
 	metric, err := s.GetMetric(mName, mType.String())
@@ -104,7 +122,8 @@ func (s *MemStorage) UpdateOrCreateMetric(mName string, mType entities.MetricTyp
 		return err
 	}
 
-	s.metrics.Store(mName, m)
+	s.metrics.Store(entities.KeyOf(m), m)
+	s.storeUpdate(entities.KeyOf(m), update)
 
 	if s.shouldBackupSync {
 		if err := s.WriteBackup(s.backupWriter); err != nil {
@@ -114,19 +133,23 @@ func (s *MemStorage) UpdateOrCreateMetric(mName string, mType entities.MetricTyp
 	return nil
 }
 
-// GetMetric получает метрику по имени.
+// GetMetric получает метрику по имени и типу.
 func (s *MemStorage) GetMetric(mName string, mType string) (entities.Metric, error) {
-	if metric, exists := s.metrics.Load(mName); exists {
+	metricType, err := entities.GetMetricType(mType)
+	if err != nil {
+		return nil, errs.ErrMetricNotFound
+	}
+	if metric, exists := s.metrics.Load(entities.MetricKey{Name: mName, Type: metricType}); exists {
 		return metric.(entities.Metric), nil
 	}
 	return nil, errs.ErrMetricNotFound
 }
 
 // GetAllMetrics получает все метрики.
-func (s *MemStorage) GetAllMetrics() (map[string]entities.Metric, error) {
-	metrics := make(map[string]entities.Metric)
+func (s *MemStorage) GetAllMetrics() (map[entities.MetricKey]entities.Metric, error) {
+	metrics := make(map[entities.MetricKey]entities.Metric)
 	s.metrics.Range(func(key, value interface{}) bool {
-		metrics[key.(string)] = value.(entities.Metric)
+		metrics[key.(entities.MetricKey)] = value.(entities.Metric)
 		return true
 	})
 	return metrics, nil
@@ -151,8 +174,36 @@ func (s *MemStorage) LoadFromFile(filename string) error {
 	return nil
 }
 
-// WriteBackup сохраняет данные хранилища в файл.
+// WriteBackup сохраняет данные хранилища в файл и учитывает сохранение в статистике.
 func (s *MemStorage) WriteBackup(w io.Writer) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	start := time.Now()
+	err := s.writeBackup(w)
+
+	s.backupMu.Lock()
+	defer s.backupMu.Unlock()
+	s.backupStat.Count++
+	s.backupStat.LastDuration = time.Since(start)
+	s.backupStat.LastError = err
+	if err != nil {
+		s.backupStat.Errors++
+	} else {
+		s.backupStat.LastSuccess = time.Now()
+	}
+	return err
+}
+
+// BackupStat возвращает статистику сохранения резервных копий.
+func (s *MemStorage) BackupStat() BackupStat {
+	s.backupMu.Lock()
+	defer s.backupMu.Unlock()
+	return s.backupStat
+}
+
+// writeBackup сохраняет данные хранилища в файл.
+func (s *MemStorage) writeBackup(w io.Writer) error {
 	data, err := json.Marshal(&s)
 	if err != nil {
 		return err
@@ -174,14 +225,8 @@ func (s *MemStorage) WriteBackup(w io.Writer) error {
 }
 
 // StartPeriodicBackup запускает периодическое сохранение данных в файл на диске.
+// Файл закрывается методом Close после сохранения последней резервной копии.
 func (s *MemStorage) StartPeriodicBackup(ctx context.Context, log *logging.Logger, fd *os.File, interval time.Duration, errChan chan<- error) {
-	defer func() {
-		err := fd.Close()
-		if err != nil {
-			log.Errorf("MemStorage backup file close error: %v", err)
-		}
-	}()
-
 	for {
 		select {
 		case <-ctx.Done():
@@ -190,7 +235,10 @@ func (s *MemStorage) StartPeriodicBackup(ctx context.Context, log *logging.Logge
 		case <-time.After(interval):
 			if err := s.WriteBackup(fd); err != nil {
 				log.Errorf("MemStorage backup error: %v", err)
-				errChan <- err
+				select {
+				case errChan <- err:
+				case <-ctx.Done():
+				}
 				return
 			}
 			log.Debug("MemStorage backup success")
@@ -198,6 +246,21 @@ func (s *MemStorage) StartPeriodicBackup(ctx context.Context, log *logging.Logge
 	}
 }
 
+// Close сохраняет последнюю резервную копию и закрывает файл резервной копии.
+func (s *MemStorage) Close() error {
+	if s.backupWriter == nil {
+		return nil
+	}
+	err := s.WriteBackup(s.backupWriter)
+	if err != nil {
+		err = fmt.Errorf("final backup failed: %w", err)
+	}
+	if c, ok := s.backupWriter.(io.Closer); ok {
+		err = errors.Join(err, c.Close())
+	}
+	return err
+}
+
 // CreateBackupFile создает файл для записи файла бэкапа.
 func CreateBackupFile(filePath string) (*os.File, error) {
 	dir := filepath.Dir(filePath)
@@ -221,34 +284,36 @@ func (s *MemStorage) Ping() error {
 }
 
 // BatchUpdateOrCreateMetrics обновляет данные в хранилище батчами.
-func (s *MemStorage) BatchUpdateOrCreateMetrics(metrics []*entities.MetricDTO) error {
+func (s *MemStorage) BatchUpdateOrCreateMetrics(metrics []*entities.MetricDTO, update entities.MetricUpdate) error {
 	for _, dto := range metrics {
 
 		var m entities.Metric
 
 		mType, err := entities.GetMetricType(dto.MType)
 		if err != nil {
-			fmt.Printf("Unknown metric type: %v\n", err)
+			s.log.Warnf("Skipping metric %s: %v", dto.ID, err)
+			continue
 		}
 		switch mType {
 		case entities.Gauge:
 			m, err = entities.NewGaugeMetricFromDTO(dto)
 			if err != nil {
-				fmt.Printf("Error creating gauge metric: %v\n", err)
+				s.log.Warnf("Skipping gauge metric %s: %v", dto.ID, err)
 				continue
 			}
 		case entities.Counter:
 			m, err = entities.NewCounterMetricFromDTO(dto)
 			if err != nil {
-				fmt.Printf("Error creating counter metric: %v\n", err)
+				s.log.Warnf("Skipping counter metric %s: %v", dto.ID, err)
 				continue
 			}
 		default:
-			fmt.Printf("Unknown metric type: %v\n", mType)
+			s.log.Warnf("Skipping metric %s of unknown type %s", dto.ID, mType)
 			continue
 		}
 
-		s.metrics.Store(m.GetName(), m)
+		s.metrics.Store(entities.KeyOf(m), m)
+		s.storeUpdate(entities.KeyOf(m), update)
 
 		if s.shouldBackupSync {
 			if err := s.WriteBackup(s.backupWriter); err != nil {
@@ -258,3 +323,30 @@ func (s *MemStorage) BatchUpdateOrCreateMetrics(metrics []*entities.MetricDTO) e
 	}
 	return nil
 }
+
+// storeUpdate запоминает последнее обновление метрики key. Пустое время обновления не запоминается.
+func (s *MemStorage) storeUpdate(key entities.MetricKey, update entities.MetricUpdate) {
+	if !update.At.IsZero() {
+		s.updates.Store(key, update)
+	}
+}
+
+// GetMetricUpdates возвращает время и агента последнего обновления метрик keys.
+func (s *MemStorage) GetMetricUpdates(keys []entities.MetricKey) (map[entities.MetricKey]entities.MetricUpdate, error) {
+	updates := make(map[entities.MetricKey]entities.MetricUpdate, len(keys))
+	for _, key := range keys {
+		if v, ok := s.updates.Load(key); ok {
+			updates[key] = v.(entities.MetricUpdate)
+		}
+	}
+	return updates, nil
+}
+
+// ListMetrics получает отфильтрованный и отсортированный список метрик.
+func (s *MemStorage) ListMetrics(q *entities.MetricQuery) ([]entities.Metric, error) {
+	metrics, err := s.GetAllMetrics()
+	if err != nil {
+		return nil, err
+	}
+	return queryMetrics(metrics, q)
+}
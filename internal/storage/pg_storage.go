@@ -5,14 +5,19 @@ import (
 	"database/sql"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gitslim/monit/internal/entities"
 	"github.com/gitslim/monit/internal/errs"
+	"github.com/gitslim/monit/internal/logging"
 	"github.com/gitslim/monit/internal/retry"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -26,21 +31,36 @@ var (
 	GetGaugeQuery      string
 	GetCounterQuery    string
 	GetAllMetricsQuery string
+	AggregateQuery     string
+	PruneSamplesQuery  string
+	GetUpdatesQuery    string
 )
 
+// pgInvalidRegularExpression код ошибки PostgreSQL invalid_regular_expression.
+const pgInvalidRegularExpression = "2201B"
+
 // PGStorage хранилище для PostgreSQL.
 type PGStorage struct {
-	db *pgxpool.Pool
+	db  *pgxpool.Pool
+	log *logging.Logger
+}
+
+// PoolStat возвращает статистику пула соединений.
+func (s *PGStorage) PoolStat() *pgxpool.Stat {
+	return s.db.Stat()
 }
 
 // loadQueries загружает SQL-запросы из файлов и присваивает их переменным.
 func loadQueries() {
 	queries := map[string]*string{
-		"upsert_gauge.sql":    &UpsertGaugeQuery,
-		"upsert_counter.sql":  &UpsertCounterQuery,
-		"get_gauge.sql":       &GetGaugeQuery,
-		"get_counter.sql":     &GetCounterQuery,
-		"get_all_metrics.sql": &GetAllMetricsQuery,
+		"upsert_gauge.sql":       &UpsertGaugeQuery,
+		"upsert_counter.sql":     &UpsertCounterQuery,
+		"get_gauge.sql":          &GetGaugeQuery,
+		"get_counter.sql":        &GetCounterQuery,
+		"get_all_metrics.sql":    &GetAllMetricsQuery,
+		"aggregate_samples.sql":  &AggregateQuery,
+		"prune_samples.sql":      &PruneSamplesQuery,
+		"get_metric_updates.sql": &GetUpdatesQuery,
 	}
 
 	for file, qPtr := range queries {
@@ -57,7 +77,7 @@ func init() {
 	loadQueries()
 }
 
-// MarshalJSON возвращает JSON-объект с метриками из хранилища.
+// MarshalJSON возвращает JSON-объект с метриками из хранилища по ключам вида <type>:<name>.
 func (s *PGStorage) MarshalJSON() ([]byte, error) {
 	tmp := make(map[string]interface{})
 
@@ -66,8 +86,8 @@ func (s *PGStorage) MarshalJSON() ([]byte, error) {
 		return nil, err
 	}
 
-	for name, metric := range metrics {
-		tmp[name] = map[string]interface{}{
+	for key, metric := range metrics {
+		tmp[key.String()] = map[string]interface{}{
 			"name":  metric.GetName(),
 			"value": metric.GetValue(),
 			"type":  metric.GetType(),
@@ -84,7 +104,7 @@ func (s *PGStorage) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	for name, raw := range temp {
+	for _, raw := range temp {
 		var metricType struct {
 			Type entities.MetricType `json:"type"`
 		}
@@ -96,14 +116,14 @@ func (s *PGStorage) UnmarshalJSON(data []byte) error {
 		case entities.Gauge:
 			var gauge entities.GaugeMetric
 			if err := json.Unmarshal(raw, &gauge); err == nil {
-				if err := s.UpdateOrCreateMetric(name, metricType.Type, gauge); err != nil {
+				if err := s.UpdateOrCreateMetric(gauge.Name, metricType.Type, gauge.Value, entities.MetricUpdate{}); err != nil {
 					return errs.ErrInternal
 				}
 			}
 		case entities.Counter:
 			var counter entities.CounterMetric
 			if err := json.Unmarshal(raw, &counter); err == nil {
-				if err := s.UpdateOrCreateMetric(name, metricType.Type, counter); err != nil {
+				if err := s.UpdateOrCreateMetric(counter.Name, metricType.Type, counter.Value, entities.MetricUpdate{}); err != nil {
 					return errs.ErrInternal
 				}
 			}
@@ -115,14 +135,16 @@ func (s *PGStorage) UnmarshalJSON(data []byte) error {
 }
 
 // NewPGStorage возвращает экземпляр хранилища с подключением к базе данных.
-func NewPGStorage(pool *pgxpool.Pool) *PGStorage {
+func NewPGStorage(log *logging.Logger, pool *pgxpool.Pool) *PGStorage {
 	return &PGStorage{
-		db: pool,
+		db:  pool,
+		log: log,
 	}
 }
 
 // UpdateOrCreateMetric обновляет значение метрики, если метрика отстутствует то создает ее (Upsert).
-func (s *PGStorage) UpdateOrCreateMetric(name string, metricType entities.MetricType, value interface{}) error {
+func (s *PGStorage) UpdateOrCreateMetric(name string, metricType entities.MetricType, value interface{}, update entities.MetricUpdate) error {
+	updatedAt, updatedBy := updateArgs(update)
 	return retry.Retry(func() error {
 		ctx := context.Background()
 
@@ -132,7 +154,7 @@ func (s *PGStorage) UpdateOrCreateMetric(name string, metricType entities.Metric
 			if !ok {
 				return errs.ErrInvalidMetricValue
 			}
-			_, err := s.db.Exec(ctx, UpsertGaugeQuery, name, metricType.String(), v)
+			_, err := s.db.Exec(ctx, UpsertGaugeQuery, name, metricType.String(), v, updatedAt, updatedBy)
 			if err != nil {
 				return errs.ErrInternal
 			}
@@ -142,7 +164,7 @@ func (s *PGStorage) UpdateOrCreateMetric(name string, metricType entities.Metric
 			if !ok {
 				return errs.ErrInvalidMetricValue
 			}
-			_, err := s.db.Exec(ctx, UpsertCounterQuery, name, metricType.String(), v)
+			_, err := s.db.Exec(ctx, UpsertCounterQuery, name, metricType.String(), v, updatedAt, updatedBy)
 			if err != nil {
 				return errs.ErrInternal
 			}
@@ -155,6 +177,53 @@ func (s *PGStorage) UpdateOrCreateMetric(name string, metricType entities.Metric
 	}, 3)
 }
 
+// updateArgs возвращает аргументы запроса с временем и агентом обновления.
+// Пустое время обновления записывается как NULL и не заменяет сохраненные сведения.
+func updateArgs(update entities.MetricUpdate) (any, any) {
+	if update.At.IsZero() {
+		return nil, nil
+	}
+	return update.At, update.Agent
+}
+
+// GetMetricUpdates возвращает время и агента последнего обновления метрик keys.
+func (s *PGStorage) GetMetricUpdates(keys []entities.MetricKey) (map[entities.MetricKey]entities.MetricUpdate, error) {
+	updates := make(map[entities.MetricKey]entities.MetricUpdate, len(keys))
+	if len(keys) == 0 {
+		return updates, nil
+	}
+
+	names := make([]string, 0, len(keys))
+	types := make([]string, 0, len(keys))
+	for _, k := range keys {
+		names = append(names, k.Name)
+		types = append(types, k.Type.String())
+	}
+
+	rows, err := s.db.Query(context.Background(), GetUpdatesQuery, names, types)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, typeStr string
+		var u entities.MetricUpdate
+		if err := rows.Scan(&name, &typeStr, &u.At, &u.Agent); err != nil {
+			return nil, err
+		}
+		mType, err := entities.GetMetricType(typeStr)
+		if err != nil {
+			continue
+		}
+		updates[entities.MetricKey{Name: name, Type: mType}] = u
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
 // GetMetric получает метрику по имени.
 func (s *PGStorage) GetMetric(mName string, mType string) (entities.Metric, error) {
 	ctx := context.Background()
@@ -169,7 +238,7 @@ func (s *PGStorage) GetMetric(mName string, mType string) (entities.Metric, erro
 		var value float64
 		err := s.db.QueryRow(ctx, GetGaugeQuery, mName, mType).Scan(&value)
 		if err != nil {
-			fmt.Printf("Ошибка выполнения запроса: %v", err)
+			s.logQueryError(mName, err)
 			return nil, errs.ErrMetricNotFound
 		}
 		return &entities.GaugeMetric{
@@ -181,7 +250,7 @@ func (s *PGStorage) GetMetric(mName string, mType string) (entities.Metric, erro
 		var counter int64
 		err := s.db.QueryRow(ctx, GetCounterQuery, mName, mType).Scan(&counter)
 		if err != nil {
-			fmt.Printf("Ошибка выполнения запроса: %v", err)
+			s.logQueryError(mName, err)
 			return nil, errs.ErrMetricNotFound
 		}
 		return &entities.CounterMetric{
@@ -194,8 +263,16 @@ func (s *PGStorage) GetMetric(mName string, mType string) (entities.Metric, erro
 	}
 }
 
+// logQueryError логирует ошибку запроса метрики mName. Отсутствие метрики ошибкой не считается.
+func (s *PGStorage) logQueryError(mName string, err error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return
+	}
+	s.log.Errorf("Metric %s query failed: %v", mName, err)
+}
+
 // GetAllMetrics получает все метрики.
-func (s *PGStorage) GetAllMetrics() (map[string]entities.Metric, error) {
+func (s *PGStorage) GetAllMetrics() (map[entities.MetricKey]entities.Metric, error) {
 	ctx := context.Background()
 
 	rows, err := s.db.Query(ctx, GetAllMetricsQuery)
@@ -204,7 +281,7 @@ func (s *PGStorage) GetAllMetrics() (map[string]entities.Metric, error) {
 	}
 	defer rows.Close()
 
-	metrics := make(map[string]entities.Metric)
+	metrics := make(map[entities.MetricKey]entities.Metric)
 
 	for rows.Next() {
 		var name, metricTypeStr string
@@ -213,20 +290,20 @@ func (s *PGStorage) GetAllMetrics() (map[string]entities.Metric, error) {
 
 		err = rows.Scan(&name, &metricTypeStr, &value, &counter)
 		if err != nil {
-			fmt.Printf("Ошибка при чтении строки: %v", err)
+			s.log.Errorf("Metrics row scan failed: %v", err)
 			continue
 		}
 
 		metricType, err := entities.GetMetricType(metricTypeStr)
 		if err != nil {
-			fmt.Printf("Неизвестный тип метрики: %s\n", metricTypeStr)
+			s.log.Warnf("Skipping metric %s of unknown type %s", name, metricTypeStr)
 			continue
 		}
 
 		switch metricType {
 		case entities.Gauge:
 			if value.Valid {
-				metrics[name] = &entities.GaugeMetric{
+				metrics[entities.MetricKey{Name: name, Type: metricType}] = &entities.GaugeMetric{
 					Name:  name,
 					Value: value.Float64,
 				}
@@ -234,20 +311,211 @@ func (s *PGStorage) GetAllMetrics() (map[string]entities.Metric, error) {
 
 		case entities.Counter:
 			if counter.Valid {
-				metrics[name] = &entities.CounterMetric{
+				metrics[entities.MetricKey{Name: name, Type: metricType}] = &entities.CounterMetric{
 					Name:  name,
 					Value: counter.Int64,
 				}
 			}
 
 		default:
-			fmt.Printf("Неизвестный тип метрики: %s\n", metricTypeStr)
+			s.log.Warnf("Skipping metric %s of unknown type %s", name, metricTypeStr)
 		}
 	}
 
 	return metrics, nil
 }
 
+// ListMetrics получает отфильтрованный и отсортированный список метрик.
+// Фильтрация, сортировка и пагинация выполняются на стороне базы данных.
+func (s *PGStorage) ListMetrics(q *entities.MetricQuery) ([]entities.Metric, error) {
+	ctx := context.Background()
+
+	query, args := buildListMetricsQuery(q)
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, mapListMetricsError(err)
+	}
+	defer rows.Close()
+
+	metrics := make([]entities.Metric, 0)
+	for rows.Next() {
+		var name, metricTypeStr string
+		var value sql.NullFloat64
+		var counter sql.NullInt64
+
+		if err := rows.Scan(&name, &metricTypeStr, &value, &counter); err != nil {
+			return nil, err
+		}
+
+		metricType, err := entities.GetMetricType(metricTypeStr)
+		if err != nil {
+			continue
+		}
+
+		switch metricType {
+		case entities.Gauge:
+			if value.Valid {
+				metrics = append(metrics, &entities.GaugeMetric{Name: name, Value: value.Float64})
+			}
+		case entities.Counter:
+			if counter.Valid {
+				metrics = append(metrics, &entities.CounterMetric{Name: name, Value: counter.Int64})
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapListMetricsError(err)
+	}
+
+	return metrics, nil
+}
+
+// AggregateMetric агрегирует историю значений метрики по интервалам на стороне базы данных.
+func (s *PGStorage) AggregateMetric(q *entities.AggregateQuery) ([]*entities.AggregateBucket, error) {
+	ctx := context.Background()
+
+	var percentiles []float64
+	for _, f := range q.Funcs {
+		if p, ok := f.Percentile(); ok {
+			percentiles = append(percentiles, p)
+		}
+	}
+
+	rows, err := s.db.Query(ctx, AggregateQuery, q.Name, q.Type.String(), q.Step.Seconds(), q.From, q.To, percentiles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := make([]*entities.AggregateBucket, 0)
+	for rows.Next() {
+		var (
+			start                         time.Time
+			count                         int64
+			minV, maxV, avgV, sumV, lastV float64
+			pcts                          []float64
+		)
+		if err := rows.Scan(&start, &count, &minV, &maxV, &avgV, &sumV, &lastV, &pcts); err != nil {
+			return nil, err
+		}
+
+		b := &entities.AggregateBucket{
+			Start:  start,
+			Count:  count,
+			Values: make(map[entities.AggregateFunc]float64, len(q.Funcs)),
+		}
+		i := 0
+		for _, f := range q.Funcs {
+			switch f {
+			case entities.AggregateMin:
+				b.Values[f] = minV
+			case entities.AggregateMax:
+				b.Values[f] = maxV
+			case entities.AggregateAvg:
+				b.Values[f] = avgV
+			case entities.AggregateSum:
+				b.Values[f] = sumV
+			case entities.AggregateLast:
+				b.Values[f] = lastV
+			default:
+				if _, ok := f.Percentile(); ok && i < len(pcts) {
+					b.Values[f] = pcts[i]
+					i++
+				}
+			}
+		}
+		buckets = append(buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buckets, nil
+}
+
+// PruneSamples удаляет значения истории метрик старше заданного момента времени.
+func (s *PGStorage) PruneSamples(before time.Time) error {
+	_, err := s.db.Exec(context.Background(), PruneSamplesQuery, before)
+	return err
+}
+
+// StartPeriodicPrune запускает периодическое удаление значений истории, вышедших за окно хранения.
+func (s *PGStorage) StartPeriodicPrune(ctx context.Context, log *logging.Logger, retention time.Duration, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug("PGStorage samples pruning stopped")
+			return
+		case <-time.After(interval):
+			if err := s.PruneSamples(time.Now().Add(-retention)); err != nil {
+				log.Errorf("PGStorage samples pruning error: %v", err)
+			}
+		}
+	}
+}
+
+// mapListMetricsError преобразует ошибку некорректного регулярного выражения в ошибку запроса.
+func mapListMetricsError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgInvalidRegularExpression {
+		return errs.ErrInvalidQuery
+	}
+	return err
+}
+
+// buildListMetricsQuery формирует SQL-запрос и его аргументы для ListMetrics.
+func buildListMetricsQuery(q *entities.MetricQuery) (string, []any) {
+	var where []string
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(q.Types) > 0 {
+		types := make([]string, 0, len(q.Types))
+		for _, t := range q.Types {
+			types = append(types, t.String())
+		}
+		where = append(where, "type = ANY("+arg(types)+")")
+	}
+	if q.NamePrefix != "" {
+		where = append(where, "starts_with(name, "+arg(q.NamePrefix)+")")
+	}
+	if q.NameRegex != "" {
+		where = append(where, "name ~ "+arg(q.NameRegex))
+	}
+
+	// COLLATE "C" обеспечивает побайтовое сравнение, как и при сортировке в памяти.
+	first, second := `name COLLATE "C"`, `type COLLATE "C"`
+	if q.SortBy == entities.SortByType {
+		first, second = second, first
+	}
+	dir, cmp := "ASC", ">"
+	if q.SortDesc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if q.After != nil {
+		key := entities.SortKey(q.SortBy, q.After.Name, q.After.Type)
+		where = append(where, fmt.Sprintf("(%s, %s) %s (%s, %s)", first, second, cmp, arg(key[0]), arg(key[1])))
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(GetAllMetricsQuery))
+	if len(where) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(where, " AND "))
+	}
+	fmt.Fprintf(&b, " ORDER BY %s %s, %s %s", first, dir, second, dir)
+	if q.Limit > 0 {
+		b.WriteString(" LIMIT " + arg(q.Limit))
+	}
+
+	return b.String(), args
+}
+
 // Ping проверяет соединение с базой данных.
 func (s *PGStorage) Ping() error {
 	if err := s.db.Ping(context.TODO()); err != nil {
@@ -256,6 +524,12 @@ func (s *PGStorage) Ping() error {
 	return nil
 }
 
+// Close закрывает пул соединений с базой данных, дождавшись возврата занятых соединений.
+func (s *PGStorage) Close() error {
+	s.db.Close()
+	return nil
+}
+
 // CreateConnPool создает пул соединений с базой данных.
 func CreateConnPool(dsn string) (*pgxpool.Pool, error) {
 	// По дефолту запросы подготавливаются и кэшируются: default_query_exec_mode=cache_statement
@@ -295,11 +569,54 @@ func CreatePGSchema(ctx context.Context, db *pgxpool.Pool) error {
 	if err != nil {
 		return fmt.Errorf("ошибка создания таблицы metrics: %w", err)
 	}
+
+	// Время и агент последнего обновления метрик, добавленные в существующие таблицы.
+	query = `
+    ALTER TABLE metrics
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS updated_by TEXT`
+	_, err = db.Exec(ctx, query)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления таблицы metrics: %w", err)
+	}
+
+	// История значений метрик для агрегации.
+	query = `
+    CREATE TABLE IF NOT EXISTS metric_samples (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    ts TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+	CREATE INDEX IF NOT EXISTS metric_samples_name_type_ts_idx ON metric_samples (name, type, ts)`
+	_, err = db.Exec(ctx, query)
+	if err != nil {
+		return fmt.Errorf("ошибка создания таблицы metric_samples: %w", err)
+	}
+	return nil
+}
+
+// pgSchemaTables таблицы, создаваемые CreatePGSchema.
+var pgSchemaTables = []string{"metrics", "metric_samples"}
+
+// CheckSchema проверяет, что в базе данных созданы все таблицы хранилища.
+func (s *PGStorage) CheckSchema(ctx context.Context) error {
+	for _, table := range pgSchemaTables {
+		var exists bool
+		if err := s.db.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check table %s: %w", table, err)
+		}
+		if !exists {
+			return fmt.Errorf("table %s does not exist", table)
+		}
+	}
 	return nil
 }
 
 // BatchUpdateOrCreateMetrics обновляет метрики в базе данных или создает их, если они не существуют.
-func (s *PGStorage) BatchUpdateOrCreateMetrics(metrics []*entities.MetricDTO) error {
+func (s *PGStorage) BatchUpdateOrCreateMetrics(metrics []*entities.MetricDTO, update entities.MetricUpdate) error {
+	updatedAt, updatedBy := updateArgs(update)
 	return retry.Retry(func() error {
 		ctx := context.TODO()
 
@@ -318,18 +635,18 @@ func (s *PGStorage) BatchUpdateOrCreateMetrics(metrics []*entities.MetricDTO) er
 		for _, dto := range metrics {
 			mType, err2 := entities.GetMetricType(dto.MType)
 			if err2 != nil {
-				fmt.Printf("Bad metric type: %v\n", err2)
+				s.log.Warnf("Skipping metric %s: %v", dto.ID, err2)
 				continue
 			}
 			switch mType {
 			case entities.Gauge:
-				_, err = tx.Exec(ctx, UpsertGaugeQuery, dto.ID, dto.MType, dto.Value)
+				_, err = tx.Exec(ctx, UpsertGaugeQuery, dto.ID, dto.MType, dto.Value, updatedAt, updatedBy)
 				if err != nil {
 					return errs.ErrInternal
 				}
 
 			case entities.Counter:
-				_, err = tx.Exec(ctx, UpsertCounterQuery, dto.ID, dto.MType, dto.Delta)
+				_, err = tx.Exec(ctx, UpsertCounterQuery, dto.ID, dto.MType, dto.Delta, updatedAt, updatedBy)
 				if err != nil {
 					return errs.ErrInternal
 				}
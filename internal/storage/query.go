@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/errs"
+)
+
+// queryMetrics применяет фильтры, сортировку и пагинацию запроса к набору метрик в памяти.
+func queryMetrics(metrics map[entities.MetricKey]entities.Metric, q *entities.MetricQuery) ([]entities.Metric, error) {
+	var re *regexp.Regexp
+	if q.NameRegex != "" {
+		var err error
+		re, err = regexp.Compile(q.NameRegex)
+		if err != nil {
+			return nil, errs.ErrInvalidQuery
+		}
+	}
+
+	var after [2]string
+	if q.After != nil {
+		after = entities.SortKey(q.SortBy, q.After.Name, q.After.Type)
+	}
+
+	res := make([]entities.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		if len(q.Types) > 0 && !slices.Contains(q.Types, m.GetType()) {
+			continue
+		}
+		if !strings.HasPrefix(m.GetName(), q.NamePrefix) {
+			continue
+		}
+		if re != nil && !re.MatchString(m.GetName()) {
+			continue
+		}
+		if q.After != nil && !q.Less(after, entities.SortKey(q.SortBy, m.GetName(), m.GetType().String())) {
+			continue
+		}
+		res = append(res, m)
+	}
+
+	slices.SortFunc(res, func(a, b entities.Metric) int {
+		ka := entities.SortKey(q.SortBy, a.GetName(), a.GetType().String())
+		kb := entities.SortKey(q.SortBy, b.GetName(), b.GetType().String())
+		switch {
+		case q.Less(ka, kb):
+			return -1
+		case q.Less(kb, ka):
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	if q.Limit > 0 && len(res) > q.Limit {
+		res = res[:q.Limit]
+	}
+	return res, nil
+}
@@ -0,0 +1,317 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/errs"
+	"github.com/gitslim/monit/internal/retry"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix префикс ключей метрик в Redis.
+const redisKeyPrefix = "monit:"
+
+// redisUpdatesKey ключ хеша со временем и агентом последнего обновления метрик.
+// Поля хеша имеют вид <type>:<name>, ключ не соответствует формату ключей метрик
+// и пропускается при их чтении.
+const redisUpdatesKey = redisKeyPrefix + "updates"
+
+// redisScanCount количество ключей, запрашиваемых за одну итерацию SCAN.
+const redisScanCount = 100
+
+// redisGlobEscaper экранирует спецсимволы glob-шаблонов Redis.
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// RedisStorage хранилище метрик в Redis.
+type RedisStorage struct {
+	client *redis.Client
+}
+
+// NewRedisStorage возвращает экземпляр хранилища с подключением к Redis.
+func NewRedisStorage(client *redis.Client) *RedisStorage {
+	return &RedisStorage{
+		client: client,
+	}
+}
+
+// redisKey формирует ключ метрики в Redis вида monit:<type>:<name>.
+func redisKey(mName string, mType entities.MetricType) string {
+	return redisKeyPrefix + mType.String() + ":" + mName
+}
+
+// parseRedisKey разбирает ключ метрики на тип и имя.
+func parseRedisKey(key string) (string, entities.MetricType, error) {
+	typeAndName, ok := strings.CutPrefix(key, redisKeyPrefix)
+	if !ok {
+		return "", -1, errs.ErrInvalidMetricType
+	}
+	typeStr, name, ok := strings.Cut(typeAndName, ":")
+	if !ok {
+		return "", -1, errs.ErrInvalidMetricType
+	}
+	mType, err := entities.GetMetricType(typeStr)
+	if err != nil {
+		return "", -1, err
+	}
+	return name, mType, nil
+}
+
+// newMetricFromRedis создает метрику из строкового значения, хранящегося в Redis.
+func newMetricFromRedis(mName string, mType entities.MetricType, raw string) (entities.Metric, error) {
+	switch mType {
+	case entities.Gauge:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, errs.ErrInvalidMetricValue
+		}
+		return &entities.GaugeMetric{Name: mName, Value: v}, nil
+	case entities.Counter:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, errs.ErrInvalidMetricValue
+		}
+		return &entities.CounterMetric{Name: mName, Value: v}, nil
+	default:
+		return nil, errs.ErrInvalidMetricType
+	}
+}
+
+// UpdateOrCreateMetric обновляет значение метрики, если метрика отстутствует то создает ее.
+// Для counter используется INCRBY, для gauge - SET. Значение и сведения об обновлении
+// записываются одной транзакцией.
+func (s *RedisStorage) UpdateOrCreateMetric(mName string, mType entities.MetricType, value interface{}, update entities.MetricUpdate) error {
+	switch mType {
+	case entities.Gauge:
+		if _, ok := value.(float64); !ok {
+			return errs.ErrInvalidMetricValue
+		}
+	case entities.Counter:
+		if _, ok := value.(int64); !ok {
+			return errs.ErrInvalidMetricValue
+		}
+	default:
+		return errs.ErrInvalidMetricType
+	}
+
+	rawUpdate, err := marshalRedisUpdate(update)
+	if err != nil {
+		return errs.ErrInternal
+	}
+
+	return retry.Retry(func() error {
+		ctx := context.Background()
+
+		_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			switch mType {
+			case entities.Gauge:
+				pipe.Set(ctx, redisKey(mName, mType), value.(float64), 0)
+			case entities.Counter:
+				pipe.IncrBy(ctx, redisKey(mName, mType), value.(int64))
+			}
+			if rawUpdate != nil {
+				pipe.HSet(ctx, redisUpdatesKey, entities.MetricKey{Name: mName, Type: mType}.String(), rawUpdate)
+			}
+			return nil
+		})
+		if err != nil {
+			return errs.ErrInternal
+		}
+		return nil
+	}, 3)
+}
+
+// marshalRedisUpdate кодирует сведения об обновлении для записи в хеш redisUpdatesKey.
+// Для пустого времени обновления возвращает nil: сохраненные сведения не изменяются.
+func marshalRedisUpdate(update entities.MetricUpdate) ([]byte, error) {
+	if update.At.IsZero() {
+		return nil, nil
+	}
+	return json.Marshal(update)
+}
+
+// BatchUpdateOrCreateMetrics обновляет метрики в Redis одной транзакцией MULTI/EXEC.
+// Обычный пайплайн не атомарен: при обрыве соединения часть INCRBY могла бы примениться,
+// и повтор запроса учел бы эти counter'ы дважды.
+func (s *RedisStorage) BatchUpdateOrCreateMetrics(metrics []*entities.MetricDTO, update entities.MetricUpdate) error {
+	rawUpdate, err := marshalRedisUpdate(update)
+	if err != nil {
+		return errs.ErrInternal
+	}
+
+	return retry.Retry(func() error {
+		ctx := context.Background()
+
+		_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, dto := range metrics {
+				mType, err := entities.GetMetricType(dto.MType)
+				if err != nil {
+					// Метрики неизвестного типа пропускаем, как и в остальных хранилищах.
+					continue
+				}
+				switch mType {
+				case entities.Gauge:
+					if dto.Value == nil {
+						continue
+					}
+					pipe.Set(ctx, redisKey(dto.ID, mType), *dto.Value, 0)
+				case entities.Counter:
+					if dto.Delta == nil {
+						continue
+					}
+					pipe.IncrBy(ctx, redisKey(dto.ID, mType), *dto.Delta)
+				default:
+					continue
+				}
+				if rawUpdate != nil {
+					pipe.HSet(ctx, redisUpdatesKey, entities.MetricKey{Name: dto.ID, Type: mType}.String(), rawUpdate)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("redis transaction failed: %w", err)
+		}
+		return nil
+	}, 3)
+}
+
+// GetMetricUpdates возвращает время и агента последнего обновления метрик keys.
+func (s *RedisStorage) GetMetricUpdates(keys []entities.MetricKey) (map[entities.MetricKey]entities.MetricUpdate, error) {
+	updates := make(map[entities.MetricKey]entities.MetricUpdate, len(keys))
+	if len(keys) == 0 {
+		return updates, nil
+	}
+
+	fields := make([]string, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, k.String())
+	}
+
+	values, err := s.client.HMGet(context.Background(), redisUpdatesKey, fields...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis hmget failed: %w", err)
+	}
+
+	for i, k := range keys {
+		raw, ok := values[i].(string)
+		if !ok {
+			continue
+		}
+		var u entities.MetricUpdate
+		if err := json.Unmarshal([]byte(raw), &u); err != nil {
+			continue
+		}
+		updates[k] = u
+	}
+	return updates, nil
+}
+
+// GetMetric получает метрику по имени и типу.
+func (s *RedisStorage) GetMetric(mName string, mType string) (entities.Metric, error) {
+	ctx := context.Background()
+
+	metricType, err := entities.GetMetricType(mType)
+	if err != nil {
+		return nil, errs.ErrInvalidMetricType
+	}
+
+	raw, err := s.client.Get(ctx, redisKey(mName, metricType)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, errs.ErrMetricNotFound
+		}
+		return nil, errs.ErrInternal
+	}
+
+	return newMetricFromRedis(mName, metricType, raw)
+}
+
+// GetAllMetrics получает все метрики.
+func (s *RedisStorage) GetAllMetrics() (map[entities.MetricKey]entities.Metric, error) {
+	return s.scanMetrics(redisKeyPrefix + "*")
+}
+
+// scanMetrics получает метрики, ключи которых соответствуют шаблону SCAN MATCH.
+func (s *RedisStorage) scanMetrics(pattern string) (map[entities.MetricKey]entities.Metric, error) {
+	ctx := context.Background()
+
+	var keys []string
+	iter := s.client.Scan(ctx, 0, pattern, redisScanCount).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("redis scan failed: %w", err)
+	}
+
+	metrics := make(map[entities.MetricKey]entities.Metric)
+	if len(keys) == 0 {
+		return metrics, nil
+	}
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis mget failed: %w", err)
+	}
+
+	for i, key := range keys {
+		raw, ok := values[i].(string)
+		if !ok {
+			// Ключ мог быть удален между SCAN и MGET.
+			continue
+		}
+		name, mType, err := parseRedisKey(key)
+		if err != nil {
+			continue
+		}
+		m, err := newMetricFromRedis(name, mType, raw)
+		if err != nil {
+			continue
+		}
+		metrics[entities.KeyOf(m)] = m
+	}
+
+	return metrics, nil
+}
+
+// ListMetrics получает отфильтрованный и отсортированный список метрик.
+// Фильтр по префиксу имени передается в Redis через шаблон SCAN MATCH.
+func (s *RedisStorage) ListMetrics(q *entities.MetricQuery) ([]entities.Metric, error) {
+	metrics, err := s.scanMetrics(redisKeyPrefix + "*:" + redisGlobEscaper.Replace(q.NamePrefix) + "*")
+	if err != nil {
+		return nil, err
+	}
+	return queryMetrics(metrics, q)
+}
+
+// Ping проверяет соединение с Redis.
+func (s *RedisStorage) Ping() error {
+	if err := s.client.Ping(context.TODO()).Err(); err != nil {
+		return fmt.Errorf("redis connection error: %w", err)
+	}
+	return nil
+}
+
+// Close закрывает соединения с Redis.
+func (s *RedisStorage) Close() error {
+	return s.client.Close()
+}
+
+// CreateRedisClient создает клиент Redis и проверяет соединение.
+func CreateRedisClient(addr string) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: addr,
+	})
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("redis connection error: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,138 @@
+package storage_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/errs"
+	"github.com/gitslim/monit/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRedisStorage создает RedisStorage, подключенное к miniredis.
+func newRedisStorage(t *testing.T) (*storage.RedisStorage, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+
+	client, err := storage.CreateRedisClient(mr.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	return storage.NewRedisStorage(client), mr
+}
+
+// TestRedisStorageUpdateOrCreateMetric тестирует обновление одиночных метрик.
+func TestRedisStorageUpdateOrCreateMetric(t *testing.T) {
+	stor, mr := newRedisStorage(t)
+
+	require.NoError(t, stor.UpdateOrCreateMetric("g1", entities.Gauge, 1.5, entities.MetricUpdate{}))
+	require.NoError(t, stor.UpdateOrCreateMetric("g1", entities.Gauge, 2.5, entities.MetricUpdate{}))
+	require.NoError(t, stor.UpdateOrCreateMetric("c1", entities.Counter, int64(10), entities.MetricUpdate{}))
+	require.NoError(t, stor.UpdateOrCreateMetric("c1", entities.Counter, int64(5), entities.MetricUpdate{}))
+
+	// Gauge перезаписывается, counter накапливается.
+	g, err := stor.GetMetric("g1", "gauge")
+	require.NoError(t, err)
+	assert.Equal(t, 2.5, g.GetValue())
+
+	c, err := stor.GetMetric("c1", "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), c.GetValue())
+
+	// Значения хранятся под ключами monit:<type>:<name>.
+	raw, err := mr.Get("monit:counter:c1")
+	require.NoError(t, err)
+	assert.Equal(t, "15", raw)
+
+	err = stor.UpdateOrCreateMetric("g2", entities.Gauge, int64(1), entities.MetricUpdate{})
+	assert.ErrorIs(t, err, errs.ErrInvalidMetricValue)
+}
+
+// TestRedisStorageGetMetric тестирует получение метрик.
+func TestRedisStorageGetMetric(t *testing.T) {
+	stor, _ := newRedisStorage(t)
+
+	require.NoError(t, stor.UpdateOrCreateMetric("m1", entities.Gauge, 3.0, entities.MetricUpdate{}))
+
+	_, err := stor.GetMetric("m1", "counter")
+	assert.ErrorIs(t, err, errs.ErrMetricNotFound)
+
+	_, err = stor.GetMetric("m1", "foo")
+	assert.ErrorIs(t, err, errs.ErrInvalidMetricType)
+
+	_, err = stor.GetMetric("unknown", "gauge")
+	assert.ErrorIs(t, err, errs.ErrMetricNotFound)
+}
+
+// TestRedisStorageBatchUpdateOrCreateMetrics тестирует пакетное обновление и получение всех метрик.
+func TestRedisStorageBatchUpdateOrCreateMetrics(t *testing.T) {
+	stor, _ := newRedisStorage(t)
+
+	delta := int64(7)
+	value := 42.5
+	metrics := []*entities.MetricDTO{
+		{ID: "c1", MType: "counter", Delta: &delta},
+		{ID: "c1", MType: "counter", Delta: &delta},
+		{ID: "g1", MType: "gauge", Value: &value},
+		{ID: "c1", MType: "gauge", Value: &value},
+		{ID: "bad", MType: "foo", Value: &value},
+	}
+	require.NoError(t, stor.BatchUpdateOrCreateMetrics(metrics, entities.MetricUpdate{At: time.Now(), Agent: "agent-1"}))
+
+	// Gauge и counter с одинаковым именем возвращаются оба, сведения об обновлениях не считаются метриками.
+	all, err := stor.GetAllMetrics()
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, int64(14), all[entities.MetricKey{Name: "c1", Type: entities.Counter}].GetValue())
+	assert.Equal(t, 42.5, all[entities.MetricKey{Name: "c1", Type: entities.Gauge}].GetValue())
+	assert.Equal(t, 42.5, all[entities.MetricKey{Name: "g1", Type: entities.Gauge}].GetValue())
+
+	list, err := stor.ListMetrics(&entities.MetricQuery{NamePrefix: "c1", SortBy: entities.SortByType})
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	assert.NoError(t, stor.Ping())
+}
+
+// TestRedisStorageListMetrics тестирует получение списка метрик с фильтром по префиксу.
+func TestRedisStorageListMetrics(t *testing.T) {
+	stor, _ := newRedisStorage(t)
+
+	require.NoError(t, stor.UpdateOrCreateMetric("cpu*1", entities.Gauge, 1.0, entities.MetricUpdate{}))
+	require.NoError(t, stor.UpdateOrCreateMetric("cpu2", entities.Gauge, 2.0, entities.MetricUpdate{}))
+	require.NoError(t, stor.UpdateOrCreateMetric("cpu*count", entities.Counter, int64(1), entities.MetricUpdate{}))
+
+	// Спецсимволы glob в префиксе экранируются.
+	metrics, err := stor.ListMetrics(&entities.MetricQuery{NamePrefix: "cpu*", SortBy: entities.SortByType})
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "cpu*count", metrics[0].GetName())
+	assert.Equal(t, "cpu*1", metrics[1].GetName())
+}
+
+// TestRedisStorageMetricUpdates тестирует сохранение времени и агента последнего обновления метрик.
+func TestRedisStorageMetricUpdates(t *testing.T) {
+	stor, _ := newRedisStorage(t)
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, stor.UpdateOrCreateMetric("g1", entities.Gauge, 1.0, entities.MetricUpdate{At: at, Agent: "agent-1"}))
+	delta := int64(1)
+	require.NoError(t, stor.BatchUpdateOrCreateMetrics([]*entities.MetricDTO{
+		{ID: "c1", MType: "counter", Delta: &delta},
+	}, entities.MetricUpdate{At: at.Add(time.Second), Agent: "agent-2"}))
+	// Обновление без сведений об агенте не заменяет сохраненные сведения.
+	require.NoError(t, stor.UpdateOrCreateMetric("g1", entities.Gauge, 2.0, entities.MetricUpdate{}))
+
+	g1 := entities.MetricKey{Name: "g1", Type: entities.Gauge}
+	c1 := entities.MetricKey{Name: "c1", Type: entities.Counter}
+	unknown := entities.MetricKey{Name: "g1", Type: entities.Counter}
+	updates, err := stor.GetMetricUpdates([]entities.MetricKey{g1, c1, unknown})
+	require.NoError(t, err)
+	require.Len(t, updates, 2)
+	assert.True(t, at.Equal(updates[g1].At))
+	assert.Equal(t, "agent-1", updates[g1].Agent)
+	assert.True(t, at.Add(time.Second).Equal(updates[c1].At))
+	assert.Equal(t, "agent-2", updates[c1].Agent)
+}
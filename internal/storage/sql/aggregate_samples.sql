@@ -0,0 +1,13 @@
+SELECT
+    date_bin(make_interval(secs => $3), ts, $4) AS bucket,
+    count(*),
+    min(value),
+    max(value),
+    avg(value),
+    sum(value),
+    (array_agg(value ORDER BY ts DESC, id DESC))[1],
+    percentile_cont($6::double precision[]) WITHIN GROUP (ORDER BY value)
+FROM metric_samples
+WHERE name = $1 AND type = $2 AND ts >= $4 AND ts < $5
+GROUP BY bucket
+ORDER BY bucket
@@ -0,0 +1,4 @@
+SELECT m.name, m.type, m.updated_at, COALESCE(m.updated_by, '')
+FROM metrics m
+JOIN unnest($1::text[], $2::text[]) AS k(name, type) ON m.name = k.name AND m.type = k.type
+WHERE m.updated_at IS NOT NULL
@@ -0,0 +1 @@
+DELETE FROM metric_samples WHERE ts < $1
@@ -1,4 +1,11 @@
-INSERT INTO metrics (name, type, counter)
-VALUES ($1, $2, $3)
-ON CONFLICT (name, type)
-DO UPDATE SET counter = metrics.counter + EXCLUDED.counter
+WITH upserted AS (
+    INSERT INTO metrics (name, type, counter, updated_at, updated_by)
+    VALUES ($1, $2, $3, $4, $5)
+    ON CONFLICT (name, type)
+    DO UPDATE SET counter = metrics.counter + EXCLUDED.counter,
+        updated_at = COALESCE(EXCLUDED.updated_at, metrics.updated_at),
+        updated_by = CASE WHEN EXCLUDED.updated_at IS NULL THEN metrics.updated_by ELSE EXCLUDED.updated_by END
+    RETURNING name, type, counter
+)
+INSERT INTO metric_samples (name, type, value)
+SELECT name, type, counter FROM upserted
@@ -1,19 +1,62 @@
 package storage
 
 import (
+	"context"
+	"time"
+
 	"github.com/gitslim/monit/internal/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Storager определяет интерфейс для работы с хранилищем метрик.
 type Storager interface {
-	// UpdadateOrCreateMetric обновляет или создает метрику.
-	UpdateOrCreateMetric(mName string, mType entities.MetricType, mValue interface{}) error
-	// BatchUpdateOrCreateMetrics обновляет или создает метрики.
-	BatchUpdateOrCreateMetrics([]*entities.MetricDTO) error
+	// UpdadateOrCreateMetric обновляет или создает метрику и сохраняет вместе с ней сведения об обновлении.
+	UpdateOrCreateMetric(mName string, mType entities.MetricType, mValue interface{}, update entities.MetricUpdate) error
+	// BatchUpdateOrCreateMetrics обновляет или создает метрики и сохраняет вместе с ними сведения об обновлении.
+	BatchUpdateOrCreateMetrics(metrics []*entities.MetricDTO, update entities.MetricUpdate) error
+	// GetMetricUpdates возвращает время и агента последнего обновления метрик keys.
+	// Метрики, для которых сведения об обновлении отсутствуют, в результат не попадают.
+	GetMetricUpdates(keys []entities.MetricKey) (map[entities.MetricKey]entities.MetricUpdate, error)
 	// GetMetric получает метрику.
 	GetMetric(mName string, mType string) (entities.Metric, error)
-	// GetAllMetrics получает все метрики.
-	GetAllMetrics() (map[string]entities.Metric, error)
+	// GetAllMetrics получает все метрики по ключам entities.MetricKey. Метрики разных типов
+	// с одинаковым именем хранятся независимо и возвращаются под разными ключами.
+	GetAllMetrics() (map[entities.MetricKey]entities.Metric, error)
+	// ListMetrics получает отфильтрованный и отсортированный список метрик.
+	ListMetrics(q *entities.MetricQuery) ([]entities.Metric, error)
 	// Ping проверяет соединение с хранилищем.
 	Ping() error
 }
+
+// Aggregator определяет хранилище, которое хранит историю значений метрик и агрегирует ее самостоятельно.
+type Aggregator interface {
+	// AggregateMetric агрегирует историю значений метрики по интервалам.
+	AggregateMetric(q *entities.AggregateQuery) ([]*entities.AggregateBucket, error)
+}
+
+// BackupStat содержит статистику сохранения резервных копий хранилища.
+type BackupStat struct {
+	Count        int64         // число сохранений
+	Errors       int64         // число неудачных сохранений
+	LastDuration time.Duration // длительность последнего сохранения
+	LastSuccess  time.Time     // время последнего успешного сохранения
+	LastError    error         // ошибка последнего сохранения, nil при успехе
+}
+
+// BackupStatter определяет хранилище, сохраняющее резервные копии и ведущее их статистику.
+type BackupStatter interface {
+	// BackupStat возвращает статистику сохранения резервных копий.
+	BackupStat() BackupStat
+}
+
+// PoolStatter определяет хранилище с пулом соединений с базой данных.
+type PoolStatter interface {
+	// PoolStat возвращает статистику пула соединений.
+	PoolStat() *pgxpool.Stat
+}
+
+// SchemaChecker определяет хранилище, схема которого создается в базе данных при запуске сервера.
+type SchemaChecker interface {
+	// CheckSchema проверяет, что схема хранилища создана.
+	CheckSchema(ctx context.Context) error
+}
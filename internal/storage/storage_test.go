@@ -0,0 +1,111 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gitslim/monit/internal/entities"
+	"github.com/gitslim/monit/internal/logging"
+	"github.com/gitslim/monit/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// dockerAvailable проверяет доступность Docker для testcontainers.
+// Без Docker testcontainers завершается паникой, а не ошибкой, поэтому она перехватывается.
+func dockerAvailable(ctx context.Context) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		return false
+	}
+	defer func() { _ = provider.Close() }()
+	return provider.Health(ctx) == nil
+}
+
+// newPGStorage создает PGStorage в контейнере PostgreSQL. Тест пропускается, если Docker недоступен.
+func newPGStorage(t *testing.T, log *logging.Logger) *storage.PGStorage {
+	ctx := context.Background()
+	if !dockerAvailable(ctx) {
+		t.Skip("Docker недоступен")
+	}
+
+	pgContainer, err := postgres.Run(ctx,
+		"postgres:17-alpine",
+		postgres.WithDatabase("test-db"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).WithStartupTimeout(30*time.Second)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = pgContainer.Terminate(ctx) })
+
+	dsn, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	pool, err := storage.CreateConnPool(dsn)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	require.NoError(t, storage.CreatePGSchema(ctx, pool))
+
+	return storage.NewPGStorage(log, pool)
+}
+
+// TestGetAllMetricsKeys тестирует, что все хранилища возвращают метрики по ключам entities.MetricKey
+// и хранят gauge и counter с одинаковым именем независимо.
+func TestGetAllMetricsKeys(t *testing.T) {
+	log, err := logging.NewLogger()
+	require.NoError(t, err)
+
+	backends := []struct {
+		name string
+		new  func(t *testing.T) storage.Storager
+	}{
+		{name: "memory", new: func(t *testing.T) storage.Storager {
+			return storage.NewMemStorage(log, false, nil)
+		}},
+		{name: "redis", new: func(t *testing.T) storage.Storager {
+			stor, _ := newRedisStorage(t)
+			return stor
+		}},
+		{name: "postgres", new: func(t *testing.T) storage.Storager {
+			return newPGStorage(t, log)
+		}},
+	}
+
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			stor := b.new(t)
+
+			require.NoError(t, stor.UpdateOrCreateMetric("m1", entities.Gauge, 1.5, entities.MetricUpdate{}))
+			require.NoError(t, stor.UpdateOrCreateMetric("m1", entities.Counter, int64(3), entities.MetricUpdate{}))
+			require.NoError(t, stor.UpdateOrCreateMetric("g1", entities.Gauge, 2.0, entities.MetricUpdate{}))
+
+			all, err := stor.GetAllMetrics()
+			require.NoError(t, err)
+			values := make(map[entities.MetricKey]any, len(all))
+			for k, m := range all {
+				assert.Equal(t, entities.KeyOf(m), k)
+				values[k] = m.GetValue()
+			}
+			assert.Equal(t, map[entities.MetricKey]any{
+				{Name: "m1", Type: entities.Gauge}:   1.5,
+				{Name: "m1", Type: entities.Counter}: int64(3),
+				{Name: "g1", Type: entities.Gauge}:   2.0,
+			}, values)
+
+			m, err := stor.GetMetric("m1", "gauge")
+			require.NoError(t, err)
+			assert.Equal(t, 1.5, m.GetValue())
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/gitslim/monit/internal/server/conf"
 	"github.com/gitslim/monit/internal/server/engine"
 	"github.com/gitslim/monit/internal/services"
+	"github.com/gitslim/monit/internal/storage"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -57,7 +58,11 @@ func CreateServerMock(withPostgres bool) (*gin.Engine, func(), error) {
 		cfg = &conf.Config{
 			DatabaseDSN: dsn,
 		}
-		svcConf, err2 = services.WithPGStorage(ctx, log, cfg)
+		pool, err2 := storage.CreateConnPool(dsn)
+		if err2 != nil {
+			return nil, nil, err2
+		}
+		svcConf, err2 = services.WithPGStorage(ctx, log, cfg, pool)
 		if err2 != nil {
 			return nil, nil, err2
 		}
@@ -0,0 +1,35 @@
+package testhelpers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"path/filepath"
+)
+
+// GenerateEd25519Keys генерирует ключи Ed25519 агента agentID: открытый ключ записывается
+// в каталог pubDir под именем <agentID>.pem, закрытый - в каталог privDir. Возвращает путь до закрытого ключа.
+func GenerateEd25519Keys(pubDir, privDir, agentID string) (string, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	if err := writePEM(filepath.Join(pubDir, agentID+".pem"), "PUBLIC KEY", pubDER); err != nil {
+		return "", err
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return "", err
+	}
+	privPath := filepath.Join(privDir, agentID+"-key.pem")
+	if err := writePEM(privPath, "PRIVATE KEY", privDER); err != nil {
+		return "", err
+	}
+	return privPath, nil
+}
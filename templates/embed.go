@@ -0,0 +1,11 @@
+// Package templates содержит HTML-шаблоны и статические файлы веб-интерфейса сервера метрик.
+//
+// Файлы встраиваются в бинарный файл сервера, поэтому сервер не зависит от расположения исходного кода.
+package templates
+
+import "embed"
+
+// FS содержит HTML-шаблоны (*.html) и статические файлы (static/).
+//
+//go:embed *.html static
+var FS embed.FS
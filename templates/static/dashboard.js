@@ -0,0 +1,279 @@
+// Интерактивная панель метрик: фильтрация, сортировка, автообновление и графики gauge.
+(function () {
+  "use strict";
+
+  const HISTORY_RANGE = "15m";
+  const HISTORY_STEP = "30s";
+  const HISTORY_POINTS = 30;
+  const PAGE_LIMIT = 1000;
+
+  const filterInput = document.getElementById("filter");
+  const refreshSelect = document.getElementById("refresh");
+  const statusEl = document.getElementById("status");
+
+  // Метрики по ключу "<type>:<name>".
+  const metrics = new Map();
+  // Значения gauge для графиков по имени метрики.
+  const history = new Map();
+  let historyEnabled = true;
+  let refreshTimer = null;
+  let renderScheduled = false;
+
+  const groups = Array.from(document.querySelectorAll("section.group")).map(function (section) {
+    return {
+      type: section.dataset.type,
+      section: section,
+      tbody: section.querySelector("tbody"),
+      count: section.querySelector(".count"),
+      sort: { field: "name", dir: "asc" },
+    };
+  });
+
+  function key(type, name) {
+    return type + ":" + name;
+  }
+
+  function setStatus(text, isError) {
+    statusEl.textContent = text;
+    statusEl.classList.toggle("error", Boolean(isError));
+  }
+
+  // Начальное состояние берется из таблиц, отрисованных сервером.
+  groups.forEach(function (g) {
+    g.tbody.querySelectorAll("tr[data-name]").forEach(function (tr) {
+      metrics.set(key(g.type, tr.dataset.name), {
+        name: tr.dataset.name,
+        type: g.type,
+        value: Number(tr.dataset.value),
+      });
+    });
+  });
+
+  function matcher() {
+    const text = filterInput.value.trim();
+    const m = text.match(/^\/(.*)\/$/);
+    if (m) {
+      try {
+        const re = new RegExp(m[1], "i");
+        return function (name) { return re.test(name); };
+      } catch (e) {
+        return function () { return false; };
+      }
+    }
+    const lower = text.toLowerCase();
+    return function (name) { return name.toLowerCase().includes(lower); };
+  }
+
+  function compare(sort) {
+    const sign = sort.dir === "asc" ? 1 : -1;
+    return function (a, b) {
+      if (sort.field === "value" && a.value !== b.value) {
+        return (a.value - b.value) * sign;
+      }
+      return (a.name < b.name ? -1 : a.name > b.name ? 1 : 0) * sign;
+    };
+  }
+
+  function formatValue(m) {
+    return m.type === "counter" ? String(m.value) : String(Number(m.value));
+  }
+
+  function sparkline(values) {
+    const width = 120;
+    const height = 24;
+    if (!values || values.length < 2) {
+      return "";
+    }
+    const min = Math.min.apply(null, values);
+    const max = Math.max.apply(null, values);
+    const span = max - min || 1;
+    const points = values.map(function (v, i) {
+      const x = (i / (values.length - 1)) * width;
+      const y = height - 1 - ((v - min) / span) * (height - 2);
+      return x.toFixed(1) + "," + y.toFixed(1);
+    });
+    return '<svg width="' + width + '" height="' + height + '" viewBox="0 0 ' + width + " " + height +
+      '"><polyline points="' + points.join(" ") + '"></polyline></svg>';
+  }
+
+  function renderGroup(g, match) {
+    const rows = [];
+    metrics.forEach(function (m) {
+      if (m.type === g.type && match(m.name)) {
+        rows.push(m);
+      }
+    });
+    rows.sort(compare(g.sort));
+
+    g.tbody.textContent = "";
+    if (rows.length === 0) {
+      const tr = g.tbody.insertRow();
+      tr.className = "empty";
+      const td = tr.insertCell();
+      td.colSpan = 3;
+      td.textContent = "No metrics found";
+    }
+    rows.forEach(function (m) {
+      const tr = g.tbody.insertRow();
+      tr.dataset.name = m.name;
+      const name = tr.insertCell();
+      name.className = "name";
+      name.textContent = m.name;
+      const value = tr.insertCell();
+      value.className = "num";
+      value.textContent = formatValue(m);
+      if (g.type === "gauge") {
+        const cell = tr.insertCell();
+        cell.className = "history";
+        cell.innerHTML = sparkline(history.get(m.name));
+      }
+    });
+    g.count.textContent = rows.length;
+
+    g.section.querySelectorAll("th.sortable").forEach(function (th) {
+      if (th.dataset.sort === g.sort.field) {
+        th.dataset.dir = g.sort.dir;
+      } else {
+        delete th.dataset.dir;
+      }
+    });
+  }
+
+  function render() {
+    renderScheduled = false;
+    const match = matcher();
+    groups.forEach(function (g) { renderGroup(g, match); });
+  }
+
+  function scheduleRender() {
+    if (!renderScheduled) {
+      renderScheduled = true;
+      window.requestAnimationFrame(render);
+    }
+  }
+
+  async function fetchJSON(url) {
+    const resp = await fetch(url, { headers: { Accept: "application/json" } });
+    if (!resp.ok) {
+      const err = new Error(resp.status + " " + resp.statusText);
+      err.status = resp.status;
+      throw err;
+    }
+    return resp.json();
+  }
+
+  // loadMetrics загружает все метрики постранично.
+  async function loadMetrics() {
+    const seen = new Set();
+    let cursor = "";
+    do {
+      let url = "/api/metrics?limit=" + PAGE_LIMIT;
+      if (cursor) {
+        url += "&cursor=" + encodeURIComponent(cursor);
+      }
+      const page = await fetchJSON(url);
+      (page.metrics || []).forEach(function (dto) {
+        const value = dto.type === "counter" ? dto.delta : dto.value;
+        seen.add(key(dto.type, dto.id));
+        metrics.set(key(dto.type, dto.id), { name: dto.id, type: dto.type, value: value });
+      });
+      cursor = page.next_cursor || "";
+    } while (cursor);
+
+    metrics.forEach(function (_, k) {
+      if (!seen.has(k)) {
+        metrics.delete(k);
+      }
+    });
+  }
+
+  // loadHistory загружает историю значений gauge для графиков.
+  async function loadHistory() {
+    if (!historyEnabled) {
+      return;
+    }
+    const names = [];
+    metrics.forEach(function (m) {
+      if (m.type === "gauge") {
+        names.push(m.name);
+      }
+    });
+
+    for (const name of names) {
+      const url = "/api/aggregate/gauge/" + encodeURIComponent(name) +
+        "?range=" + HISTORY_RANGE + "&step=" + HISTORY_STEP + "&agg=last";
+      try {
+        const res = await fetchJSON(url);
+        history.set(name, (res.buckets || []).map(function (b) { return b.values.last; }));
+      } catch (e) {
+        if (e.status === 501) {
+          // История значений на сервере отключена.
+          historyEnabled = false;
+          return;
+        }
+      }
+    }
+  }
+
+  async function refresh() {
+    try {
+      await loadMetrics();
+      await loadHistory();
+      setStatus("Updated " + new Date().toLocaleTimeString());
+    } catch (e) {
+      setStatus("Update failed: " + e.message, true);
+    }
+    scheduleRender();
+  }
+
+  function scheduleRefresh() {
+    if (refreshTimer) {
+      window.clearInterval(refreshTimer);
+      refreshTimer = null;
+    }
+    const seconds = Number(refreshSelect.value);
+    if (seconds > 0) {
+      refreshTimer = window.setInterval(refresh, seconds * 1000);
+    }
+  }
+
+  // subscribe получает обновления метрик в реальном времени между полными обновлениями.
+  function subscribe() {
+    if (!window.EventSource) {
+      return;
+    }
+    const source = new EventSource("/stream");
+    source.addEventListener("metric", function (event) {
+      const dto = JSON.parse(event.data);
+      const value = dto.type === "counter" ? dto.delta : dto.value;
+      metrics.set(key(dto.type, dto.id), { name: dto.id, type: dto.type, value: value });
+      if (dto.type === "gauge") {
+        const values = history.get(dto.id) || [];
+        values.push(value);
+        history.set(dto.id, values.slice(-HISTORY_POINTS));
+      }
+      scheduleRender();
+    });
+  }
+
+  groups.forEach(function (g) {
+    g.section.querySelectorAll("th.sortable").forEach(function (th) {
+      th.addEventListener("click", function () {
+        if (g.sort.field === th.dataset.sort) {
+          g.sort.dir = g.sort.dir === "asc" ? "desc" : "asc";
+        } else {
+          g.sort = { field: th.dataset.sort, dir: "asc" };
+        }
+        scheduleRender();
+      });
+    });
+  });
+
+  filterInput.addEventListener("input", scheduleRender);
+  refreshSelect.addEventListener("change", scheduleRefresh);
+
+  render();
+  refresh();
+  scheduleRefresh();
+  subscribe();
+})();